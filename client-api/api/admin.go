@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/netip"
+
+	"case-itau/api/handler"
+	"case-itau/api/middleware"
+	l "case-itau/utils/logger"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+)
+
+// RegisterAdmin wires the admin listener, which only answers the addresses in
+// allowed:
+//   - GET and PUT /log-level read and change the log level, e.g. {"level":"debug"}
+//   - GET and PUT /maintenance read and toggle read-only maintenance mode
+//   - POST /backups takes an online backup of a SQLite database, GET lists them
+//   - /debug/pprof/ serves the Go profiler
+func RegisterAdmin(app *fiber.App, allowed []netip.Prefix, h *handler.AdminHandler) {
+	app.Use(middleware.RequestMetadata())
+	app.Use(middleware.AccessLog())
+	app.Use(middleware.IPAllowlist(allowed))
+
+	app.Get("/log-level", adaptor.HTTPHandler(l.Level))
+	app.Put("/log-level", adaptor.HTTPHandler(l.Level))
+
+	app.Get("/maintenance", h.GetMaintenance)
+	app.Put("/maintenance", h.SetMaintenance)
+
+	app.Get("/backups", h.ListBackups)
+	app.Post("/backups", h.CreateBackup)
+
+	app.Use(pprof.New())
+}
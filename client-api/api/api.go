@@ -1,46 +1,322 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
 	"case-itau/api/handler"
+	"case-itau/api/middleware"
 	"case-itau/config"
 	"case-itau/repositories"
 	"case-itau/repositories/connection"
+	"case-itau/repositories/migrations"
+	"case-itau/services/apikey"
+	"case-itau/services/audit"
+	"case-itau/services/backup"
 	"case-itau/services/customer"
+	"case-itau/services/events"
+	"case-itau/services/health"
+	"case-itau/services/importer"
+	"case-itau/services/maintenance"
+	"case-itau/services/ratelimit"
+	"case-itau/services/session"
+	"case-itau/services/stepup"
+	"case-itau/services/stream"
+	"case-itau/services/webhook"
+	"case-itau/utils/auth"
 	l "case-itau/utils/logger"
+	"case-itau/utils/metrics"
+	"case-itau/utils/rbac"
+	"case-itau/utils/tracing"
 
 	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
-func Start() {
-	cfg := config.Load()
+// Start serves the API until SIGINT or SIGTERM is received, then shuts it
+// down gracefully.
+func Start(cfg *config.Config) error {
+	l.NewLogger(cfg.LogLevel)
+	defer l.Logger.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return Run(ctx, cfg)
+}
 
+// Run serves the API until ctx is done. Shutting down stops accepting
+// connections, waits up to cfg.ShutdownTimeout for in-flight requests, stops
+// the background workers, flushes pending spans and closes the database.
+func Run(ctx context.Context, cfg *config.Config) error {
 	app := fiber.New(fiber.Config{
 		AppName: "Customer API",
 	})
 
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to configure tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			l.Logger.Warn("failed to flush spans", zap.Error(err))
+		}
+	}()
+
 	// init db
-	db, err := connection.NewSqliteConnection(cfg.DBPath)
+	db, err := connection.New(cfg.Database())
 	if err != nil {
-		l.Logger.Sugar().Fatalf("failed to connect database: %v", err)
+		return fmt.Errorf("failed to connect database: %w", err)
 	}
-	err = db.AutoMigrate(&repositories.Customers{})
+	defer closeDatabase(db)
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to register database metrics: %w", err)
+	}
+	migrator, err := migrations.New(db)
 	if err != nil {
-		l.Logger.Sugar().Fatalf("failed to migrate database: %v", err)
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	applied, err := migrator.Ensure(ctx, cfg.MigrateOnStart)
+	for _, m := range applied {
+		l.Logger.Info("migration applied", zap.Int64("version", m.Version), zap.String("name", m.Name))
 	}
-	err = db.AutoMigrate(&repositories.Transaction{})
 	if err != nil {
-		l.Logger.Sugar().Fatalf("failed to migrate database: %v", err)
+		return err
+	}
+
+	healthSvc := health.NewService(cfg.HealthCheckTimeout, cfg.HealthCacheTTL)
+	healthSvc.Register("database", repositories.Ping(db))
+	healthSvc.Register("migrations", migrator.Check)
+	healthSvc.Register("schema", repositories.SchemaMatchesModels(db))
+	if cfg.DBDriver == connection.DriverSQLite {
+		healthSvc.Register("disk", health.DiskWritable(filepath.Dir(cfg.DBPath)))
 	}
 
 	// init repo
 	repoCli := repositories.NewGormRepository[repositories.Customers](db)
+	if cfg.CustomerCacheSize > 0 {
+		repoCli = repositories.NewCachedRepository(repoCli, repositories.NewMemoryCache(cfg.CustomerCacheSize), cfg.CustomerCacheTTL)
+	}
 	repoTrans := repositories.NewGormRepository[repositories.Transaction](db)
+	repoAudit := repositories.NewGormRepository[repositories.AuditEntry](db)
+	repoOutbox := repositories.NewGormRepository[repositories.OutboxEvent](db)
+	repoWebhooks := repositories.NewGormRepository[repositories.WebhookSubscription](db)
+	repoDeliveries := repositories.NewGormRepository[repositories.WebhookDelivery](db)
+	repoImports := repositories.NewGormRepository[repositories.ImportJob](db)
+	repoKeys := repositories.NewGormRepository[repositories.APIKey](db)
+	repoNonces := repositories.NewGormRepository[repositories.APIKeyNonce](db)
+	repoCredentials := repositories.NewGormRepository[repositories.Credential](db)
+	repoSessions := repositories.NewGormRepository[repositories.Session](db)
+	repoRefreshTokens := repositories.NewGormRepository[repositories.RefreshToken](db)
+	repoTOTP := repositories.NewGormRepository[repositories.TOTPEnrollment](db)
+	tx := repositories.NewTransactor(db)
+
+	// Background workers run until the API shuts down, after the HTTP server
+	// has drained.
+	workers, stopWorkers := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	runWorker := func(run func(context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(workers)
+		}()
+	}
+	defer func() {
+		stopWorkers()
+		wg.Wait()
+	}()
+
+	// init event delivery
+	worker := webhook.NewWorker(repoWebhooks, repoDeliveries, webhook.NewClient(cfg.WebhookTimeout), cfg.WebhookPollInterval, cfg.WebhookMaxAttempts)
+	webhookSvc := webhook.NewService(repoWebhooks, repoDeliveries, worker)
+	runWorker(worker.Run)
+
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	outbox := events.NewOutbox(repoOutbox)
+	dispatcher := events.NewDispatcher(repoOutbox, events.MultiPublisher{publisher, webhookSvc}, cfg.OutboxPollInterval, cfg.OutboxMaxAttempts)
+	outbox.Subscribe(func(events.Event) { dispatcher.Notify() })
+	broker := stream.NewBroker(repoOutbox, cfg.StreamMaxConnectionsPerClient)
+	outbox.Subscribe(broker.Publish)
+	runWorker(dispatcher.Run)
+
+	store, err := newRateLimitStore(cfg, db)
+	if err != nil {
+		return err
+	}
+	limiter := ratelimit.NewLimiter(store, cfg.RateLimitWindow, map[ratelimit.Class]int{
+		ratelimit.ClassRead:  int(cfg.RateLimitMax),
+		ratelimit.ClassWrite: int(cfg.RateLimitWriteMax),
+		ratelimit.ClassMoney: int(cfg.RateLimitMoneyMax),
+		ratelimit.ClassAuth:  int(cfg.RateLimitAuthMax),
+		// Failed authentications share the quota of logins.
+		ratelimit.ClassAuthFailure: int(cfg.RateLimitAuthMax),
+	})
+	runWorker(limiter.Run)
 
 	// init services and handlers
-	svc := customer.NewService(repoCli, repoTrans)
-	h := handler.NewCustomerHandler(svc)
+	auditSvc := audit.NewService(repoAudit)
+	svc := customer.NewService(tx, repoCli, repoTrans, auditSvc, outbox)
+	importSvc := importer.NewService(tx, svc, repoImports)
+	keySvc := apikey.NewService(repoKeys, repoNonces, cfg.APIKeySignatureWindow)
+
+	// Customers can only log in when the API is able to sign tokens, which
+	// setups verifying against an external JWKS may not allow.
+	issuer, err := auth.NewIssuer(cfg.JWT)
+	if err != nil {
+		l.Logger.Sugar().Warnf("customer login disabled: %v", err)
+		issuer = nil
+	}
+	sessionSvc := session.NewService(tx, svc, repoCredentials, repoSessions, repoRefreshTokens, issuer, session.Config{
+		AccessTokenTTL:    cfg.AccessTokenTTL,
+		RefreshTokenTTL:   cfg.RefreshTokenTTL,
+		MaxFailedAttempts: cfg.LoginMaxFailedAttempts,
+		LockoutDuration:   cfg.LoginLockoutDuration,
+	})
+	stepUpSvc := stepup.NewService(tx, svc, repoTOTP, stepup.Config{
+		Threshold:         cfg.StepUpThreshold,
+		Issuer:            cfg.TOTPIssuer,
+		MaxFailedAttempts: cfg.LoginMaxFailedAttempts,
+		LockoutDuration:   cfg.LoginLockoutDuration,
+	})
+
+	var sec *Security
+	if cfg.AuthEnabled {
+		verifier, err := auth.NewVerifier(cfg.JWT)
+		if err != nil {
+			return fmt.Errorf("failed to configure authentication: %w", err)
+		}
+		policy, err := rbac.Load(cfg.RBACPolicyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load RBAC policy: %w", err)
+		}
+		sec = &Security{Authenticate: middleware.Authenticate(verifier, keySvc, sessionSvc), Policy: policy}
+	} else {
+		l.Logger.Warn("authentication is disabled; every route is open")
+	}
 
-	Register(app, db, cfg, h)
+	mode := maintenance.NewMode()
+	Register(app, db, cfg, sec, limiter, mode, Handlers{
+		Customer: handler.NewCustomerHandler(svc, stepUpSvc),
+		Audit:    handler.NewAuditHandler(auditSvc),
+		Webhook:  handler.NewWebhookHandler(webhookSvc),
+		Stream:   handler.NewStreamHandler(broker, svc, cfg.StreamHeartbeatInterval),
+		Import:   handler.NewImportHandler(importSvc),
+		Batch:    handler.NewBatchHandler(app, tx, limiter, cfg.BatchMaxOperations),
+		APIKey:   handler.NewAPIKeyHandler(keySvc),
+		Session:  handler.NewSessionHandler(sessionSvc),
+		TOTP:     handler.NewTOTPHandler(stepUpSvc),
+		Health:   handler.NewHealthHandler(healthSvc),
+	})
+
+	var admin *fiber.App
+	if cfg.AdminPort != "" {
+		allowed, err := cfg.AdminAllowlist()
+		if err != nil {
+			return fmt.Errorf("invalid admin allowlist: %w", err)
+		}
+		admin = fiber.New(fiber.Config{
+			AppName:               "Customer API admin",
+			DisableStartupMessage: true,
+		})
+		var backups *backup.Service
+		if cfg.DBDriver == connection.DriverSQLite {
+			backups = backup.NewService(db, cfg.Backup())
+		}
+		RegisterAdmin(admin, allowed, handler.NewAdminHandler(mode, backups))
+	}
 
-	l.Logger.Sugar().Fatal(app.Listen(":" + cfg.APIPort))
+	serveErr := make(chan error, 2)
+	listeners := 1
+	go func() {
+		serveErr <- app.Listen(":" + cfg.APIPort)
+	}()
+	if admin != nil {
+		listeners++
+		go func() {
+			serveErr <- admin.Listen(":" + cfg.AdminPort)
+		}()
+		l.Logger.Info("admin listener started", zap.String("port", cfg.AdminPort), zap.String("allowed_ips", cfg.AdminAllowedIPs))
+	}
+
+	select {
+	case err := <-serveErr:
+		app.Shutdown()
+		if admin != nil {
+			admin.Shutdown()
+		}
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Readiness fails from here on so load balancers stop routing new
+	// requests while the in-flight ones finish.
+	l.Logger.Info("shutting down", zap.Duration("timeout", cfg.ShutdownTimeout))
+	healthSvc.Drain()
+	time.Sleep(cfg.ShutdownDelay)
+	broker.Close()
+	if err := app.ShutdownWithTimeout(cfg.ShutdownTimeout); err != nil {
+		l.Logger.Warn("in-flight requests did not finish before the shutdown timeout", zap.Error(err))
+	}
+	// The admin listener stays up while the API drains, so it can still be
+	// profiled.
+	if admin != nil {
+		if err := admin.ShutdownWithTimeout(cfg.ShutdownTimeout); err != nil {
+			l.Logger.Warn("admin listener did not stop before the shutdown timeout", zap.Error(err))
+		}
+	}
+	for range listeners {
+		if err := <-serveErr; err != nil {
+			l.Logger.Warn("server stopped with an error", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// closeDatabase closes the connection pool behind db.
+func closeDatabase(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	if err != nil {
+		l.Logger.Warn("failed to close database", zap.Error(err))
+	}
+}
+
+// newRateLimitStore returns the store selected by RATE_LIMIT_STORAGE. The
+// database one is shared by every instance using the same database.
+func newRateLimitStore(cfg *config.Config, db *gorm.DB) (ratelimit.Store, error) {
+	switch cfg.RateLimitStorage {
+	case "database":
+		return repositories.NewRateLimitStore(db), nil
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit storage %q", cfg.RateLimitStorage)
+	}
+}
+
+// newPublisher builds the publisher selected by EVENTS_PUBLISHER.
+func newPublisher(cfg *config.Config) (events.Publisher, error) {
+	switch cfg.EventsPublisher {
+	case "file":
+		return events.NewFilePublisher(cfg.EventsFilePath), nil
+	case "log":
+		return events.NewLogPublisher(l.Logger), nil
+	default:
+		return nil, fmt.Errorf("unknown events publisher %q", cfg.EventsPublisher)
+	}
 }
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"case-itau/api/types"
+	"case-itau/services/backup"
+	"case-itau/services/maintenance"
+	l "case-itau/utils/logger"
+)
+
+// AdminHandler serves the operational endpoints of the admin listener.
+// backups is nil when the database is not SQLite.
+type AdminHandler struct {
+	mode    *maintenance.Mode
+	backups *backup.Service
+}
+
+func NewAdminHandler(mode *maintenance.Mode, backups *backup.Service) *AdminHandler {
+	return &AdminHandler{
+		mode:    mode,
+		backups: backups,
+	}
+}
+
+// GetMaintenance returns whether the API is in read-only maintenance mode.
+func (h *AdminHandler) GetMaintenance(c *fiber.Ctx) error {
+	return c.JSON(toMaintenanceDto(h.mode.Status()))
+}
+
+// SetMaintenance turns read-only maintenance mode on or off.
+func (h *AdminHandler) SetMaintenance(c *fiber.Ctx) error {
+	req := &types.MaintenanceRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	var status maintenance.Status
+	if *req.Enabled {
+		status = h.mode.Enable(req.Reason)
+		l.FromContext(c.UserContext()).Warn("maintenance mode enabled", zap.String("reason", req.Reason), zap.String("client_ip", c.IP()))
+	} else {
+		status = h.mode.Disable()
+		l.FromContext(c.UserContext()).Warn("maintenance mode disabled", zap.String("client_ip", c.IP()))
+	}
+	return c.JSON(toMaintenanceDto(status))
+}
+
+// CreateBackup takes an online backup of the database.
+func (h *AdminHandler) CreateBackup(c *fiber.Ctx) error {
+	if h.backups == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(types.ErrorResponse{Code: "BACKUP_NOT_SUPPORTED", Message: "Backup disponível apenas para SQLite"})
+	}
+	result, err := h.backups.Backup(c.UserContext())
+	if errors.Is(err, backup.ErrBackupInProgress) {
+		return c.Status(fiber.StatusConflict).JSON(types.ErrorResponse{Code: "BACKUP_IN_PROGRESS", Message: "Backup já em andamento"})
+	}
+	if err != nil {
+		l.FromContext(c.UserContext()).Error("backup failed", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+
+	l.FromContext(c.UserContext()).Info("backup created",
+		zap.String("path", result.Path),
+		zap.Int64("size_bytes", result.Size),
+		zap.Strings("removed", result.Removed),
+		zap.String("client_ip", c.IP()),
+	)
+	dto := toBackupDto(result.File)
+	dto.Removed = result.Removed
+	return c.Status(fiber.StatusCreated).JSON(dto)
+}
+
+// ListBackups lists the backups on disk, newest first.
+func (h *AdminHandler) ListBackups(c *fiber.Ctx) error {
+	if h.backups == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(types.ErrorResponse{Code: "BACKUP_NOT_SUPPORTED", Message: "Backup disponível apenas para SQLite"})
+	}
+	files, err := h.backups.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	dtos := make([]types.BackupDto, 0, len(files))
+	for _, f := range files {
+		dtos = append(dtos, toBackupDto(f))
+	}
+	return c.JSON(dtos)
+}
+
+func toBackupDto(f backup.File) types.BackupDto {
+	return types.BackupDto{Path: f.Path, SizeBytes: f.Size, Compressed: f.Compressed, CreatedAt: f.CreatedAt}
+}
+
+func toMaintenanceDto(s maintenance.Status) types.MaintenanceDto {
+	dto := types.MaintenanceDto{Enabled: s.Enabled, Reason: s.Reason}
+	if s.Enabled {
+		dto.Since = &s.Since
+	}
+	return dto
+}
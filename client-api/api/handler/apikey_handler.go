@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"case-itau/api/types"
+	repo "case-itau/repositories"
+	"case-itau/services/apikey"
+)
+
+type APIKeyHandler struct {
+	service *apikey.Service
+}
+
+func NewAPIKeyHandler(s *apikey.Service) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: s,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary      Cria uma chave de API
+// @Description  Endpoint para criar uma chave de API para integrações. A chave e o segredo de assinatura só são exibidos nesta resposta. A chave deve ser enviada no cabeçalho X-API-Key. Opcionalmente, a requisição pode ser assinada com os cabeçalhos X-Timestamp, X-Nonce e X-Signature, um HMAC-SHA256 sobre método, caminho, timestamp, nonce e SHA-256 do corpo, separados por quebra de linha, usando como chave o segredo de assinatura; requisições assinadas podem identificar a chave apenas por ck_<prefixo> no X-API-Key, sem enviar a chave completa.
+// @Tags         Administração
+// @Accept       json
+// @Produce      json
+// @Param        apiKey  body      types.CreateAPIKeyRequest  true  "Dados da chave"
+// @Success      201  {object}  types.APIKeyDto
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/api-keys [post]
+func (h *APIKeyHandler) Create(c *fiber.Ctx) error {
+	req := &types.CreateAPIKeyRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	key, raw, err := h.service.Create(c.UserContext(), req.Name, req.Scopes, req.ExpiresAt, req.RequireSignature)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	out := toAPIKeyDto(key)
+	out.Key = raw
+	out.SigningSecret = key.SigningSecret
+	return c.Status(fiber.StatusCreated).JSON(out)
+}
+
+// ListAPIKeys godoc
+// @Summary      Lista as chaves de API
+// @Description  Endpoint para listar as chaves de API, inclusive as revogadas e expiradas
+// @Tags         Administração
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   types.APIKeyDto
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/api-keys [get]
+func (h *APIKeyHandler) List(c *fiber.Ctx) error {
+	list, err := h.service.List(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+
+	out := make([]types.APIKeyDto, 0, len(list))
+	for i := range list {
+		out = append(out, toAPIKeyDto(&list[i]))
+	}
+	return c.JSON(out)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoga uma chave de API
+// @Description  Endpoint para revogar uma chave de API. A revogação tem efeito imediato e não pode ser desfeita.
+// @Tags         Administração
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID da chave"
+// @Success      204  {object}  nil
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *fiber.Ctx) error {
+	if err := h.service.Revoke(c.UserContext(), c.Params("id")); err != nil {
+		if errors.Is(err, apikey.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "API_KEY_NOT_FOUND", Message: "Chave de API não encontrada"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func toAPIKeyDto(k *repo.APIKey) types.APIKeyDto {
+	return types.APIKeyDto{
+		ID:               k.ID,
+		Name:             k.Name,
+		Prefix:           k.Prefix,
+		Scopes:           strings.Split(k.Scopes, ","),
+		RequireSignature: k.RequireSignature,
+		CreatedBy:        k.CreatedBy,
+		ExpiresAt:        k.ExpiresAt,
+		RevokedAt:        k.RevokedAt,
+		LastUsedAt:       k.LastUsedAt,
+		CreatedAt:        k.CreatedAt,
+	}
+}
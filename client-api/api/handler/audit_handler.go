@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"case-itau/api/types"
+	"case-itau/services/audit"
+)
+
+type AuditHandler struct {
+	service *audit.Service
+}
+
+func NewAuditHandler(s *audit.Service) *AuditHandler {
+	return &AuditHandler{
+		service: s,
+	}
+}
+
+// GetAuditTrail godoc
+// @Summary      Lista o histórico de auditoria de um usuário
+// @Description  Endpoint para listar as alterações feitas em um cliente, com autor, data, request ID, IP de origem e valores antes/depois de cada campo. O histórico permanece disponível após a exclusão do cliente.
+// @Tags         Auditoria
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string true  "ID do usuário (UUID)"
+// @Param        page  query     int    false "Número da página (default: 1)"
+// @Param        size  query     int    false "Itens por página (default: 10)"
+// @Success      200  {object}  map[string]interface{}  "Retorna metadados de paginação e a lista de registros de auditoria"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /clientes/{id}/auditoria [get]
+func (h *AuditHandler) List(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	size, _ := strconv.Atoi(c.Query("size", "10"))
+	// Clamped as the service does, so that the page count below is computed
+	// from the page size actually used.
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 10
+	}
+
+	entries, total, err := h.service.List(c.UserContext(), id, page, size)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()},
+		)
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(size)))
+
+	if page > totalPages && totalPages != 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
+			Code:    "INVALID_PAGE",
+			Message: fmt.Sprintf("A página %d não existe. Total de páginas: %d", page, totalPages),
+		})
+	}
+
+	out := make([]types.AuditEntryDto, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, types.AuditEntryDto{
+			ID:         e.ID,
+			CustomerID: e.CustomerID,
+			Action:     e.Action,
+			Actor:      e.Actor,
+			RequestID:  e.RequestID,
+			ClientIP:   e.ClientIP,
+			Changes:    json.RawMessage(e.Changes),
+			CreatedAt:  e.CreatedAt,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"page":        page,
+		"size":        size,
+		"total_items": total,
+		"total_pages": totalPages,
+		"items":       out,
+	})
+}
@@ -0,0 +1,68 @@
+//go:build unit
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	repo "case-itau/repositories"
+	"case-itau/services/audit"
+	"case-itau/services/customer/customertest"
+)
+
+func TestCases_AuditHandler_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success paging with the default size when size is zero or negative", testAuditDefaultSize},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testAuditDefaultSize(t *testing.T) {
+	t.Log("testAuditDefaultSize - Testing a success clause for page sizes the service replaces with its default")
+	cf := customertest.New(t)
+	entries := repo.NewGormRepository[repo.AuditEntry](cf.DB)
+	customerID := uuid.New()
+	for range 12 {
+		require.NoError(t, entries.InsertOne(context.Background(), &repo.AuditEntry{
+			ID: uuid.New(), CustomerID: customerID, Action: "update", Actor: "admin1", Changes: "{}",
+		}))
+	}
+
+	app := fiber.New()
+	app.Get("/clientes/:id/auditoria", NewAuditHandler(audit.NewService(entries)).List)
+
+	for _, query := range []string{"?size=0", "?size=-5", "?size=0&page=0"} {
+		resp := send(t, app, fiber.MethodGet, "/clientes/"+customerID.String()+"/auditoria"+query, "", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode, query)
+
+		var body struct {
+			Page       int               `json:"page"`
+			Size       int               `json:"size"`
+			TotalItems int64             `json:"total_items"`
+			TotalPages int               `json:"total_pages"`
+			Items      []json.RawMessage `json:"items"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, 1, body.Page, query)
+		assert.Equal(t, 10, body.Size, query)
+		assert.Equal(t, int64(12), body.TotalItems, query)
+		assert.Equal(t, 2, body.TotalPages, query)
+		assert.Len(t, body.Items, 10, query)
+	}
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"case-itau/api/middleware"
+	"case-itau/api/types"
+	repo "case-itau/repositories"
+	"case-itau/services/ratelimit"
+)
+
+// errBatchAborted rolls back an atomic batch once a sub-request fails.
+var errBatchAborted = errors.New("batch aborted")
+
+// batchReference matches {{index.field}} placeholders, where field may be a
+// dotted path into the JSON body of an earlier response.
+var batchReference = regexp.MustCompile(`\{\{(\d+)\.([A-Za-z0-9_.]+)\}\}`)
+
+// batchResponseHeaders are the sub-response headers copied to the batch output.
+var batchResponseHeaders = []string{fiber.HeaderETag, fiber.HeaderLocation}
+
+// batchOperationTimeout bounds the context each sub-request runs with, so that
+// a slow operation cannot hold the transaction of an atomic batch open.
+const batchOperationTimeout = 10 * time.Second
+
+type BatchHandler struct {
+	app           *fiber.App
+	tx            repo.Transactor
+	limiter       *ratelimit.Limiter
+	maxOperations int
+
+	once    sync.Once
+	handler fasthttp.RequestHandler
+}
+
+// NewBatchHandler builds the batch handler. Operations are counted against
+// the quotas of limiter up front; a nil limiter counts nothing.
+func NewBatchHandler(app *fiber.App, tx repo.Transactor, limiter *ratelimit.Limiter, maxOperations int) *BatchHandler {
+	return &BatchHandler{
+		app:           app,
+		tx:            tx,
+		limiter:       limiter,
+		maxOperations: maxOperations,
+	}
+}
+
+// Batch godoc
+// @Summary      Executa várias operações em uma única requisição
+// @Description  Endpoint para executar uma lista de operações sobre as rotas de `/clientes`. Cada operação informa `method`, `path`, `body` e, opcionalmente, `headers` (por exemplo `If-Match`). O caminho e o corpo podem referenciar campos de respostas anteriores no formato `{{índice.campo}}`, como `{{0.id}}`. Com `atomic: true` todas as operações rodam em uma única transação, desfeita se alguma falhar; as operações seguintes à falha não são executadas e retornam 424. Cada operação conta no limite de requisições da sua rota antes de o lote começar; se alguma exceder o limite, nenhuma é executada e o lote retorna 429.
+// @Tags         Lote
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.BatchRequest  true  "Operações do lote"
+// @Success      200  {object}  types.BatchResponse
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      429  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /batch [post]
+func (h *BatchHandler) Batch(c *fiber.Ctx) error {
+	var req types.BatchRequest
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Corpo da requisição inválido"})
+	}
+	if err := req.IsValid(&req, h.maxOperations); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+	if ok, err := middleware.RateLimitOperations(c, h.limiter, req.Operations); !ok {
+		return err
+	}
+
+	responses := make([]types.BatchResponseItem, 0, len(req.Operations))
+	run := func(ctx context.Context) error {
+		for _, op := range req.Operations {
+			res := h.dispatch(c, ctx, op, responses)
+			responses = append(responses, res)
+			if req.Atomic && res.Status >= fiber.StatusBadRequest {
+				return errBatchAborted
+			}
+		}
+		return nil
+	}
+
+	if !req.Atomic {
+		run(c.UserContext())
+		return c.JSON(types.BatchResponse{Responses: responses})
+	}
+
+	err := h.tx.WithinTransaction(c.UserContext(), run)
+	if err != nil && !errors.Is(err, errBatchAborted) {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	rolledBack := err != nil
+	for len(responses) < len(req.Operations) {
+		responses = append(responses, batchError(fiber.StatusFailedDependency, "BATCH_ABORTED", "Operação não executada porque uma operação anterior do lote falhou"))
+	}
+	return c.JSON(types.BatchResponse{Atomic: true, RolledBack: rolledBack, Responses: responses})
+}
+
+// dispatch runs op through the application's own router, so sub-requests go
+// through the same middlewares, validation and handlers as regular requests.
+func (h *BatchHandler) dispatch(c *fiber.Ctx, ctx context.Context, op types.BatchOperation, previous []types.BatchResponseItem) types.BatchResponseItem {
+	path, err := resolveReferences(op.Path, previous, url.PathEscape)
+	if err != nil {
+		return batchError(fiber.StatusBadRequest, "INVALID_REFERENCE", err.Error())
+	}
+	body, err := resolveReferences(string(op.Body), previous, jsonEscape)
+	if err != nil {
+		return batchError(fiber.StatusBadRequest, "INVALID_REFERENCE", err.Error())
+	}
+
+	var sub fasthttp.Request
+	sub.Header.SetMethod(op.Method)
+	sub.SetRequestURI(path)
+	sub.Header.SetHost(string(c.Request().Host()))
+	for k, v := range op.Headers {
+		sub.Header.Set(k, v)
+	}
+	if body != "" && body != "null" {
+		sub.Header.SetContentType(fiber.MIMEApplicationJSON)
+		sub.SetBodyString(body)
+	}
+
+	var rctx fasthttp.RequestCtx
+	rctx.Init(&sub, c.Context().RemoteAddr(), nil)
+	ctx, cancel := context.WithTimeout(ctx, batchOperationTimeout)
+	defer cancel()
+	middleware.WithParentContext(&rctx, ctx)
+
+	h.once.Do(func() { h.handler = h.app.Handler() })
+	h.handler(&rctx)
+
+	res := types.BatchResponseItem{Status: rctx.Response.StatusCode()}
+	for _, name := range batchResponseHeaders {
+		if v := rctx.Response.Header.Peek(name); len(v) > 0 {
+			if res.Headers == nil {
+				res.Headers = map[string]string{}
+			}
+			res.Headers[name] = string(v)
+		}
+	}
+	if out := rctx.Response.Body(); len(out) > 0 {
+		if json.Valid(out) {
+			res.Body = append(json.RawMessage(nil), out...)
+		} else {
+			res.Body, _ = json.Marshal(string(out))
+		}
+	}
+	return res
+}
+
+// resolveReferences replaces {{index.field}} placeholders in s with values
+// taken from the bodies of earlier responses, encoded by escape.
+func resolveReferences(s string, previous []types.BatchResponseItem, escape func(string) string) (string, error) {
+	var failed error
+	out := batchReference.ReplaceAllStringFunc(s, func(m string) string {
+		parts := batchReference.FindStringSubmatch(m)
+		idx, _ := strconv.Atoi(parts[1])
+		if idx >= len(previous) {
+			failed = fmt.Errorf("referência %s aponta para uma operação posterior", m)
+			return m
+		}
+
+		var value any
+		if err := json.Unmarshal(previous[idx].Body, &value); err != nil {
+			failed = fmt.Errorf("referência %s não pôde ser resolvida", m)
+			return m
+		}
+		for _, key := range strings.Split(parts[2], ".") {
+			obj, ok := value.(map[string]any)
+			if !ok {
+				value = nil
+				break
+			}
+			value = obj[key]
+		}
+		if value == nil {
+			failed = fmt.Errorf("referência %s não pôde ser resolvida", m)
+			return m
+		}
+		return escape(fmt.Sprint(value))
+	})
+	return out, failed
+}
+
+// jsonEscape escapes s for use inside a JSON string literal.
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+func batchError(status int, code, message string) types.BatchResponseItem {
+	body, _ := json.Marshal(types.ErrorResponse{Code: code, Message: message})
+	return types.BatchResponseItem{Status: status, Body: body}
+}
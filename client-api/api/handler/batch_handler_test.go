@@ -0,0 +1,172 @@
+//go:build unit
+
+package handler
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"case-itau/api/middleware"
+	"case-itau/api/types"
+	repo "case-itau/repositories"
+	"case-itau/services/customer/customertest"
+	"case-itau/services/stream"
+)
+
+func TestCases_BatchReferences_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success resolving references to earlier responses", testResolveReferences},
+		{"Failure resolving references to later or missing fields", testResolveInvalidReferences},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func previousResponses() []types.BatchResponseItem {
+	return []types.BatchResponseItem{
+		{Status: 201, Body: json.RawMessage(`{"id":"abc","name":"John \"JD\" Doe","meta":{"version":2}}`)},
+	}
+}
+
+func testResolveReferences(t *testing.T) {
+	t.Log("testResolveReferences - Testing a success clause for path and body placeholders")
+	path, err := resolveReferences("/clientes/{{0.id}}/depositar", previousResponses(), url.PathEscape)
+	assert.NoError(t, err)
+	assert.Equal(t, "/clientes/abc/depositar", path)
+
+	body, err := resolveReferences(`{"name":"{{0.name}}","version":{{0.meta.version}}}`, previousResponses(), jsonEscape)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"John \"JD\" Doe","version":2}`, body)
+}
+
+func testResolveInvalidReferences(t *testing.T) {
+	t.Log("testResolveInvalidReferences - Testing a failure clause for unresolvable placeholders")
+	for _, s := range []string{"/clientes/{{1.id}}", "/clientes/{{0.missing}}", "/clientes/{{0.id.nested}}"} {
+		_, err := resolveReferences(s, previousResponses(), url.PathEscape)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestCases_Batch_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Failure in an atomic batch rolls back earlier operations and skips later ones", testAtomicBatchRollback},
+		{"Failure dispatching event streams in any letter case", testBatchRefusesStreams},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testAtomicBatchRollback(t *testing.T) {
+	t.Log("testAtomicBatchRollback - Testing a failure clause for an atomic batch whose third operation fails")
+	cf := customertest.New(t)
+	existing := cf.Create(t, "John Doe", "john.doe@example.com")
+	path := "/clientes/" + existing.ID.String()
+
+	h := NewCustomerHandler(cf.Customers, nil)
+	app := fiber.New()
+	app.Use(middleware.RequestMetadata())
+	app.Post("/batch", NewBatchHandler(app, cf.Tx, nil, 10).Batch)
+	app.Get("/clientes/:id", h.Get)
+	app.Post("/clientes", h.Create)
+	app.Post("/clientes/:id/depositar", h.Deposit)
+	app.Post("/clientes/:id/sacar", h.Withdraw)
+
+	batch := `{"atomic":true,"operations":[
+		{"method":"POST","path":"` + path + `/depositar","body":{"amount":50}},
+		{"method":"POST","path":"/clientes","body":{"name":"Jane Doe","email":"jane.doe@example.com"}},
+		{"method":"POST","path":"` + path + `/sacar","body":{"amount":1000}},
+		{"method":"POST","path":"/clientes/{{1.id}}/depositar","body":{"amount":10}}
+	]}`
+	resp := send(t, app, fiber.MethodPost, "/batch", batch, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var out types.BatchResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	assert.True(t, out.Atomic)
+	assert.True(t, out.RolledBack)
+	require.Len(t, out.Responses, 4)
+	assert.Equal(t, fiber.StatusOK, out.Responses[0].Status)
+	assert.Equal(t, fiber.StatusCreated, out.Responses[1].Status)
+	assert.Equal(t, fiber.StatusBadRequest, out.Responses[2].Status)
+	assert.Equal(t, fiber.StatusFailedDependency, out.Responses[3].Status)
+	var aborted types.ErrorResponse
+	require.NoError(t, json.Unmarshal(out.Responses[3].Body, &aborted))
+	assert.Equal(t, "BATCH_ABORTED", aborted.Code)
+
+	resp = send(t, app, fiber.MethodGet, path, "", nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var cust types.CustomerDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&cust))
+	assert.True(t, cust.Balance.IsZero(), "the deposit is rolled back")
+	assert.Equal(t, int64(1), cust.Version)
+
+	var created types.CustomerDto
+	require.NoError(t, json.Unmarshal(out.Responses[1].Body, &created))
+	resp = send(t, app, fiber.MethodGet, "/clientes/"+created.ID.String(), "", nil)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "the created customer is rolled back")
+}
+
+func testBatchRefusesStreams(t *testing.T) {
+	t.Log("testBatchRefusesStreams - Testing a failure clause for sub-requests to the event streams, which would never end")
+	cf := customertest.New(t)
+	cust := cf.Create(t, "John Doe", "john.doe@example.com")
+	path := "/clientes/" + cust.ID.String()
+
+	h := NewCustomerHandler(cf.Customers, nil)
+	streams := NewStreamHandler(stream.NewBroker(repo.NewGormRepository[repo.OutboxEvent](cf.DB), 5), cf.Customers, time.Second)
+	app := fiber.New()
+	app.Use(middleware.RequestMetadata())
+	app.Post("/batch", NewBatchHandler(app, cf.Tx, nil, 10).Batch)
+	app.Get("/clientes/:id", h.Get)
+	app.Post("/clientes/:id/depositar", h.Deposit)
+	app.Get("/clientes/:id/eventos", streams.SSE)
+	app.Get("/clientes/:id/eventos/ws", streams.UpgradeEvents, streams.WebSocket())
+
+	batch := `{"atomic":true,"operations":[
+		{"method":"POST","path":"` + path + `/depositar","body":{"amount":50}},
+		{"method":"GET","path":"` + path + `/EVENTOS"},
+		{"method":"GET","path":"` + path + `/Eventos/WS","headers":{"Connection":"Upgrade","Upgrade":"websocket"}}
+	]}`
+	resp := send(t, app, fiber.MethodPost, "/batch", batch, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var out types.BatchResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	assert.True(t, out.RolledBack)
+	require.Len(t, out.Responses, 3)
+	assert.Equal(t, fiber.StatusBadRequest, out.Responses[1].Status)
+	assert.Equal(t, fiber.StatusFailedDependency, out.Responses[2].Status)
+
+	batch = `{"operations":[{"method":"GET","path":"` + path + `/Eventos/WS","headers":{"Connection":"Upgrade","Upgrade":"websocket"}}]}`
+	resp = send(t, app, fiber.MethodPost, "/batch", batch, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	out = types.BatchResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Len(t, out.Responses, 1)
+	assert.Equal(t, fiber.StatusBadRequest, out.Responses[0].Status)
+
+	resp = send(t, app, fiber.MethodPost, path+"/depositar", `{"amount":10}`, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode, "the batch left no transaction open")
+}
@@ -13,15 +13,19 @@ import (
 	"case-itau/api/types"
 	repo "case-itau/repositories"
 	"case-itau/services/customer"
+	"case-itau/services/stepup"
+	"case-itau/utils/requestctx"
 )
 
 type CustomerHandler struct {
 	service *customer.Service
+	stepUp  *stepup.Service
 }
 
-func NewCustomerHandler(s *customer.Service) *CustomerHandler {
+func NewCustomerHandler(s *customer.Service, stepUp *stepup.Service) *CustomerHandler {
 	return &CustomerHandler{
 		service: s,
+		stepUp:  stepUp,
 	}
 }
 
@@ -33,7 +37,10 @@ func NewCustomerHandler(s *customer.Service) *CustomerHandler {
 // @Produce      json
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
 // @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
 // @Router       /clientes [get]
 func (h *CustomerHandler) List(c *fiber.Ctx) error {
 	list, err := h.service.ListAll(c.UserContext())
@@ -44,7 +51,7 @@ func (h *CustomerHandler) List(c *fiber.Ctx) error {
 	out := make([]types.CustomerDto, 0, len(list))
 	for _, it := range list {
 		out = append(out, types.CustomerDto{
-			ID: it.ID, Name: it.Name, Email: it.Email, Balance: it.Balance,
+			ID: it.ID, Name: it.Name, Email: it.Email, Balance: it.Balance, Version: it.Version,
 		})
 	}
 	return c.JSON(out)
@@ -56,10 +63,15 @@ func (h *CustomerHandler) List(c *fiber.Ctx) error {
 // @Tags         Clientes
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "ID do usuário"
+// @Param        id             path      string  true   "ID do usuário"
+// @Param        If-None-Match  header    string  false  "ETag conhecido pelo cliente"
 // @Success      200  {object}  types.CustomerDto
+// @Success      304  {object}  nil
 // @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
 // @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
 // @Router       /clientes/{id} [get]
 func (h *CustomerHandler) Get(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -71,8 +83,11 @@ func (h *CustomerHandler) Get(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
 	}
-	out := types.CustomerDto{ID: cust.ID, Name: cust.Name, Email: cust.Email, Balance: cust.Balance}
-	return c.JSON(out)
+	if notModified(c, cust.Version) {
+		c.Set(fiber.HeaderETag, etag(cust.Version))
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return sendCustomer(c, cust)
 }
 
 // CreateCustomer godoc
@@ -84,7 +99,10 @@ func (h *CustomerHandler) Get(c *fiber.Ctx) error {
 // @Param        customer  body      types.CreateCustomerRequest  true  "Dados do usuário"
 // @Success      201  {object}  types.CustomerDto
 // @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
 // @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
 // @Router       /clientes [post]
 func (h *CustomerHandler) Create(c *fiber.Ctx) error {
 	req := &types.CreateCustomerRequest{}
@@ -110,9 +128,7 @@ func (h *CustomerHandler) Create(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
 	}
-	out := types.CustomerDto{ID: created.ID, Name: created.Name, Email: created.Email, Balance: created.Balance}
-
-	return c.Status(fiber.StatusCreated).JSON(out)
+	return sendCustomer(c.Status(fiber.StatusCreated), &created)
 }
 
 // UpdateCustomer godoc
@@ -122,16 +138,64 @@ func (h *CustomerHandler) Create(c *fiber.Ctx) error {
 // @Accept       json
 // @Produce      json
 // @Param        id        path      string  true  "ID do usuário"
+// @Param        If-Match  header    string  false "ETag esperado do usuário"
 // @Param        customer  body      types.UpdateCustomerRequest  true  "Dados do usuário"
 // @Success      200  {object}  types.CustomerDto
 // @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      412  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
 // @Router       /clientes/{id} [put]
 func (h *CustomerHandler) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return preconditionFailed(c)
+	}
+
 	req := &types.CreateCustomerRequest{}
-	err := req.FromBody(c)
+	err = req.FromBody(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	in := repo.Customers{Name: req.Name, Email: req.Email}
+	return h.update(c, id, version, in)
+}
+
+// PatchCustomer godoc
+// @Summary      Atualiza parcialmente um usuário existente
+// @Description  Endpoint para atualizar apenas os campos informados de um usuário existente
+// @Tags         Clientes
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true  "ID do usuário"
+// @Param        If-Match  header    string  false "ETag esperado do usuário"
+// @Param        customer  body      types.PatchCustomerRequest  true  "Campos a alterar"
+// @Success      200  {object}  types.CustomerDto
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /clientes/{id} [patch]
+func (h *CustomerHandler) Patch(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return preconditionFailed(c)
+	}
+
+	req := &types.PatchCustomerRequest{}
+	err = req.FromBody(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
 	}
@@ -140,18 +204,24 @@ func (h *CustomerHandler) Update(c *fiber.Ctx) error {
 	}
 
 	in := repo.Customers{Name: req.Name, Email: req.Email}
-	updated, err := h.service.Update(c.UserContext(), id, in)
+	return h.update(c, id, version, in)
+}
+
+func (h *CustomerHandler) update(c *fiber.Ctx, id string, version int64, in repo.Customers) error {
+	updated, err := h.service.Update(c.UserContext(), id, version, in)
 	if err != nil {
 		if errors.Is(err, customer.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "CUSTOMER_NOT_FOUND", Message: "Cliente não encontrado"})
 		}
+		if errors.Is(err, customer.ErrVersionMismatch) {
+			return preconditionFailed(c)
+		}
 		if errors.Is(err, customer.ErrUniqueEmail) {
 			return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "EMAIL_ALREADY_EXISTS", Message: "Email já cadastrado"})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
 	}
-	out := types.CustomerDto{ID: updated.ID, Name: updated.Name, Email: updated.Email, Balance: updated.Balance}
-	return c.JSON(out)
+	return sendCustomer(c, updated)
 }
 
 // DeleteCustomer godoc
@@ -160,18 +230,31 @@ func (h *CustomerHandler) Update(c *fiber.Ctx) error {
 // @Tags         Clientes
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "ID do usuário"
+// @Param        id        path      string  true  "ID do usuário"
+// @Param        If-Match  header    string  false "ETag esperado do usuário"
 // @Success      204  {object}  nil
 // @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      412  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
 // @Router       /clientes/{id} [delete]
 func (h *CustomerHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	if err := h.service.Delete(c.UserContext(), id); err != nil {
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return preconditionFailed(c)
+	}
+
+	if err := h.service.Delete(c.UserContext(), id, version); err != nil {
 		if errors.Is(err, customer.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "CUSTOMER_NOT_FOUND", Message: "Cliente não encontrado"})
 		}
+		if errors.Is(err, customer.ErrVersionMismatch) {
+			return preconditionFailed(c)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
 	}
 	return c.SendStatus(fiber.StatusNoContent)
@@ -184,16 +267,26 @@ func (h *CustomerHandler) Delete(c *fiber.Ctx) error {
 // @Accept       json
 // @Produce      json
 // @Param        id        path      string  true  "ID do usuário"
+// @Param        If-Match  header    string  false "ETag esperado do usuário"
 // @Param        deposit  body      types.TransactionRequest  true  "Dados do depósito"
 // @Success      200  {object}  types.CustomerDto
 // @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      412  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
 // @Router       /clientes/{id}/depositar [post]
 func (h *CustomerHandler) Deposit(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return preconditionFailed(c)
+	}
+
 	req := &types.TransactionRequest{}
-	err := req.FromBody(c)
+	err = req.FromBody(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
 	}
@@ -201,7 +294,7 @@ func (h *CustomerHandler) Deposit(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
 	}
 
-	cust, err := h.service.Transactions(c.UserContext(), id, req.Amount)
+	cust, err := h.service.Transactions(c.UserContext(), id, version, req.Amount)
 	if err != nil {
 		if errors.Is(err, customer.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "CUSTOMER_NOT_FOUND", Message: "Cliente não encontrado"})
@@ -209,29 +302,42 @@ func (h *CustomerHandler) Deposit(c *fiber.Ctx) error {
 		if errors.Is(err, customer.ErrInsufficientFunds) {
 			return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INSUFICIENT_BALANCE", Message: "Saldo insuficiente"})
 		}
+		if errors.Is(err, customer.ErrVersionMismatch) {
+			return preconditionFailed(c)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
 	}
-	out := types.CustomerDto{ID: cust.ID, Name: cust.Name, Email: cust.Email, Balance: cust.Balance}
-	return c.JSON(out)
+	return sendCustomer(c, cust)
 }
 
 // WithdrawCustomer godoc
 // @Summary      Saca um valor da conta do usuário
-// @Description  Endpoint para sacar um valor da conta do usuário
+// @Description  Endpoint para sacar um valor da conta do usuário. Quando o próprio cliente saca um valor acima do limite configurado, o código do autenticador TOTP (`totp_code`) é obrigatório.
 // @Tags         Transações
 // @Accept       json
 // @Produce      json
 // @Param        id        path      string  true  "ID do usuário"
+// @Param        If-Match  header    string  false "ETag esperado do usuário"
 // @Param        withdraw  body      types.TransactionRequest  true  "Dados do saque"
 // @Success      200  {object}  types.CustomerDto
 // @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      423  {object}  types.ErrorResponse
 // @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
 // @Router       /clientes/{id}/sacar [post]
 func (h *CustomerHandler) Withdraw(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return preconditionFailed(c)
+	}
+
 	req := &types.TransactionRequest{}
-	err := req.FromBody(c)
+	err = req.FromBody(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
 	}
@@ -239,7 +345,15 @@ func (h *CustomerHandler) Withdraw(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
 	}
 
-	cust, err := h.service.Transactions(c.UserContext(), id, req.Amount.Neg())
+	// Staff and API keys are bound by their roles and scopes; the step-up
+	// protects customers whose own session could be hijacked.
+	if requestctx.FromContext(c.UserContext()).Actor == id {
+		if err := h.stepUp.Check(c.UserContext(), id, req.Amount, req.TOTPCode); err != nil {
+			return stepUpError(c, err)
+		}
+	}
+
+	cust, err := h.service.Transactions(c.UserContext(), id, version, req.Amount.Neg())
 	if err != nil {
 		if errors.Is(err, customer.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "CUSTOMER_NOT_FOUND", Message: "Cliente não encontrado"})
@@ -247,10 +361,12 @@ func (h *CustomerHandler) Withdraw(c *fiber.Ctx) error {
 		if errors.Is(err, customer.ErrInsufficientFunds) {
 			return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INSUFICIENT_BALANCE", Message: "Saldo insuficiente"})
 		}
+		if errors.Is(err, customer.ErrVersionMismatch) {
+			return preconditionFailed(c)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
 	}
-	out := types.CustomerDto{ID: cust.ID, Name: cust.Name, Email: cust.Email, Balance: cust.Balance}
-	return c.JSON(out)
+	return sendCustomer(c, cust)
 }
 
 // GetTransactions retorna o histórico de transações de um cliente com paginação.
@@ -266,8 +382,11 @@ func (h *CustomerHandler) Withdraw(c *fiber.Ctx) error {
 // @Param        size  query     int    false "Itens por página (default: 10)"
 // @Success      200  {object}  map[string]interface{}  "Retorna metadados de paginação e a lista de transações"
 // @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
 // @Failure      404  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
 // @Router       /clientes/{id}/transacoes [get]
 func (h *CustomerHandler) GetTransactions(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -304,13 +423,17 @@ func (h *CustomerHandler) GetTransactions(c *fiber.Ctx) error {
 
 	out := make([]types.TransactionDto, 0, len(txs))
 	for _, t := range txs {
-		out = append(out, types.TransactionDto{
+		dto := types.TransactionDto{
 			TransactionID: t.TransactionID,
 			CustomerID:    t.CustomerID,
 			Amount:        t.Amount,
 			Type:          t.Type,
 			CreatedAt:     t.CreatedAt,
-		})
+		}
+		if t.Reference != nil {
+			dto.Reference = *t.Reference
+		}
+		out = append(out, dto)
 	}
 
 	return c.JSON(fiber.Map{
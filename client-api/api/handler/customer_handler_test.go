@@ -0,0 +1,149 @@
+//go:build unit
+
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"case-itau/api/types"
+	"case-itau/services/customer/customertest"
+)
+
+// newCustomerApp serves the customer routes over a fresh database holding one
+// customer, whose path it returns.
+func newCustomerApp(t *testing.T) (*fiber.App, string) {
+	cf := customertest.New(t)
+	cust := cf.Create(t, "John Doe", "john.doe@example.com")
+
+	h := NewCustomerHandler(cf.Customers, nil)
+	app := fiber.New()
+	app.Get("/clientes/:id", h.Get)
+	app.Put("/clientes/:id", h.Update)
+	app.Delete("/clientes/:id", h.Delete)
+	app.Post("/clientes/:id/depositar", h.Deposit)
+	return app, "/clientes/" + cust.ID.String()
+}
+
+func send(t *testing.T, app *fiber.App, method, path, body string, headers map[string]string) *http.Response {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func decodeError(t *testing.T, resp *http.Response) types.ErrorResponse {
+	var body types.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body
+}
+
+func TestCases_CustomerHandler_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success answering 304 to a matching If-None-Match", testNotModified},
+		{"Success writing with a current If-Match and getting the next ETag", testIfMatchVersionBump},
+		{"Failure writing with a stale If-Match", testStaleIfMatch},
+		{"Failure writing with a malformed If-Match", testMalformedIfMatch},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testNotModified(t *testing.T) {
+	t.Log("testNotModified - Testing a success clause for a cached representation confirmed without a body")
+	app, path := newCustomerApp(t)
+
+	resp := send(t, app, fiber.MethodGet, path, "", nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get(fiber.HeaderETag))
+
+	for _, tag := range []string{`"1"`, `W/"1"`, `"7", "1"`, `*`} {
+		resp = send(t, app, fiber.MethodGet, path, "", map[string]string{fiber.HeaderIfNoneMatch: tag})
+		assert.Equal(t, fiber.StatusNotModified, resp.StatusCode, tag)
+		assert.Equal(t, `"1"`, resp.Header.Get(fiber.HeaderETag), tag)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Empty(t, body, tag)
+	}
+
+	resp = send(t, app, fiber.MethodGet, path, "", map[string]string{fiber.HeaderIfNoneMatch: `"2"`})
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func testIfMatchVersionBump(t *testing.T) {
+	t.Log("testIfMatchVersionBump - Testing a success clause for conditional writes returning the ETag of the version they made")
+	app, path := newCustomerApp(t)
+
+	resp := send(t, app, fiber.MethodPut, path, `{"name":"John Smith","email":"john.doe@example.com"}`, map[string]string{fiber.HeaderIfMatch: `"1"`})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get(fiber.HeaderETag))
+	var dto types.CustomerDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&dto))
+	assert.Equal(t, int64(2), dto.Version)
+
+	resp = send(t, app, fiber.MethodPost, path+"/depositar", `{"amount":10}`, map[string]string{fiber.HeaderIfMatch: `"2"`})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"3"`, resp.Header.Get(fiber.HeaderETag))
+
+	resp = send(t, app, fiber.MethodPost, path+"/depositar", `{"amount":10}`, map[string]string{fiber.HeaderIfMatch: `*`})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode, "the wildcard matches any version")
+	assert.Equal(t, `"4"`, resp.Header.Get(fiber.HeaderETag))
+
+	resp = send(t, app, fiber.MethodDelete, path, "", map[string]string{fiber.HeaderIfMatch: `"4"`})
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+}
+
+func testStaleIfMatch(t *testing.T) {
+	t.Log("testStaleIfMatch - Testing a failure clause for writes conditioned on a version the customer moved past")
+	app, path := newCustomerApp(t)
+	resp := send(t, app, fiber.MethodPost, path+"/depositar", `{"amount":10}`, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	for _, req := range []struct{ method, path, body string }{
+		{fiber.MethodPut, path, `{"name":"John Smith","email":"john.doe@example.com"}`},
+		{fiber.MethodPost, path + "/depositar", `{"amount":10}`},
+		{fiber.MethodDelete, path, ""},
+	} {
+		resp = send(t, app, req.method, req.path, req.body, map[string]string{fiber.HeaderIfMatch: `"1"`})
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode, req.method+" "+req.path)
+		assert.Equal(t, "PRECONDITION_FAILED", decodeError(t, resp).Code)
+	}
+
+	resp = send(t, app, fiber.MethodGet, path, "", nil)
+	assert.Equal(t, `"2"`, resp.Header.Get(fiber.HeaderETag), "rejected writes change nothing")
+}
+
+func testMalformedIfMatch(t *testing.T) {
+	t.Log("testMalformedIfMatch - Testing a failure clause for weak, unquoted and non-numeric entity tags")
+	app, path := newCustomerApp(t)
+
+	for _, tag := range []string{`W/"1"`, `1`, `"abc"`, `"0"`} {
+		resp := send(t, app, fiber.MethodPut, path, `{"name":"John Smith","email":"john.doe@example.com"}`, map[string]string{fiber.HeaderIfMatch: tag})
+		assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode, tag)
+		assert.Equal(t, "PRECONDITION_FAILED", decodeError(t, resp).Code, tag)
+	}
+
+	resp := send(t, app, fiber.MethodGet, path, "", nil)
+	assert.Equal(t, `"1"`, resp.Header.Get(fiber.HeaderETag))
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"case-itau/api/types"
+	repo "case-itau/repositories"
+)
+
+var errInvalidPrecondition = errors.New("cabeçalho If-Match inválido")
+
+// etag renders a customer version as a strong entity tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseETag extracts the version from a strong entity tag. Weak tags never
+// satisfy If-Match, so they are rejected here as well.
+func parseETag(tag string) (int64, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// ifMatchVersion returns the version the client expects the customer to be at,
+// or zero when the request carries no If-Match header or uses the "*" wildcard.
+func ifMatchVersion(c *fiber.Ctx) (int64, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	version, ok := parseETag(header)
+	if !ok {
+		return 0, errInvalidPrecondition
+	}
+	return version, nil
+}
+
+// notModified reports whether any tag in If-None-Match matches the current
+// version. Weak comparison applies, so W/ prefixes are ignored.
+func notModified(c *fiber.Ctx, version int64) bool {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfNoneMatch))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if v, ok := parseETag(tag); ok && v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// sendCustomer writes the customer as JSON along with its ETag.
+func sendCustomer(c *fiber.Ctx, cust *repo.Customers) error {
+	c.Set(fiber.HeaderETag, etag(cust.Version))
+	return c.JSON(types.CustomerDto{ID: cust.ID, Name: cust.Name, Email: cust.Email, Balance: cust.Balance, Version: cust.Version})
+}
+
+func preconditionFailed(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusPreconditionFailed).JSON(types.ErrorResponse{Code: "PRECONDITION_FAILED", Message: "O cliente foi alterado por outra operação. Recarregue os dados e tente novamente"})
+}
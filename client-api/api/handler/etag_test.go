@@ -0,0 +1,41 @@
+//go:build unit
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCases_ETag_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success parsing a strong entity tag", testParseStrongETag},
+		{"Failure parsing weak or malformed entity tags", testParseInvalidETag},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testParseStrongETag(t *testing.T) {
+	t.Log("testParseStrongETag - Testing a success clause for parsing the tag rendered by etag")
+	version, ok := parseETag(etag(42))
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), version)
+}
+
+func testParseInvalidETag(t *testing.T) {
+	t.Log("testParseInvalidETag - Testing a failure clause for parsing weak and malformed tags")
+	for _, tag := range []string{`W/"1"`, `1`, `"abc"`, `"0"`, `""`} {
+		_, ok := parseETag(tag)
+		assert.False(t, ok, tag)
+	}
+}
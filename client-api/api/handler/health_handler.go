@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"case-itau/api/types"
+	"case-itau/services/health"
+)
+
+type HealthHandler struct {
+	service *health.Service
+}
+
+func NewHealthHandler(s *health.Service) *HealthHandler {
+	return &HealthHandler{
+		service: s,
+	}
+}
+
+// Live godoc
+// @Summary      Verifica se a API está no ar
+// @Description  Endpoint de liveness: responde 200 enquanto o processo consegue atender requisições, sem consultar dependências.
+// @Tags         Saúde
+// @Produce      json
+// @Success      200  {object}  types.HealthResponse
+// @Router       /health/live [get]
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
+	return c.JSON(types.HealthResponse{Status: health.StatusOK})
+}
+
+// Ready godoc
+// @Summary      Verifica se a API está pronta para receber tráfego
+// @Description  Endpoint de readiness: verifica a conexão com o banco, as migrações aplicadas e se o disco aceita escrita, informando apenas o status e a latência de cada componente; o resultado é reaproveitado por alguns segundos. Responde 503 quando algum componente falha ou durante o desligamento.
+// @Tags         Saúde
+// @Produce      json
+// @Success      200  {object}  types.HealthResponse
+// @Failure      503  {object}  types.HealthResponse
+// @Router       /health/ready [get]
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	report := h.service.Ready(c.UserContext())
+
+	resp := types.HealthResponse{
+		Status:     report.Status,
+		Components: make(map[string]types.HealthComponentResponse, len(report.Components)),
+	}
+	for name, comp := range report.Components {
+		resp.Components[name] = types.HealthComponentResponse{
+			Status:    comp.Status,
+			LatencyMs: float64(comp.Latency.Microseconds()) / 1000,
+		}
+	}
+
+	status := fiber.StatusOK
+	if !report.Ready() {
+		status = fiber.StatusServiceUnavailable
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(resp)
+}
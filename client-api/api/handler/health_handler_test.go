@@ -0,0 +1,54 @@
+//go:build unit
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"case-itau/services/health"
+	l "case-itau/utils/logger"
+)
+
+func TestCases_HealthHandler_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Failure reporting a component down without its error", testReadyHidesErrors},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testReadyHidesErrors(t *testing.T) {
+	t.Log("testReadyHidesErrors - Testing a failure clause for a public report that must not expose check errors")
+	l.Logger = zap.NewNop()
+	svc := health.NewService(time.Second, time.Second)
+	svc.Register("database", func(context.Context) error {
+		return errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	})
+	app := fiber.New()
+	app.Get("/health/ready", NewHealthHandler(svc).Ready)
+
+	resp := send(t, app, fiber.MethodGet, "/health/ready", "", nil)
+	require.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"database":{"status":"down"`)
+	assert.NotContains(t, string(body), "10.0.0.5")
+	assert.NotContains(t, string(body), "error")
+}
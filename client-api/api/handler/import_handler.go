@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+
+	"case-itau/api/types"
+	repo "case-itau/repositories"
+	"case-itau/services/importer"
+)
+
+type ImportHandler struct {
+	service *importer.Service
+}
+
+func NewImportHandler(s *importer.Service) *ImportHandler {
+	return &ImportHandler{
+		service: s,
+	}
+}
+
+// ImportTransactions godoc
+// @Summary      Importa transações em lote a partir de um CSV
+// @Description  Endpoint para aplicar depósitos e saques em lote. O CSV deve ter cabeçalho com as colunas `customer` (ID ou e-mail), `type` (deposit ou withdraw), `amount` e, opcionalmente, `reference`. Envie o arquivo no campo `file` de um formulário multipart ou diretamente no corpo com `Content-Type: text/csv`. No modo `all_or_nothing` nenhuma linha é aplicada se alguma falhar; no modo `best_effort` (padrão) cada linha válida é aplicada independentemente. Cada `reference` é aplicada uma única vez: linhas cuja referência já foi importada retornam o status `duplicate_reference`.
+// @Tags         Transações
+// @Accept       multipart/form-data
+// @Accept       text/csv
+// @Produce      json
+// @Param        file  formData  file    false "Arquivo CSV"
+// @Param        mode  query     string  false "Modo de importação (best_effort ou all_or_nothing)"
+// @Success      200  {object}  map[string]interface{}  "Retorna o ID da importação e o resultado de cada linha"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /transacoes/lote [post]
+func (h *ImportHandler) Import(c *fiber.Ctx) error {
+	mode := c.Query("mode", importer.ModeBestEffort)
+	if mode != importer.ModeBestEffort && mode != importer.ModeAllOrNothing {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Modo deve ser best_effort ou all_or_nothing"})
+	}
+
+	file, err := csvBody(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Envie o arquivo CSV no campo file ou no corpo da requisição"})
+	}
+
+	rows, err := types.ParseTransactionBatch(file)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_CSV", Message: err.Error()})
+	}
+
+	job, results, err := h.service.Import(c.UserContext(), mode, rows)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	return c.JSON(importReport(job, results))
+}
+
+// GetImport godoc
+// @Summary      Consulta uma importação de transações
+// @Description  Endpoint para obter o resultado de uma importação em lote pelo ID
+// @Tags         Transações
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID da importação"
+// @Success      200  {object}  map[string]interface{}  "Retorna o resumo da importação e o resultado de cada linha"
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /transacoes/lote/{id} [get]
+func (h *ImportHandler) Get(c *fiber.Ctx) error {
+	job, results, err := h.service.Get(c.UserContext(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, importer.ErrJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "IMPORT_NOT_FOUND", Message: "Importação não encontrada"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	return c.JSON(importReport(job, results))
+}
+
+// csvBody returns the uploaded file, falling back to the raw request body.
+func csvBody(c *fiber.Ctx) (io.Reader, error) {
+	if fh, err := c.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+
+	if len(c.Body()) == 0 {
+		return nil, errors.New("empty body")
+	}
+	return bytes.NewReader(c.Body()), nil
+}
+
+func importReport(job *repo.ImportJob, results []importer.RowResult) fiber.Map {
+	return fiber.Map{
+		"job_id":     job.ID,
+		"mode":       job.Mode,
+		"status":     job.Status,
+		"total_rows": job.TotalRows,
+		"succeeded":  job.Succeeded,
+		"failed":     job.Failed,
+		"created_at": job.CreatedAt,
+		"items":      results,
+	}
+}
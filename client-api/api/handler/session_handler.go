@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"case-itau/api/types"
+	"case-itau/services/customer"
+	"case-itau/services/session"
+	"case-itau/utils/requestctx"
+)
+
+type SessionHandler struct {
+	service *session.Service
+}
+
+func NewSessionHandler(s *session.Service) *SessionHandler {
+	return &SessionHandler{
+		service: s,
+	}
+}
+
+// Login godoc
+// @Summary      Autentica um cliente
+// @Description  Endpoint para o cliente entrar com e-mail e senha. Retorna um access token de curta duração e um refresh token de uso único. Após tentativas inválidas seguidas a conta é bloqueada temporariamente.
+// @Tags         Autenticação
+// @Accept       json
+// @Produce      json
+// @Param        credentials  body      types.LoginRequest  true  "Credenciais"
+// @Success      200  {object}  types.TokenResponse
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      423  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Failure      503  {object}  types.ErrorResponse
+// @Router       /auth/login [post]
+func (h *SessionHandler) Login(c *fiber.Ctx) error {
+	req := &types.LoginRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	tokens, err := h.service.Login(c.UserContext(), req.Email, req.Password, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return sessionError(c, err)
+	}
+	return c.JSON(toTokenResponse(tokens))
+}
+
+// Refresh godoc
+// @Summary      Renova os tokens de um cliente
+// @Description  Endpoint para trocar um refresh token por um novo par de tokens. Cada refresh token só pode ser usado uma vez; reutilizá-lo encerra a sessão.
+// @Tags         Autenticação
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.RefreshRequest  true  "Refresh token"
+// @Success      200  {object}  types.TokenResponse
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Failure      503  {object}  types.ErrorResponse
+// @Router       /auth/refresh [post]
+func (h *SessionHandler) Refresh(c *fiber.Ctx) error {
+	req := &types.RefreshRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	tokens, err := h.service.Refresh(c.UserContext(), req.RefreshToken)
+	if err != nil {
+		return sessionError(c, err)
+	}
+	return c.JSON(toTokenResponse(tokens))
+}
+
+// Logout godoc
+// @Summary      Encerra a sessão de um cliente
+// @Description  Endpoint para encerrar a sessão do refresh token informado
+// @Tags         Autenticação
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.RefreshRequest  true  "Refresh token"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /auth/logout [post]
+func (h *SessionHandler) Logout(c *fiber.Ctx) error {
+	req := &types.RefreshRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	if err := h.service.Logout(c.UserContext(), req.RefreshToken); err != nil {
+		return sessionError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListSessions godoc
+// @Summary      Lista as sessões do cliente autenticado
+// @Description  Endpoint para listar as sessões ativas do cliente autenticado
+// @Tags         Autenticação
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   types.SessionDto
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /auth/sessions [get]
+func (h *SessionHandler) List(c *fiber.Ctx) error {
+	list, err := h.service.List(c.UserContext(), requestctx.FromContext(c.UserContext()).Actor)
+	if err != nil {
+		return sessionError(c, err)
+	}
+
+	out := make([]types.SessionDto, 0, len(list))
+	for _, s := range list {
+		out = append(out, types.SessionDto{
+			ID:         s.ID,
+			ClientIP:   s.ClientIP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+		})
+	}
+	return c.JSON(out)
+}
+
+// RevokeSession godoc
+// @Summary      Revoga uma sessão do cliente autenticado
+// @Description  Endpoint para encerrar uma sessão do cliente autenticado, por exemplo a de um dispositivo perdido. Os tokens da sessão deixam de ser aceitos imediatamente.
+// @Tags         Autenticação
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID da sessão"
+// @Success      204  {object}  nil
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /auth/sessions/{id} [delete]
+func (h *SessionHandler) Revoke(c *fiber.Ctx) error {
+	if err := h.service.Revoke(c.UserContext(), requestctx.FromContext(c.UserContext()).Actor, c.Params("id")); err != nil {
+		return sessionError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SetPassword godoc
+// @Summary      Define a senha de um cliente
+// @Description  Endpoint para definir a senha usada pelo cliente no login. Quando o próprio cliente altera uma senha existente, a senha atual é obrigatória. As sessões abertas são encerradas e um eventual bloqueio é removido.
+// @Tags         Clientes
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                    true  "ID do usuário"
+// @Param        request  body      types.SetPasswordRequest  true  "Nova senha"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /clientes/{id}/senha [put]
+func (h *SessionHandler) SetPassword(c *fiber.Ctx) error {
+	req := &types.SetPasswordRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	id := c.Params("id")
+	selfService := requestctx.FromContext(c.UserContext()).Actor == id
+	if err := h.service.SetPassword(c.UserContext(), id, req.CurrentPassword, req.Password, selfService); err != nil {
+		return sessionError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func sessionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, session.ErrInvalidCredentials):
+		return c.Status(fiber.StatusUnauthorized).JSON(types.ErrorResponse{Code: "INVALID_CREDENTIALS", Message: "E-mail ou senha inválidos"})
+	case errors.Is(err, session.ErrAccountLocked):
+		return c.Status(fiber.StatusLocked).JSON(types.ErrorResponse{Code: "ACCOUNT_LOCKED", Message: "Conta bloqueada temporariamente após tentativas de login inválidas"})
+	case errors.Is(err, session.ErrInvalidRefreshToken):
+		return c.Status(fiber.StatusUnauthorized).JSON(types.ErrorResponse{Code: "INVALID_REFRESH_TOKEN", Message: "Refresh token inválido ou expirado"})
+	case errors.Is(err, session.ErrWrongPassword):
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "WRONG_PASSWORD", Message: "Senha atual incorreta"})
+	case errors.Is(err, session.ErrSessionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "SESSION_NOT_FOUND", Message: "Sessão não encontrada"})
+	case errors.Is(err, customer.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "CUSTOMER_NOT_FOUND", Message: "Cliente não encontrado"})
+	case errors.Is(err, session.ErrLoginUnavailable):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(types.ErrorResponse{Code: "LOGIN_UNAVAILABLE", Message: "Login de clientes não configurado"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+}
+
+func toTokenResponse(t *session.Tokens) types.TokenResponse {
+	return types.TokenResponse{
+		AccessToken:  t.AccessToken,
+		RefreshToken: t.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(t.ExpiresIn.Seconds()),
+		SessionID:    t.SessionID,
+		CustomerID:   t.CustomerID,
+	}
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"case-itau/api/middleware"
+	"case-itau/api/types"
+	"case-itau/services/customer"
+	"case-itau/services/events"
+	"case-itau/services/stream"
+	l "case-itau/utils/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	replayTimeout = 5 * time.Second
+	writeTimeout  = 10 * time.Second
+)
+
+// streamRequest is what the SSE and WebSocket endpoints read from a request.
+// Resume is set when the client supplied the ID of the last event it saw.
+type streamRequest struct {
+	CustomerID uuid.UUID
+	LastID     uint64
+	Resume     bool
+}
+
+type StreamHandler struct {
+	broker    *stream.Broker
+	customers *customer.Service
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(b *stream.Broker, customers *customer.Service, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{
+		broker:    b,
+		customers: customers,
+		heartbeat: heartbeat,
+	}
+}
+
+// StreamEvents godoc
+// @Summary      Acompanha os eventos de um usuário em tempo real
+// @Description  Stream Server-Sent Events com os eventos de saldo e transações do cliente assim que são confirmados. Envie `Last-Event-ID` (ou `last_event_id`) para retomar a partir do último evento recebido. Um comentário de heartbeat é enviado periodicamente.
+// @Tags         Eventos
+// @Produce      text/event-stream
+// @Param        id             path      string  true   "ID do usuário"
+// @Param        Last-Event-ID  header    string  false  "ID do último evento recebido"
+// @Success      200  {string}  string  "Stream de eventos"
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      429  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Failure      503  {object}  types.ErrorResponse
+// @Security     BearerAuth
+// @Router       /clientes/{id}/eventos [get]
+func (h *StreamHandler) SSE(c *fiber.Ctx) error {
+	req, ok, err := h.resolve(c)
+	if !ok {
+		return err
+	}
+
+	sub, err := h.broker.Subscribe(req.CustomerID, c.IP())
+	if err != nil {
+		return streamError(c, err)
+	}
+
+	backlog, err := h.replay(c.UserContext(), req)
+	if err != nil {
+		sub.Close()
+		return streamError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	heartbeat := h.heartbeat
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		send := func(e events.Event) error {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+			return w.Flush()
+		}
+
+		fmt.Fprintf(w, ": connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+		pump(sub, backlog, req.LastID, heartbeat, send, func() error {
+			fmt.Fprintf(w, ": heartbeat\n\n")
+			return w.Flush()
+		})
+	})
+	return nil
+}
+
+// UpgradeEvents rejects plain HTTP requests to the WebSocket endpoint and
+// checks that the customer exists before the connection is upgraded.
+func (h *StreamHandler) UpgradeEvents(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) && !middleware.IsInternal(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(types.ErrorResponse{Code: "UPGRADE_REQUIRED", Message: "Use uma conexão WebSocket"})
+	}
+	req, ok, err := h.resolve(c)
+	if !ok {
+		return err
+	}
+	c.Locals("streamRequest", req)
+	return c.Next()
+}
+
+// WebSocketEvents godoc
+// @Summary      Acompanha os eventos de um usuário via WebSocket
+// @Description  Equivalente WebSocket de /clientes/{id}/eventos. Cada mensagem é um evento em JSON; use `last_event_id` para retomar. O servidor envia pings periodicamente.
+// @Tags         Eventos
+// @Param        id             path      string  true   "ID do usuário"
+// @Param        last_event_id  query     int     false  "ID do último evento recebido"
+// @Success      101  {string}  string  "Switching Protocols"
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      426  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /clientes/{id}/eventos/ws [get]
+func (h *StreamHandler) WebSocket() fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		req := conn.Locals("streamRequest").(streamRequest)
+
+		sub, err := h.broker.Subscribe(req.CustomerID, conn.IP())
+		if err != nil {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()))
+			return
+		}
+		defer sub.Close()
+		defer conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+
+		ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+		backlog, err := h.replay(ctx, req)
+		cancel()
+		if err != nil {
+			l.Logger.Error("failed to replay events", zap.Error(err))
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "erro ao carregar eventos"))
+			return
+		}
+
+		// The read loop only notices the client going away; closing the
+		// subscription then ends pump.
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					sub.Close()
+					return
+				}
+			}
+		}()
+
+		pump(sub, backlog, req.LastID, h.heartbeat, func(e events.Event) error {
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			return conn.WriteJSON(e)
+		}, func() error {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+		})
+	})
+}
+
+// resolve validates the customer in the path and reads the resume position
+// from Last-Event-ID or the last_event_id query parameter. When ok is false
+// the error response has been written and err is what the handler returns.
+//
+// Batch sub-requests are refused: the batch waits for each response body, so
+// a stream would hold it, and the transaction of an atomic batch, forever.
+func (h *StreamHandler) resolve(c *fiber.Ctx) (req streamRequest, ok bool, err error) {
+	if middleware.IsInternal(c) {
+		return req, false, c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Streams de eventos não podem ser usados em lote"})
+	}
+
+	cust, err := h.customers.GetByID(c.UserContext(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, customer.ErrNotFound) {
+			return req, false, c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "CUSTOMER_NOT_FOUND", Message: "Cliente não encontrado"})
+		}
+		return req, false, c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	req.CustomerID = cust.ID
+
+	raw := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	if raw == "" {
+		return req, true, nil
+	}
+	if req.LastID, err = strconv.ParseUint(raw, 10, 64); err != nil {
+		return req, false, c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_LAST_EVENT_ID", Message: "Last-Event-ID inválido"})
+	}
+	req.Resume = true
+	return req, true, nil
+}
+
+// replay loads the events missed by a resuming client. New streams start
+// with live events only.
+func (h *StreamHandler) replay(ctx context.Context, req streamRequest) ([]events.Event, error) {
+	if !req.Resume {
+		return nil, nil
+	}
+	return h.broker.Replay(ctx, req.CustomerID, req.LastID)
+}
+
+// pump sends the backlog followed by live events, skipping anything at or
+// before the last event already sent, and calls ping when the connection has
+// been idle for a heartbeat interval. It returns when a write fails or the
+// subscription is closed.
+func pump(sub *stream.Subscription, backlog []events.Event, lastID uint64, heartbeat time.Duration, send func(events.Event) error, ping func() error) {
+	for _, e := range backlog {
+		if err := send(e); err != nil {
+			return
+		}
+		lastID = e.ID
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if e.ID <= lastID {
+				continue
+			}
+			if err := send(e); err != nil {
+				return
+			}
+			lastID = e.ID
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func streamError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, stream.ErrTooManyConnections) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(types.ErrorResponse{Code: "TOO_MANY_CONNECTIONS", Message: "Limite de conexões simultâneas atingido"})
+	}
+	if errors.Is(err, stream.ErrClosed) {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(types.ErrorResponse{Code: "SHUTTING_DOWN", Message: "Servidor em desligamento, tente novamente"})
+	}
+	l.FromContext(c.UserContext()).Error("failed to open event stream", zap.Error(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"case-itau/api/types"
+	"case-itau/services/customer"
+	"case-itau/services/stepup"
+	"case-itau/utils/requestctx"
+)
+
+type TOTPHandler struct {
+	service *stepup.Service
+}
+
+func NewTOTPHandler(s *stepup.Service) *TOTPHandler {
+	return &TOTPHandler{
+		service: s,
+	}
+}
+
+// EnrollTOTP godoc
+// @Summary      Cadastra um autenticador TOTP
+// @Description  Endpoint para gerar o segredo do autenticador usado para confirmar saques acima do limite. O cadastro só passa a valer depois de confirmado com um código; cadastrar novamente substitui um segredo ainda não confirmado.
+// @Tags         Clientes
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID do usuário"
+// @Success      201  {object}  types.TOTPEnrollmentResponse
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /clientes/{id}/totp [post]
+func (h *TOTPHandler) Enroll(c *fiber.Ctx) error {
+	enrollment, err := h.service.Enroll(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return stepUpError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(types.TOTPEnrollmentResponse{Secret: enrollment.Secret, ProvisioningURI: enrollment.URI})
+}
+
+// ConfirmTOTP godoc
+// @Summary      Confirma o autenticador TOTP
+// @Description  Endpoint para ativar o autenticador cadastrado informando um código gerado por ele
+// @Tags         Clientes
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                 true  "ID do usuário"
+// @Param        request  body      types.TOTPCodeRequest  true  "Código do autenticador"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /clientes/{id}/totp/confirmar [post]
+func (h *TOTPHandler) Confirm(c *fiber.Ctx) error {
+	req := &types.TOTPCodeRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	if err := h.service.Confirm(c.UserContext(), c.Params("id"), req.Code); err != nil {
+		return stepUpError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DisableTOTP godoc
+// @Summary      Remove o autenticador TOTP
+// @Description  Endpoint para remover o autenticador de um cliente. Quando o próprio cliente remove um autenticador confirmado, um código gerado por ele é obrigatório.
+// @Tags         Clientes
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                 true   "ID do usuário"
+// @Param        request  body      types.TOTPCodeRequest  false  "Código do autenticador"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      423  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /clientes/{id}/totp [delete]
+func (h *TOTPHandler) Disable(c *fiber.Ctx) error {
+	req := &types.TOTPCodeRequest{}
+	if len(c.Body()) > 0 {
+		if err := req.FromBody(c); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+		}
+		if err := req.IsValid(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+		}
+	}
+
+	id := c.Params("id")
+	selfService := requestctx.FromContext(c.UserContext()).Actor == id
+	if err := h.service.Disable(c.UserContext(), id, req.Code, selfService); err != nil {
+		return stepUpError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func stepUpError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, stepup.ErrStepUpRequired) && errors.Is(err, stepup.ErrNotEnrolled):
+		return c.Status(fiber.StatusForbidden).JSON(types.ErrorResponse{Code: "STEP_UP_REQUIRED", Message: "Cadastre e confirme um autenticador para realizar saques acima do limite"})
+	case errors.Is(err, stepup.ErrStepUpRequired):
+		return c.Status(fiber.StatusForbidden).JSON(types.ErrorResponse{Code: "STEP_UP_REQUIRED", Message: "Informe o código do autenticador para confirmar a operação"})
+	case errors.Is(err, stepup.ErrInvalidCode):
+		return c.Status(fiber.StatusForbidden).JSON(types.ErrorResponse{Code: "INVALID_TOTP_CODE", Message: "Código do autenticador inválido"})
+	case errors.Is(err, stepup.ErrVerificationLock):
+		return c.Status(fiber.StatusLocked).JSON(types.ErrorResponse{Code: "STEP_UP_LOCKED", Message: "Confirmação bloqueada temporariamente após códigos inválidos"})
+	case errors.Is(err, stepup.ErrNotEnrolled):
+		return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "TOTP_NOT_ENROLLED", Message: "Autenticador não cadastrado"})
+	case errors.Is(err, stepup.ErrAlreadyEnrolled):
+		return c.Status(fiber.StatusConflict).JSON(types.ErrorResponse{Code: "TOTP_ALREADY_ENROLLED", Message: "Autenticador já cadastrado"})
+	case errors.Is(err, customer.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "CUSTOMER_NOT_FOUND", Message: "Cliente não encontrado"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+}
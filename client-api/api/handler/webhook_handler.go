@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"case-itau/api/types"
+	repo "case-itau/repositories"
+	"case-itau/services/webhook"
+)
+
+type WebhookHandler struct {
+	service *webhook.Service
+}
+
+func NewWebhookHandler(s *webhook.Service) *WebhookHandler {
+	return &WebhookHandler{
+		service: s,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary      Cadastra um webhook
+// @Description  Endpoint para cadastrar uma URL que receberá os eventos informados. As entregas são assinadas com HMAC-SHA256 no cabeçalho X-Webhook-Signature; o segredo só é exibido nesta resposta. As entregas só são feitas para endereços públicos: URLs que resolvem para loopback, rede privada ou link-local, inclusive após redirecionamentos, falham na entrega.
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        webhook  body      types.CreateWebhookRequest  true  "Dados do webhook"
+// @Success      201  {object}  types.WebhookDto
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /webhooks [post]
+func (h *WebhookHandler) Create(c *fiber.Ctx) error {
+	req := &types.CreateWebhookRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	sub, err := h.service.Create(c.UserContext(), req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	out := toWebhookDto(sub)
+	out.Secret = sub.Secret
+	return c.Status(fiber.StatusCreated).JSON(out)
+}
+
+// ListWebhooks godoc
+// @Summary      Lista os webhooks
+// @Description  Endpoint para listar os webhooks cadastrados
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   types.WebhookDto
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /webhooks [get]
+func (h *WebhookHandler) List(c *fiber.Ctx) error {
+	list, err := h.service.List(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+
+	out := make([]types.WebhookDto, 0, len(list))
+	for i := range list {
+		out = append(out, toWebhookDto(&list[i]))
+	}
+	return c.JSON(out)
+}
+
+// GetWebhook godoc
+// @Summary      Obtém um webhook pelo ID
+// @Description  Endpoint para obter um webhook pelo ID
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID do webhook"
+// @Success      200  {object}  types.WebhookDto
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /webhooks/{id} [get]
+func (h *WebhookHandler) Get(c *fiber.Ctx) error {
+	sub, err := h.service.Get(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(toWebhookDto(sub))
+}
+
+// UpdateWebhook godoc
+// @Summary      Atualiza um webhook
+// @Description  Endpoint para alterar URL, eventos e status de um webhook. Informar um segredo o substitui.
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                      true  "ID do webhook"
+// @Param        webhook  body      types.UpdateWebhookRequest  true  "Dados do webhook"
+// @Success      200  {object}  types.WebhookDto
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /webhooks/{id} [put]
+func (h *WebhookHandler) Update(c *fiber.Ctx) error {
+	req := &types.UpdateWebhookRequest{}
+	if err := req.FromBody(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(&types.ErrorResponse{Code: "INVALID_REQUEST", Message: "Json inválido"})
+	}
+	if err := req.IsValid(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{Code: "INVALID_REQUEST", Message: err.Error()})
+	}
+
+	sub, err := h.service.Update(c.UserContext(), c.Params("id"), req.URL, req.EventTypes, req.Secret, *req.Active)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(toWebhookDto(sub))
+}
+
+// DeleteWebhook godoc
+// @Summary      Remove um webhook
+// @Description  Endpoint para remover um webhook. Entregas pendentes são descartadas.
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID do webhook"
+// @Success      204  {object}  nil
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(c *fiber.Ctx) error {
+	if err := h.service.Delete(c.UserContext(), c.Params("id")); err != nil {
+		return webhookError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary      Lista as entregas de um webhook
+// @Description  Endpoint para listar as tentativas de entrega de um webhook, com paginação. Use `status=dead` para ver as entregas que esgotaram as tentativas.
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string true  "ID do webhook"
+// @Param        status  query     string false "Filtra por status (pending, delivered, dead)"
+// @Param        page    query     int    false "Número da página (default: 1)"
+// @Param        size    query     int    false "Itens por página (default: 10)"
+// @Success      200  {object}  map[string]interface{}  "Retorna metadados de paginação e a lista de entregas"
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /webhooks/{id}/entregas [get]
+func (h *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	id := c.Params("id")
+	status := strings.ToLower(c.Query("status"))
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	size, _ := strconv.Atoi(c.Query("size", "10"))
+
+	if _, err := h.service.Get(c.UserContext(), id); err != nil {
+		return webhookError(c, err)
+	}
+
+	list, total, err := h.service.ListDeliveries(c.UserContext(), id, status, page, size)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(size)))
+
+	if page > totalPages && totalPages != 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(types.ErrorResponse{
+			Code:    "INVALID_PAGE",
+			Message: fmt.Sprintf("A página %d não existe. Total de páginas: %d", page, totalPages),
+		})
+	}
+
+	out := make([]types.WebhookDeliveryDto, 0, len(list))
+	for i := range list {
+		out = append(out, toWebhookDeliveryDto(&list[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"page":        page,
+		"size":        size,
+		"total_items": total,
+		"total_pages": totalPages,
+		"items":       out,
+	})
+}
+
+// ReplayWebhookDelivery godoc
+// @Summary      Reenvia uma entrega de webhook
+// @Description  Endpoint para agendar o reenvio imediato de uma entrega, reiniciando suas tentativas
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string  true  "ID do webhook"
+// @Param        deliveryId  path      string  true  "ID da entrega"
+// @Success      202  {object}  types.WebhookDeliveryDto
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Security     BearerAuth
+// @Router       /webhooks/{id}/entregas/{deliveryId}/reenviar [post]
+func (h *WebhookHandler) Replay(c *fiber.Ctx) error {
+	d, err := h.service.Replay(c.UserContext(), c.Params("id"), c.Params("deliveryId"))
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(toWebhookDeliveryDto(d))
+}
+
+func webhookError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, webhook.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "WEBHOOK_NOT_FOUND", Message: "Webhook não encontrado"})
+	}
+	if errors.Is(err, webhook.ErrDeliveryNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(types.ErrorResponse{Code: "DELIVERY_NOT_FOUND", Message: "Entrega não encontrada"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+}
+
+func toWebhookDto(sub *repo.WebhookSubscription) types.WebhookDto {
+	return types.WebhookDto{
+		ID:         sub.ID,
+		URL:        sub.URL,
+		EventTypes: strings.Split(sub.EventTypes, ","),
+		Active:     sub.Active,
+		CreatedAt:  sub.CreatedAt,
+		UpdatedAt:  sub.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryDto(d *repo.WebhookDelivery) types.WebhookDeliveryDto {
+	return types.WebhookDeliveryDto{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        json.RawMessage(d.Payload),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+}
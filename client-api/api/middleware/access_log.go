@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	l "case-itau/utils/logger"
+	"case-itau/utils/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessLog stores a logger carrying the request and trace IDs in the user
+// context, so that every line logged while serving the request can be tied
+// together, and writes one line per request once it is served. It runs after
+// RequestMetadata and Tracing, whose IDs it picks up.
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		m := requestctx.FromContext(c.UserContext())
+
+		fields := []zap.Field{zap.String("request_id", m.RequestID)}
+		if sc := trace.SpanContextFromContext(c.UserContext()); sc.IsValid() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()), zap.String("span_id", sc.SpanID().String()))
+		}
+		if _, internal := c.Context().UserValue(parentContextKey{}).(context.Context); internal {
+			fields = append(fields, zap.Bool("internal", true))
+		}
+		lg := l.Logger.With(fields...)
+		c.SetUserContext(l.WithContext(c.UserContext(), lg))
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			status = fe.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+		route := c.Route().Path
+		if route == "/" {
+			route = "none"
+		}
+
+		level := zapcore.InfoLevel
+		if status >= fiber.StatusInternalServerError {
+			level = zapcore.ErrorLevel
+		}
+		if ce := lg.Check(level, "request served"); ce != nil {
+			ce.Write(
+				zap.String("method", c.Method()),
+				zap.String("route", route),
+				zap.String("path", c.Path()),
+				zap.Int("status", status),
+				zap.Duration("latency", time.Since(start)),
+				zap.Int("bytes_in", len(c.Request().Body())),
+				zap.Int("bytes_out", len(c.Response().Body())),
+				zap.String("client_ip", m.ClientIP),
+				zap.String("user_agent", c.Get(fiber.HeaderUserAgent)),
+				zap.String("actor", requestctx.FromContext(c.UserContext()).Actor),
+				zap.Error(err),
+			)
+		}
+		return err
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/netip"
+
+	"case-itau/api/types"
+	l "case-itau/utils/logger"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// IPAllowlist rejects with 403 every request whose peer address is outside
+// allowed. The peer address is used as is, so it must not run behind a proxy
+// that rewrites it.
+func IPAllowlist(allowed []netip.Prefix) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		addr, err := netip.ParseAddr(c.IP())
+		if err == nil {
+			addr = addr.Unmap()
+			for _, p := range allowed {
+				if p.Contains(addr) {
+					return c.Next()
+				}
+			}
+		}
+		l.FromContext(c.UserContext()).Warn("admin request from an address outside the allowlist", zap.String("client_ip", c.IP()))
+		return c.Status(fiber.StatusForbidden).JSON(types.ErrorResponse{Code: "FORBIDDEN", Message: "Endereço de origem não autorizado"})
+	}
+}
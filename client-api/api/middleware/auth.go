@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"case-itau/api/types"
+	"case-itau/services/apikey"
+	"case-itau/utils/auth"
+	l "case-itau/utils/logger"
+	"case-itau/utils/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// PublicPaths are served without authentication.
+var PublicPaths = []string{"/docs", "/health", "/auth/login", "/auth/refresh", "/auth/logout"}
+
+// SessionChecker reports whether the login session an access token was issued
+// for is still active.
+type SessionChecker interface {
+	Active(ctx context.Context, sessionID string) (bool, error)
+}
+
+// Authenticate requires a valid bearer token or API key on every route outside
+// PublicPaths and stores the caller in the request metadata. Event streams may
+// pass the token in the access_token query parameter, since browsers cannot set
+// headers on EventSource and WebSocket connections.
+//
+// Tokens bound to a session are rejected once the session is revoked.
+// Requests dispatched internally by the batch endpoint inherit the caller of
+// the batch and are only checked against its API key scopes.
+func Authenticate(v *auth.Verifier, keys *apikey.Service, sessions SessionChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() == fiber.MethodOptions || isPublic(c.Path()) {
+			return c.Next()
+		}
+
+		if _, internal := c.Context().UserValue(parentContextKey{}).(context.Context); !internal {
+			var err error
+			if raw := c.Get(apikey.HeaderAPIKey); raw != "" {
+				err = authenticateKey(c, keys, raw)
+			} else {
+				err = authenticateToken(c, v, sessions)
+			}
+			if err != nil {
+				return unauthorized(c, err)
+			}
+		}
+
+		if scopes := requestctx.FromContext(c.UserContext()).Scopes; scopes != nil && !allowedScope(scopes, c.Method(), c.Path()) {
+			return c.Status(fiber.StatusForbidden).JSON(types.ErrorResponse{Code: "INSUFFICIENT_SCOPE", Message: "A chave de API não tem permissão para esta operação"})
+		}
+		return c.Next()
+	}
+}
+
+func authenticateToken(c *fiber.Ctx, v *auth.Verifier, sessions SessionChecker) error {
+	principal, err := v.Verify(bearerToken(c))
+	if err != nil {
+		return err
+	}
+	if principal.SessionID != "" {
+		active, err := sessions.Active(c.UserContext(), principal.SessionID)
+		if err != nil {
+			return err
+		}
+		if !active {
+			return fmt.Errorf("%w: session ended", auth.ErrInvalidToken)
+		}
+	}
+
+	m := requestctx.FromContext(c.UserContext())
+	m.Actor = principal.Subject
+	m.Roles = principal.Roles
+	setCaller(c, m)
+	return nil
+}
+
+func authenticateKey(c *fiber.Ctx, keys *apikey.Service, raw string) error {
+	key, err := keys.Authenticate(c.UserContext(), raw, apikey.SignedRequest{
+		Method:    c.Method(),
+		Path:      c.OriginalURL(),
+		Timestamp: c.Get(apikey.HeaderTimestamp),
+		Nonce:     c.Get(apikey.HeaderNonce),
+		Body:      c.Body(),
+		Signature: c.Get(apikey.HeaderSignature),
+	})
+	if err != nil {
+		return err
+	}
+
+	m := requestctx.FromContext(c.UserContext())
+	m.Actor = "apikey:" + key.Prefix
+	m.Scopes = strings.Split(key.Scopes, ",")
+	setCaller(c, m)
+	return nil
+}
+
+// setCaller stores the authenticated caller in the request metadata and adds
+// it to the request logger.
+func setCaller(c *fiber.Ctx, m requestctx.Metadata) {
+	ctx := requestctx.WithMetadata(c.UserContext(), m)
+	c.SetUserContext(l.WithContext(ctx, l.FromContext(ctx).With(zap.String("actor", m.Actor))))
+}
+
+func bearerToken(c *fiber.Ctx) string {
+	header := c.Get(fiber.HeaderAuthorization)
+	if scheme, token, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	if header == "" && c.Method() == fiber.MethodGet && isEventStream(c.Path()) {
+		return c.Query("access_token")
+	}
+	return ""
+}
+
+// allowedScope maps a route to the API key scope it needs. Routes outside
+// /clientes, /transacoes and /batch, such as webhooks and administration, are
+// never open to API keys, and neither are the credential routes of customers.
+// The router matches paths regardless of case, so they are compared in lower
+// case.
+func allowedScope(scopes []string, method, path string) bool {
+	path = strings.ToLower(strings.TrimSuffix(path, "/"))
+	var scope string
+	switch {
+	case path == "/batch":
+		// Each operation is checked when it is dispatched.
+		return true
+	case strings.HasPrefix(path, "/transacoes/lote"):
+		scope = apikey.ScopeImport
+		if method == fiber.MethodGet {
+			scope = apikey.ScopeRead
+		}
+	case credentialRoute(path):
+		return false
+	case path == "/clientes" || strings.HasPrefix(path, "/clientes/"):
+		switch {
+		case method == fiber.MethodGet || method == fiber.MethodHead:
+			scope = apikey.ScopeRead
+		case strings.HasSuffix(path, "/depositar"):
+			scope = apikey.ScopeDeposit
+		case strings.HasSuffix(path, "/sacar"):
+			scope = apikey.ScopeWithdraw
+		default:
+			scope = apikey.ScopeWrite
+		}
+	default:
+		return false
+	}
+	return slices.Contains(scopes, scope)
+}
+
+// credentialRoute reports whether path sets how a customer proves who they
+// are. A key able to set a password could log in as the customer, and one able
+// to enroll or remove a TOTP device could pass the step-up it guards, whatever
+// scope it was given for writes. Like the router, it ignores case.
+func credentialRoute(path string) bool {
+	if len(path) < len("/clientes/") || !strings.EqualFold(path[:len("/clientes/")], "/clientes/") {
+		return false
+	}
+	parts := strings.Split(path[len("/clientes/"):], "/")
+	return len(parts) >= 2 && (strings.EqualFold(parts[1], "senha") || strings.EqualFold(parts[1], "totp"))
+}
+
+func isPublic(path string) bool {
+	for _, p := range PublicPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isEventStream(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	return strings.HasSuffix(path, "/eventos") || strings.HasSuffix(path, "/eventos/ws")
+}
+
+func unauthorized(c *fiber.Ctx, err error) error {
+	var resp types.ErrorResponse
+	switch {
+	case errors.Is(err, auth.ErrMissingToken):
+		resp = types.ErrorResponse{Code: "UNAUTHORIZED", Message: "Token de acesso ausente"}
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="customer-api"`)
+	case errors.Is(err, auth.ErrTokenExpired):
+		resp = types.ErrorResponse{Code: "TOKEN_EXPIRED", Message: "Token de acesso expirado"}
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="customer-api", error="invalid_token", error_description="token expired"`)
+	case errors.Is(err, auth.ErrInvalidToken):
+		resp = types.ErrorResponse{Code: "INVALID_TOKEN", Message: "Token de acesso inválido"}
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="customer-api", error="invalid_token"`)
+	case errors.Is(err, apikey.ErrInvalidKey), errors.Is(err, apikey.ErrKeyExpired), errors.Is(err, apikey.ErrKeyRevoked):
+		resp = types.ErrorResponse{Code: "INVALID_API_KEY", Message: err.Error()}
+	case errors.Is(err, apikey.ErrSignatureRequired), errors.Is(err, apikey.ErrInvalidSignature):
+		resp = types.ErrorResponse{Code: "INVALID_SIGNATURE", Message: err.Error()}
+	case errors.Is(err, apikey.ErrStaleTimestamp):
+		resp = types.ErrorResponse{Code: "REQUEST_EXPIRED", Message: err.Error()}
+	case errors.Is(err, apikey.ErrReplayedNonce):
+		resp = types.ErrorResponse{Code: "REPLAYED_REQUEST", Message: err.Error()}
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(types.ErrorResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(resp)
+}
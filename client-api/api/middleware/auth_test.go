@@ -0,0 +1,84 @@
+//go:build unit
+
+package middleware
+
+import (
+	"testing"
+
+	"case-itau/services/apikey"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCases_Auth_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success mapping customer routes to API key scopes", testAllowedScope},
+		{"Failure reaching credential routes with an API key", testCredentialRoutesDenied},
+		{"Success mapping mixed-case routes to the scope of their lower-case form", testAllowedScopeMixedCase},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testAllowedScope(t *testing.T) {
+	t.Log("testAllowedScope - Testing a success clause for routes reachable with the matching scope only")
+	read := []string{apikey.ScopeRead}
+	write := []string{apikey.ScopeWrite}
+
+	assert.True(t, allowedScope(read, fiber.MethodGet, "/clientes/"))
+	assert.True(t, allowedScope(read, fiber.MethodGet, "/clientes/c1/transacoes"))
+	assert.False(t, allowedScope(read, fiber.MethodPut, "/clientes/c1"))
+	assert.True(t, allowedScope(write, fiber.MethodPut, "/clientes/c1"))
+	assert.False(t, allowedScope(write, fiber.MethodPost, "/clientes/c1/depositar"))
+	assert.True(t, allowedScope([]string{apikey.ScopeDeposit}, fiber.MethodPost, "/clientes/c1/depositar"))
+	assert.False(t, allowedScope(write, fiber.MethodPost, "/webhooks"))
+}
+
+func testCredentialRoutesDenied(t *testing.T) {
+	t.Log("testCredentialRoutesDenied - Testing a failure clause for passwords and TOTP devices set through an API key")
+	every := []string{apikey.ScopeRead, apikey.ScopeWrite, apikey.ScopeDeposit, apikey.ScopeWithdraw, apikey.ScopeImport}
+
+	assert.False(t, allowedScope(every, fiber.MethodPut, "/clientes/c1/senha"))
+	assert.False(t, allowedScope(every, fiber.MethodPost, "/clientes/c1/totp"))
+	assert.False(t, allowedScope(every, fiber.MethodPost, "/clientes/c1/totp/confirmar/"))
+	assert.False(t, allowedScope(every, fiber.MethodDelete, "/clientes/c1/totp"))
+	assert.True(t, allowedScope(every, fiber.MethodGet, "/clientes/senha"), "a customer ID is not a credential route")
+
+	assert.False(t, allowedScope(every, fiber.MethodPut, "/clientes/c1/SENHA"))
+	assert.False(t, allowedScope(every, fiber.MethodPut, "/Clientes/c1/Senha/"))
+	assert.True(t, credentialRoute("/CLIENTES/c1/SENHA"))
+
+	assert.False(t, allowedScope(every, fiber.MethodPost, "/clientes/c1/TOTP"))
+	assert.False(t, allowedScope(every, fiber.MethodPost, "/clientes/c1/Totp/Confirmar"))
+	assert.False(t, allowedScope(every, fiber.MethodDelete, "/CLIENTES/c1/TOTP/"))
+	assert.True(t, credentialRoute("/clientes/c1/TOTP"))
+}
+
+func testAllowedScopeMixedCase(t *testing.T) {
+	t.Log("testAllowedScopeMixedCase - Testing a success clause for paths the router matches whatever their case")
+	read := []string{apikey.ScopeRead}
+	write := []string{apikey.ScopeWrite}
+
+	assert.True(t, allowedScope(read, fiber.MethodGet, "/CLIENTES/c1"))
+	assert.False(t, allowedScope(read, fiber.MethodPut, "/Clientes/c1"))
+	assert.True(t, allowedScope(write, fiber.MethodPut, "/Clientes/c1"))
+	assert.False(t, allowedScope(write, fiber.MethodPost, "/clientes/c1/DEPOSITAR"))
+	assert.True(t, allowedScope([]string{apikey.ScopeDeposit}, fiber.MethodPost, "/clientes/c1/DEPOSITAR"))
+	assert.False(t, allowedScope(write, fiber.MethodPost, "/clientes/c1/SACAR"))
+	assert.False(t, allowedScope([]string{apikey.ScopeDeposit}, fiber.MethodPost, "/clientes/c1/Sacar/"))
+	assert.True(t, allowedScope([]string{apikey.ScopeWithdraw}, fiber.MethodPost, "/clientes/c1/SACAR"))
+	assert.False(t, allowedScope(write, fiber.MethodPost, "/Transacoes/Lote"))
+	assert.True(t, allowedScope([]string{apikey.ScopeImport}, fiber.MethodPost, "/Transacoes/Lote"))
+	assert.True(t, allowedScope(read, fiber.MethodGet, "/TRANSACOES/LOTE/j1"))
+	assert.True(t, allowedScope(read, fiber.MethodPost, "/BATCH"))
+	assert.False(t, allowedScope(write, fiber.MethodPost, "/WEBHOOKS"))
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"case-itau/api/types"
+	l "case-itau/utils/logger"
+	"case-itau/utils/rbac"
+	"case-itau/utils/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// Authorize rejects callers whose roles do not grant permission under policy.
+// The route's :id parameter is compared with the caller to apply owner scoped
+// roles. API keys are limited by their scopes instead and pass through.
+func Authorize(policy *rbac.Policy, permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		m := requestctx.FromContext(c.UserContext())
+		if m.Scopes != nil {
+			return c.Next()
+		}
+
+		id := c.Params("id")
+		if policy.Allows(m.Roles, permission, id != "" && id == m.Actor) {
+			return c.Next()
+		}
+
+		l.FromContext(c.UserContext()).Warn("access denied",
+			zap.Strings("roles", m.Roles),
+			zap.String("permission", permission),
+			zap.String("method", c.Method()),
+			zap.String("path", c.Path()),
+		)
+		return c.Status(fiber.StatusForbidden).JSON(types.ErrorResponse{Code: "FORBIDDEN", Message: "Você não tem permissão para esta operação"})
+	}
+}
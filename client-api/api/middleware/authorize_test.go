@@ -0,0 +1,118 @@
+//go:build unit
+
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"case-itau/api/types"
+	l "case-itau/utils/logger"
+	"case-itau/utils/rbac"
+	"case-itau/utils/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newAuthorizeApp serves the customer routes behind Authorize with the default
+// policy. Callers name themselves and their roles in the X-Test-Actor and
+// X-Test-Roles headers.
+func newAuthorizeApp(t *testing.T) *fiber.App {
+	l.Logger = zap.NewNop()
+	policy, err := rbac.Load("")
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(requestctx.WithMetadata(c.UserContext(), requestctx.Metadata{
+			Actor: c.Get("X-Test-Actor"),
+			Roles: strings.Split(c.Get("X-Test-Roles"), ","),
+		}))
+		return c.Next()
+	})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/clientes/:id", Authorize(policy, rbac.CustomersRead), ok)
+	app.Put("/clientes/:id", Authorize(policy, rbac.CustomersUpdate), ok)
+	app.Delete("/clientes/:id", Authorize(policy, rbac.CustomersDelete), ok)
+	return app
+}
+
+func call(t *testing.T, app *fiber.App, method, path, actor, roles string) (int, types.ErrorResponse) {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-Test-Actor", actor)
+	req.Header.Set("X-Test-Roles", roles)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	var body types.ErrorResponse
+	if resp.StatusCode == fiber.StatusForbidden {
+		assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+	return resp.StatusCode, body
+}
+
+func TestCases_Authorize_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Failure writing as an auditor", testAuditorDeniedWrite},
+		{"Failure deleting as a teller", testTellerDeniedDelete},
+		{"Failure reading another customer as a customer", testCustomerDeniedOther},
+		{"Failure answered with the forbidden error body", testForbiddenBody},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testAuditorDeniedWrite(t *testing.T) {
+	t.Log("testAuditorDeniedWrite - Testing a failure clause for an auditor updating a customer it may read")
+	app := newAuthorizeApp(t)
+
+	status, _ := call(t, app, fiber.MethodGet, "/clientes/c1", "auditor1", "auditor")
+	assert.Equal(t, fiber.StatusOK, status)
+	status, _ = call(t, app, fiber.MethodPut, "/clientes/c1", "auditor1", "auditor")
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
+
+func testTellerDeniedDelete(t *testing.T) {
+	t.Log("testTellerDeniedDelete - Testing a failure clause for a teller deleting a customer it may update")
+	app := newAuthorizeApp(t)
+
+	status, _ := call(t, app, fiber.MethodPut, "/clientes/c1", "teller1", "teller")
+	assert.Equal(t, fiber.StatusOK, status)
+	status, _ = call(t, app, fiber.MethodDelete, "/clientes/c1", "teller1", "teller")
+	assert.Equal(t, fiber.StatusForbidden, status)
+	status, _ = call(t, app, fiber.MethodDelete, "/clientes/c1", "admin1", "admin")
+	assert.Equal(t, fiber.StatusOK, status)
+}
+
+func testCustomerDeniedOther(t *testing.T) {
+	t.Log("testCustomerDeniedOther - Testing a failure clause for a customer reading an ID other than its own")
+	app := newAuthorizeApp(t)
+
+	status, _ := call(t, app, fiber.MethodGet, "/clientes/c1", "c1", "customer")
+	assert.Equal(t, fiber.StatusOK, status)
+	status, _ = call(t, app, fiber.MethodGet, "/clientes/c2", "c1", "customer")
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
+
+func testForbiddenBody(t *testing.T) {
+	t.Log("testForbiddenBody - Testing a failure clause for the code and message of a denied request")
+	app := newAuthorizeApp(t)
+
+	status, body := call(t, app, fiber.MethodDelete, "/clientes/c1", "auditor1", "auditor")
+	require.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, types.ErrorResponse{Code: "FORBIDDEN", Message: "Você não tem permissão para esta operação"}, body)
+}
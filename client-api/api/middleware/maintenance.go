@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"case-itau/api/types"
+	"case-itau/services/maintenance"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Maintenance rejects every write with 503 MAINTENANCE_MODE while mode is
+// enabled. Reads keep being served.
+func Maintenance(mode *maintenance.Mode) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if !mode.Status().Enabled {
+			return c.Next()
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(types.ErrorResponse{
+			Code:    "MAINTENANCE_MODE",
+			Message: "API em manutenção: somente operações de leitura estão disponíveis no momento",
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"case-itau/utils/metrics"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics counts and times every request by method, route pattern and status.
+// Using the pattern rather than the path keeps IDs out of the labels.
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			status = fe.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+
+		// The API has no "/" route, so that pattern means the response came
+		// from a global middleware, e.g. failed authentication, or that no
+		// route matched.
+		route := c.Route().Path
+		if route == "/" {
+			route = "none"
+		}
+		// The method is backed by a buffer fasthttp reuses, so it is copied
+		// before being kept as a label.
+		labels := []string{strings.Clone(c.Method()), strings.Clone(route), strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -1,21 +1,36 @@
 package middleware
 
 import (
-	"time"
+	"case-itau/services/maintenance"
+	"case-itau/services/ratelimit"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
-func RegisterMiddlewares(app *fiber.App, rateLimit int64) {
+// RegisterMiddlewares installs the global middlewares. Authentication is
+// skipped when authn is nil. Rate limiting runs after it so that quotas can
+// follow the caller instead of its IP, except for failed authentications,
+// which are limited by IP before it. Writes are turned away during
+// maintenance before any of them is spent on authentication.
+func RegisterMiddlewares(app *fiber.App, limiter *ratelimit.Limiter, mode *maintenance.Mode, authn fiber.Handler) {
+	app.Use(Metrics())
+
 	app.Use(helmet.New())
 
-	app.Use(limiter.New(limiter.Config{
-		Max:        int(rateLimit),
-		Expiration: 1 * time.Minute,
-		LimitReached: func(c *fiber.Ctx) error {
-			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests"})
-		},
-	}))
+	app.Use(RequestMetadata())
+
+	app.Use(Tracing())
+
+	app.Use(AccessLog())
+
+	app.Use(Maintenance(mode))
+
+	app.Use(AuthFailureLimit(limiter))
+
+	if authn != nil {
+		app.Use(authn)
+	}
+
+	app.Use(RateLimit(limiter))
 }
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"case-itau/api/types"
+	"case-itau/services/ratelimit"
+	l "case-itau/utils/logger"
+	"case-itau/utils/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// RateLimit counts each request against the quota of its route class. Clients
+// are told apart by API key, then user, then IP, so it must run after
+// authentication. The quota is advertised in RateLimit-* headers. When the
+// store fails, requests are let through rather than rejected.
+//
+// Batch sub-requests were counted by RateLimitOperations before the batch ran
+// and are let through.
+func RateLimit(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if strings.HasPrefix(c.Path(), "/health/") || c.Path() == "/metrics" {
+			return c.Next()
+		}
+		if _, internal := c.Context().UserValue(parentContextKey{}).(context.Context); internal {
+			return c.Next()
+		}
+
+		res, ok := allow(c, limiter, routeClass(c.Method(), c.Path()))
+		if !ok {
+			return c.Next()
+		}
+		reset := resetSeconds(res)
+		c.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(reset))
+		c.Set("RateLimit-Policy", strconv.Itoa(res.Limit)+";w="+strconv.Itoa(int(limiter.Window().Seconds())))
+		if !res.Allowed {
+			return tooManyRequests(c, reset)
+		}
+		return c.Next()
+	}
+}
+
+// AuthFailureLimit turns away the IPs that spent their quota of failed
+// authentications before authentication spends any work on them, and counts
+// every request the rest of the chain answers with 401. It must run before
+// authentication, since failures never reach RateLimit.
+func AuthFailureLimit(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if strings.HasPrefix(c.Path(), "/health/") || c.Path() == "/metrics" {
+			return c.Next()
+		}
+		if _, internal := c.Context().UserValue(parentContextKey{}).(context.Context); internal {
+			return c.Next()
+		}
+
+		ctx := c.UserContext()
+		client := clientKey(c, requestctx.FromContext(ctx))
+		res, err := limiter.Check(ctx, ratelimit.ClassAuthFailure, client)
+		if err != nil {
+			l.FromContext(ctx).Warn("rate limit check failed", zap.Error(err))
+		} else if !res.Allowed {
+			return tooManyRequests(c, resetSeconds(res))
+		}
+
+		err = c.Next()
+		if c.Response().StatusCode() == fiber.StatusUnauthorized {
+			if _, err := limiter.Allow(ctx, ratelimit.ClassAuthFailure, client); err != nil {
+				l.FromContext(ctx).Warn("failed to count authentication failure", zap.Error(err))
+			}
+		}
+		return err
+	}
+}
+
+// RateLimitOperations counts the operations of a batch against the quotas of
+// their routes before the batch runs any of them. The sub-requests of an atomic
+// batch run inside its transaction, where a count would wait on the locks the
+// batch holds. It responds 429 and returns false when an operation is over its
+// quota.
+func RateLimitOperations(c *fiber.Ctx, limiter *ratelimit.Limiter, ops []types.BatchOperation) (bool, error) {
+	if limiter == nil {
+		return true, nil
+	}
+	for _, op := range ops {
+		res, ok := allow(c, limiter, routeClass(op.Method, op.Path))
+		if ok && !res.Allowed {
+			return false, tooManyRequests(c, resetSeconds(res))
+		}
+	}
+	return true, nil
+}
+
+// allow counts the request against the quota of class. It returns false when
+// the class is not limited or the store failed.
+func allow(c *fiber.Ctx, limiter *ratelimit.Limiter, class ratelimit.Class) (ratelimit.Result, bool) {
+	m := requestctx.FromContext(c.UserContext())
+	res, err := limiter.Allow(c.UserContext(), class, clientKey(c, m))
+	if err != nil {
+		l.FromContext(c.UserContext()).Warn("rate limit check failed", zap.Error(err))
+		return res, false
+	}
+	return res, res.Limit != 0
+}
+
+func tooManyRequests(c *fiber.Ctx, retryAfter int) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return c.Status(fiber.StatusTooManyRequests).JSON(types.ErrorResponse{Code: "TOO_MANY_REQUESTS", Message: "Limite de requisições excedido. Tente novamente mais tarde"})
+}
+
+func resetSeconds(res ratelimit.Result) int {
+	return int(math.Ceil(time.Until(res.Reset).Seconds()))
+}
+
+// clientKey identifies who the quota belongs to. API key actors are already
+// prefixed with "apikey:".
+func clientKey(c *fiber.Ctx, m requestctx.Metadata) string {
+	switch {
+	case m.Scopes != nil:
+		return m.Actor
+	case m.Actor != requestctx.AnonymousActor:
+		return "user:" + m.Actor
+	case m.ClientIP != "":
+		return "ip:" + m.ClientIP
+	}
+	return "ip:" + c.IP()
+}
+
+// routeClass maps a route to the quota it counts against. Paths are compared in
+// lower case, as the router matches them regardless of case.
+func routeClass(method, path string) ratelimit.Class {
+	path = strings.ToLower(strings.TrimSuffix(path, "/"))
+	switch {
+	case path == "/auth/login" || path == "/auth/refresh":
+		return ratelimit.ClassAuth
+	case method == fiber.MethodGet || method == fiber.MethodHead:
+		return ratelimit.ClassRead
+	case strings.HasSuffix(path, "/depositar"), strings.HasSuffix(path, "/sacar"), path == "/transacoes/lote":
+		return ratelimit.ClassMoney
+	}
+	return ratelimit.ClassWrite
+}
@@ -0,0 +1,135 @@
+//go:build unit
+
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"case-itau/api/types"
+	"case-itau/services/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCases_RateLimit_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Failure exceeding a quota with the RateLimit headers", testTooManyRequests},
+		{"Failure authenticating from an IP that spent its failures", testAuthFailureLimit},
+		{"Failure moving money through mixed-case paths past the money quota", testMoneyQuotaMixedCase},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testTooManyRequests(t *testing.T) {
+	t.Log("testTooManyRequests - Testing a failure clause for a spent quota answered with 429, its RateLimit headers and Retry-After")
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), time.Minute, map[ratelimit.Class]int{ratelimit.ClassRead: 2})
+	app := fiber.New()
+	app.Use(RequestMetadata())
+	app.Use(RateLimit(limiter))
+	app.Get("/clientes/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/clientes/", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(remaining), resp.Header.Get("RateLimit-Remaining"))
+		assert.Empty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+	}
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/clientes/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", resp.Header.Get("RateLimit-Policy"))
+
+	reset, err := strconv.Atoi(resp.Header.Get("RateLimit-Reset"))
+	require.NoError(t, err)
+	assert.True(t, reset > 0 && reset <= 60, "the window resets within a minute, got %d", reset)
+	assert.Equal(t, resp.Header.Get("RateLimit-Reset"), resp.Header.Get(fiber.HeaderRetryAfter))
+
+	var body types.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "TOO_MANY_REQUESTS", body.Code)
+
+	// Health checks are never limited.
+	app.Get("/health/live", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/health/live", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+}
+
+func testAuthFailureLimit(t *testing.T) {
+	t.Log("testAuthFailureLimit - Testing a failure clause for an IP turned away before authentication once its failures are spent")
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), time.Minute, map[ratelimit.Class]int{ratelimit.ClassAuthFailure: 2})
+	authenticated := 0
+	app := fiber.New()
+	app.Use(RequestMetadata())
+	app.Use(AuthFailureLimit(limiter))
+	app.Get("/clientes/", func(c *fiber.Ctx) error {
+		if c.Get(fiber.HeaderAuthorization) != "Bearer valid" {
+			return c.Status(fiber.StatusUnauthorized).JSON(types.ErrorResponse{Code: "INVALID_TOKEN", Message: "Token de acesso inválido"})
+		}
+		authenticated++
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	get := func(token string) int {
+		req := httptest.NewRequest(fiber.MethodGet, "/clientes/", nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		if resp.StatusCode == fiber.StatusTooManyRequests {
+			assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, get("valid"), "successes are not counted")
+	assert.Equal(t, fiber.StatusUnauthorized, get("guess-1"))
+	assert.Equal(t, fiber.StatusUnauthorized, get("guess-2"))
+	assert.Equal(t, fiber.StatusTooManyRequests, get("guess-3"))
+	assert.Equal(t, fiber.StatusTooManyRequests, get("valid"), "the IP is turned away before its token is checked")
+	assert.Equal(t, 1, authenticated)
+}
+
+func testMoneyQuotaMixedCase(t *testing.T) {
+	t.Log("testMoneyQuotaMixedCase - Testing a failure clause for deposits and withdrawals counted as money whatever the case of their path")
+	assert.Equal(t, ratelimit.ClassMoney, routeClass(fiber.MethodPost, "/clientes/c1/SACAR"))
+	assert.Equal(t, ratelimit.ClassMoney, routeClass(fiber.MethodPost, "/Clientes/c1/Depositar/"))
+	assert.Equal(t, ratelimit.ClassMoney, routeClass(fiber.MethodPost, "/TRANSACOES/LOTE"))
+	assert.Equal(t, ratelimit.ClassAuth, routeClass(fiber.MethodPost, "/Auth/Login"))
+	assert.Equal(t, ratelimit.ClassWrite, routeClass(fiber.MethodPut, "/CLIENTES/c1"))
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), time.Minute, map[ratelimit.Class]int{ratelimit.ClassMoney: 1, ratelimit.ClassWrite: 10})
+	app := fiber.New()
+	app.Use(RequestMetadata())
+	app.Use(RateLimit(limiter))
+	app.Post("/clientes/:id/sacar", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/clientes/c1/sacar", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodPost, "/clientes/c1/SACAR", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Limit"))
+}
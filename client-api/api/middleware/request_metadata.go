@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+
+	"case-itau/utils/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+)
+
+// maxRequestIDLength caps client supplied request IDs so they cannot bloat logs
+// and audit records.
+const maxRequestIDLength = 128
+
+type parentContextKey struct{}
+
+// WithParentContext marks a request dispatched internally, such as a batch
+// sub-request, so that it runs with ctx as its user context. That keeps the
+// caller's request metadata and lets it share the caller's transaction.
+func WithParentContext(rctx *fasthttp.RequestCtx, ctx context.Context) {
+	rctx.SetUserValue(parentContextKey{}, ctx)
+}
+
+// IsInternal reports whether the request was dispatched with
+// WithParentContext.
+func IsInternal(c *fiber.Ctx) bool {
+	_, internal := c.Context().UserValue(parentContextKey{}).(context.Context)
+	return internal
+}
+
+// RequestMetadata assigns a request ID, reusing the incoming X-Request-ID when
+// present, and stores it with the client IP in the request context.
+func RequestMetadata() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if parent, ok := c.Context().UserValue(parentContextKey{}).(context.Context); ok {
+			c.Set(fiber.HeaderXRequestID, requestctx.FromContext(parent).RequestID)
+			c.SetUserContext(parent)
+			return c.Next()
+		}
+
+		id := c.Get(fiber.HeaderXRequestID)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+		c.Set(fiber.HeaderXRequestID, id)
+
+		c.SetUserContext(requestctx.WithMetadata(c.UserContext(), requestctx.Metadata{
+			RequestID: id,
+			Actor:     requestctx.AnonymousActor,
+			ClientIP:  c.IP(),
+		}))
+		return c.Next()
+	}
+}
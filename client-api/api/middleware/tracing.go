@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"strings"
+
+	"case-itau/utils/requestctx"
+	"case-itau/utils/tracing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// headerCarrier lets the propagator read the incoming fasthttp headers.
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	h.c.Request().Header.VisitAll(func(k, _ []byte) {
+		keys = append(keys, string(k))
+	})
+	return keys
+}
+
+// Tracing opens a server span per request, continuing the trace of an
+// incoming traceparent header, and stores it in the user context so that
+// services and repositories add their spans under it. It runs after
+// RequestMetadata, so batch sub-requests become children of the batch span.
+func Tracing() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+		method := strings.Clone(c.Method())
+		ctx, span := tracing.StartServer(ctx, method,
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLPath(strings.Clone(c.Path())),
+			semconv.ClientAddress(requestctx.FromContext(ctx).ClientIP),
+			attribute.String("request.id", requestctx.FromContext(ctx).RequestID),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// As in Metrics, "/" means no route handled the request.
+		if route := c.Route().Path; route != "/" {
+			span.SetName(c.Method() + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(strings.Clone(route)))
+		}
+		span.SetAttributes(
+			semconv.HTTPResponseStatusCode(c.Response().StatusCode()),
+			attribute.String("enduser.id", requestctx.FromContext(c.UserContext()).Actor),
+		)
+		if err != nil {
+			tracing.Fail(span, err)
+		} else if c.Response().StatusCode() >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+		return err
+	}
+}
@@ -5,22 +5,63 @@ import (
 	"case-itau/api/middleware"
 	"case-itau/config"
 	_ "case-itau/docs"
+	"case-itau/services/maintenance"
+	"case-itau/services/ratelimit"
+	"case-itau/utils/metrics"
+	"case-itau/utils/rbac"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	fiberSwagger "github.com/swaggo/fiber-swagger"
 	"gorm.io/gorm"
 )
 
-func Register(app *fiber.App, db *gorm.DB, cfg *config.Config, h *handler.CustomerHandler) {
+// Handlers groups the HTTP handlers wired by Register.
+type Handlers struct {
+	Customer *handler.CustomerHandler
+	Audit    *handler.AuditHandler
+	Webhook  *handler.WebhookHandler
+	Stream   *handler.StreamHandler
+	Import   *handler.ImportHandler
+	Batch    *handler.BatchHandler
+	APIKey   *handler.APIKeyHandler
+	Session  *handler.SessionHandler
+	TOTP     *handler.TOTPHandler
+	Health   *handler.HealthHandler
+}
+
+// Security holds the authentication middleware and the permission matrix
+// enforced on each route. A nil Security leaves every route open.
+type Security struct {
+	Authenticate fiber.Handler
+	Policy       *rbac.Policy
+}
+
+// authorize returns the middleware checking permission, or a no-op when
+// security is disabled.
+func (s *Security) authorize(permission string) fiber.Handler {
+	if s == nil {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	return middleware.Authorize(s.Policy, permission)
+}
+
+func Register(app *fiber.App, db *gorm.DB, cfg *config.Config, sec *Security, limiter *ratelimit.Limiter, mode *maintenance.Mode, hs Handlers) {
 	// CORS
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,DELETE",
+		AllowOrigins:  "*",
+		AllowMethods:  "GET,POST,PUT,PATCH,DELETE",
+		ExposeHeaders: "ETag,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Retry-After",
 	}))
 
 	// apply middlewares
-	middleware.RegisterMiddlewares(app, int64(cfg.RateLimitMax))
+	var authn fiber.Handler
+	if sec != nil {
+		authn = sec.Authenticate
+	}
+	middleware.RegisterMiddlewares(app, limiter, mode, authn)
 
 	// swagger
 	app.Get("/docs", func(c *fiber.Ctx) error {
@@ -31,14 +72,56 @@ func Register(app *fiber.App, db *gorm.DB, cfg *config.Config, h *handler.Custom
 	})
 	app.Get("/docs/*", fiberSwagger.WrapHandler)
 
+	// health
+	app.Get("/health/live", hs.Health.Live)
+	app.Get("/health/ready", hs.Health.Ready)
+
+	// prometheus
+	app.Get("/metrics", sec.authorize(rbac.MetricsRead), adaptor.HTTPHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})))
+
 	// routes
 	v1 := app.Group("/clientes")
-	v1.Get("/", h.List)
-	v1.Get("/:id", h.Get)
-	v1.Post("/", h.Create)
-	v1.Put("/:id", h.Update)
-	v1.Delete("/:id", h.Delete)
-	v1.Post("/:id/depositar", h.Deposit)
-	v1.Post("/:id/sacar", h.Withdraw)
-	v1.Get("/:id/transacoes", h.GetTransactions)
+	v1.Get("/", sec.authorize(rbac.CustomersList), hs.Customer.List)
+	v1.Get("/:id", sec.authorize(rbac.CustomersRead), hs.Customer.Get)
+	v1.Post("/", sec.authorize(rbac.CustomersCreate), hs.Customer.Create)
+	v1.Put("/:id", sec.authorize(rbac.CustomersUpdate), hs.Customer.Update)
+	v1.Patch("/:id", sec.authorize(rbac.CustomersUpdate), hs.Customer.Patch)
+	v1.Delete("/:id", sec.authorize(rbac.CustomersDelete), hs.Customer.Delete)
+	v1.Post("/:id/depositar", sec.authorize(rbac.CustomersDeposit), hs.Customer.Deposit)
+	v1.Post("/:id/sacar", sec.authorize(rbac.CustomersWithdraw), hs.Customer.Withdraw)
+	v1.Get("/:id/transacoes", sec.authorize(rbac.CustomersTransactions), hs.Customer.GetTransactions)
+	v1.Get("/:id/auditoria", sec.authorize(rbac.CustomersAudit), hs.Audit.List)
+	v1.Get("/:id/eventos", sec.authorize(rbac.CustomersEvents), hs.Stream.SSE)
+	v1.Get("/:id/eventos/ws", sec.authorize(rbac.CustomersEvents), hs.Stream.UpgradeEvents, hs.Stream.WebSocket())
+	v1.Put("/:id/senha", sec.authorize(rbac.CustomersPassword), hs.Session.SetPassword)
+	v1.Post("/:id/totp", sec.authorize(rbac.CustomersTOTP), hs.TOTP.Enroll)
+	v1.Post("/:id/totp/confirmar", sec.authorize(rbac.CustomersTOTP), hs.TOTP.Confirm)
+	v1.Delete("/:id/totp", sec.authorize(rbac.CustomersTOTP), hs.TOTP.Disable)
+
+	authGroup := app.Group("/auth")
+	authGroup.Post("/login", hs.Session.Login)
+	authGroup.Post("/refresh", hs.Session.Refresh)
+	authGroup.Post("/logout", hs.Session.Logout)
+	authGroup.Get("/sessions", hs.Session.List)
+	authGroup.Delete("/sessions/:id", hs.Session.Revoke)
+
+	transactions := app.Group("/transacoes", sec.authorize(rbac.TransactionsImport))
+	transactions.Post("/lote", hs.Import.Import)
+	transactions.Get("/lote/:id", hs.Import.Get)
+
+	app.Post("/batch", hs.Batch.Batch)
+
+	webhooks := app.Group("/webhooks", sec.authorize(rbac.WebhooksManage))
+	webhooks.Get("/", hs.Webhook.List)
+	webhooks.Get("/:id", hs.Webhook.Get)
+	webhooks.Post("/", hs.Webhook.Create)
+	webhooks.Put("/:id", hs.Webhook.Update)
+	webhooks.Delete("/:id", hs.Webhook.Delete)
+	webhooks.Get("/:id/entregas", hs.Webhook.ListDeliveries)
+	webhooks.Post("/:id/entregas/:deliveryId/reenviar", hs.Webhook.Replay)
+
+	admin := app.Group("/admin", sec.authorize(rbac.APIKeysManage))
+	admin.Get("/api-keys", hs.APIKey.List)
+	admin.Post("/api-keys", hs.APIKey.Create)
+	admin.Delete("/api-keys/:id", hs.APIKey.Revoke)
 }
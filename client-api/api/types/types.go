@@ -2,7 +2,12 @@ package types
 
 import (
 	validations "case-itau/utils/validation"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,6 +20,7 @@ type CustomerDto struct {
 	Name    string          `json:"name"`
 	Email   string          `json:"email"`
 	Balance decimal.Decimal `json:"balance"`
+	Version int64           `json:"version"`
 }
 
 type TransactionDto struct {
@@ -22,9 +28,79 @@ type TransactionDto struct {
 	CustomerID    uuid.UUID       `json:"customer_id"`
 	Amount        decimal.Decimal `json:"value"`
 	Type          string          `json:"type"`
+	Reference     string          `json:"reference,omitempty"`
 	CreatedAt     time.Time       `json:"created_at"`
 }
 
+type AuditEntryDto struct {
+	ID         uuid.UUID       `json:"id"`
+	CustomerID uuid.UUID       `json:"customer_id"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id"`
+	ClientIP   string          `json:"client_ip"`
+	Changes    json.RawMessage `json:"changes" swaggertype:"object"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type WebhookDto struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	Secret     string    `json:"secret,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryDto struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uint64          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"last_status_code"`
+	LastError      string          `json:"last_error"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+type APIKeyDto struct {
+	ID               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	Prefix           string     `json:"prefix"`
+	Scopes           []string   `json:"scopes"`
+	RequireSignature bool       `json:"require_signature"`
+	CreatedBy        string     `json:"created_by"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	Key              string     `json:"key,omitempty"`
+	SigningSecret    string     `json:"signing_secret,omitempty"`
+}
+
+type TokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresIn    int       `json:"expires_in"`
+	SessionID    uuid.UUID `json:"session_id"`
+	CustomerID   uuid.UUID `json:"customer_id"`
+}
+
+type SessionDto struct {
+	ID         uuid.UUID `json:"id"`
+	ClientIP   string    `json:"client_ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
 type CreateCustomerRequest struct {
 	Name  string `json:"name" validate:"required,min=2"`
 	Email string `json:"email" validate:"required,email"`
@@ -35,8 +111,113 @@ type UpdateCustomerRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
 
+type PatchCustomerRequest struct {
+	Name  string `json:"name" validate:"omitempty,min=2"`
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
 type TransactionRequest struct {
 	Amount decimal.Decimal `json:"amount" validate:"required"`
+	// TOTPCode confirms withdrawals above the step-up threshold.
+	TOTPCode string `json:"totp_code" validate:"omitempty,len=6,numeric"`
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" validate:"required,http_url"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=* CustomerCreated CustomerUpdated CustomerDeleted DepositBooked WithdrawalBooked"`
+	Secret     string   `json:"secret" validate:"omitempty,min=16"`
+}
+
+type UpdateWebhookRequest struct {
+	URL        string   `json:"url" validate:"required,http_url"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=* CustomerCreated CustomerUpdated CustomerDeleted DepositBooked WithdrawalBooked"`
+	Secret     string   `json:"secret" validate:"omitempty,min=16"`
+	Active     *bool    `json:"active" validate:"required"`
+}
+
+// BatchOperation is a single sub-request of a batch. Path and Body may refer to
+// fields of earlier responses as {{index.field}}, e.g. {{0.id}}.
+type BatchOperation struct {
+	Method  string            `json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	Path    string            `json:"path" validate:"required,startswith=/clientes"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body" swaggertype:"object"`
+}
+
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations" validate:"required,min=1,dive"`
+}
+
+type BatchResponseItem struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty" swaggertype:"object"`
+}
+
+type BatchResponse struct {
+	Atomic     bool                `json:"atomic"`
+	RolledBack bool                `json:"rolled_back"`
+	Responses  []BatchResponseItem `json:"responses"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name             string     `json:"name" validate:"required,min=2"`
+	Scopes           []string   `json:"scopes" validate:"required,min=1,dive,oneof=read write deposit withdraw import"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	RequireSignature bool       `json:"require_signature"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type SetPasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"omitempty,len=6,numeric"`
+}
+
+type HealthComponentResponse struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+type HealthResponse struct {
+	Status     string                             `json:"status"`
+	Components map[string]HealthComponentResponse `json:"components,omitempty"`
+}
+
+type MaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" validate:"required"`
+	Reason  string `json:"reason" validate:"max=200"`
+}
+
+type MaintenanceDto struct {
+	Enabled bool       `json:"enabled"`
+	Reason  string     `json:"reason,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+type BackupDto struct {
+	Path       string    `json:"path"`
+	SizeBytes  int64     `json:"size_bytes"`
+	Compressed bool      `json:"compressed"`
+	CreatedAt  time.Time `json:"created_at"`
+	Removed    []string  `json:"removed,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -60,6 +241,17 @@ func (fi *UpdateCustomerRequest) FromBody(ctx *fiber.Ctx) error {
 	return ctx.BodyParser(&fi)
 }
 
+func (fi *PatchCustomerRequest) IsValid(c *PatchCustomerRequest) error {
+	if c.Name == "" && c.Email == "" {
+		return errors.New("informe ao menos um campo para atualizar")
+	}
+	return validations.Validate(c)
+}
+
+func (fi *PatchCustomerRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
 func (fi *TransactionRequest) IsValid(t *TransactionRequest) error {
 	if t.Amount.LessThanOrEqual(decimal.Zero) {
 		return errors.New("valor da transação deve ser maior que zero")
@@ -70,3 +262,182 @@ func (fi *TransactionRequest) IsValid(t *TransactionRequest) error {
 func (fi *TransactionRequest) FromBody(ctx *fiber.Ctx) error {
 	return ctx.BodyParser(fi)
 }
+
+func (fi *BatchRequest) IsValid(b *BatchRequest, maxOperations int) error {
+	for i := range b.Operations {
+		b.Operations[i].Method = strings.ToUpper(b.Operations[i].Method)
+	}
+	if len(b.Operations) > maxOperations {
+		return fmt.Errorf("o lote excede o limite de %d operações", maxOperations)
+	}
+	return validations.Validate(b)
+}
+
+func (fi *BatchRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+func (fi *CreateAPIKeyRequest) IsValid(k *CreateAPIKeyRequest) error {
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		return errors.New("a data de expiração deve estar no futuro")
+	}
+	return validations.Validate(k)
+}
+
+func (fi *CreateAPIKeyRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+func (fi *LoginRequest) IsValid(r *LoginRequest) error {
+	return validations.Validate(r)
+}
+
+func (fi *LoginRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+func (fi *RefreshRequest) IsValid(r *RefreshRequest) error {
+	return validations.Validate(r)
+}
+
+func (fi *RefreshRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+func (fi *SetPasswordRequest) IsValid(r *SetPasswordRequest) error {
+	return validations.Validate(r)
+}
+
+func (fi *SetPasswordRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+func (fi *TOTPCodeRequest) IsValid(r *TOTPCodeRequest) error {
+	return validations.Validate(r)
+}
+
+func (fi *TOTPCodeRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+func (fi *MaintenanceRequest) IsValid(r *MaintenanceRequest) error {
+	return validations.Validate(r)
+}
+
+func (fi *MaintenanceRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+func (fi *CreateWebhookRequest) IsValid(w *CreateWebhookRequest) error {
+	return validations.Validate(w)
+}
+
+func (fi *CreateWebhookRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+func (fi *UpdateWebhookRequest) IsValid(w *UpdateWebhookRequest) error {
+	return validations.Validate(w)
+}
+
+func (fi *UpdateWebhookRequest) FromBody(ctx *fiber.Ctx) error {
+	return ctx.BodyParser(fi)
+}
+
+// MaxTransactionBatchRows caps how many rows a single import file may hold.
+const MaxTransactionBatchRows = 10000
+
+// TransactionBatchRow is one line of a transaction import file. Invalid holds
+// the validation message of rows that must not be applied.
+type TransactionBatchRow struct {
+	Line      int
+	Customer  string
+	Type      string
+	Amount    decimal.Decimal
+	Reference string
+	Invalid   string
+}
+
+// ParseTransactionBatch reads a CSV file whose header names the columns
+// customer (ID or e-mail), type (deposit or withdraw), amount and, optionally,
+// reference. Every row is validated with the same rules as TransactionRequest;
+// rows that fail are returned with Invalid set rather than aborting the parse.
+func ParseTransactionBatch(r io.Reader) ([]TransactionBatchRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("arquivo CSV vazio ou inválido")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"customer", "type", "amount"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("coluna obrigatória ausente: %s", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []TransactionBatchRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: %v", line, err)
+		}
+		if len(rows) == MaxTransactionBatchRows {
+			return nil, fmt.Errorf("o arquivo excede o limite de %d linhas", MaxTransactionBatchRows)
+		}
+
+		row := TransactionBatchRow{
+			Line:      line,
+			Customer:  field(record, "customer"),
+			Type:      strings.ToLower(field(record, "type")),
+			Reference: field(record, "reference"),
+		}
+		row.Invalid = row.validate(field(record, "amount"))
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("o arquivo não possui linhas")
+	}
+	return rows, nil
+}
+
+// validate parses the amount into the row and returns the validation message,
+// or an empty string when the row is valid.
+func (row *TransactionBatchRow) validate(amount string) string {
+	if row.Customer == "" {
+		return "cliente não informado"
+	}
+	if row.Type != "deposit" && row.Type != "withdraw" {
+		return "tipo deve ser deposit ou withdraw"
+	}
+
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return "valor inválido"
+	}
+	row.Amount = value
+
+	req := &TransactionRequest{Amount: value}
+	if err := req.IsValid(req); err != nil {
+		return err.Error()
+	}
+	return ""
+}
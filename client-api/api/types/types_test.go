@@ -0,0 +1,59 @@
+//go:build unit
+
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCases_TransactionBatch_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success parsing a batch keeps invalid rows with their message", testParseTransactionBatch},
+		{"Failure parsing a batch without the required columns", testParseTransactionBatchMissingColumn},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testParseTransactionBatch(t *testing.T) {
+	t.Log("testParseTransactionBatch - Testing a success clause for parsing valid and invalid rows")
+	csv := "Type,Customer,Amount,Reference\n" +
+		"deposit,john.doe@example.com,10.50,r1\n" +
+		"WITHDRAW,john.doe@example.com,2,\n" +
+		"deposit,john.doe@example.com,0,r3\n" +
+		"transfer,john.doe@example.com,1,r4\n"
+
+	rows, err := ParseTransactionBatch(strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "john.doe@example.com", rows[0].Customer)
+	assert.True(t, decimal.RequireFromString("10.50").Equal(rows[0].Amount))
+	assert.Equal(t, "r1", rows[0].Reference)
+	assert.Empty(t, rows[0].Invalid)
+
+	assert.Equal(t, "withdraw", rows[1].Type)
+	assert.Empty(t, rows[1].Invalid)
+
+	assert.NotEmpty(t, rows[2].Invalid)
+	assert.NotEmpty(t, rows[3].Invalid)
+}
+
+func testParseTransactionBatchMissingColumn(t *testing.T) {
+	t.Log("testParseTransactionBatchMissingColumn - Testing a failure clause for a header without amount")
+	_, err := ParseTransactionBatch(strings.NewReader("customer,type\njohn.doe@example.com,deposit\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"case-itau/config"
+	"case-itau/repositories/connection"
+	"case-itau/repositories/migrations"
+	"case-itau/services/backup"
+)
+
+// runBackup takes an online backup, safe to run while the API is serving.
+func runBackup(args []string, stdout io.Writer) error {
+	cfg, err := config.LoadFlags(newFlagSet("backup"), args)
+	if err != nil {
+		return err
+	}
+	if err := requireSQLite(cfg); err != nil {
+		return err
+	}
+
+	db, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	result, err := backup.NewService(db, cfg.Backup()).Backup(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "backup written to %s (%d bytes)\n", result.Path, result.Size)
+	for _, path := range result.Removed {
+		fmt.Fprintf(stdout, "removed %s\n", path)
+	}
+	return nil
+}
+
+// runRestore swaps a verified backup in place of the database. The API must
+// be stopped first.
+func runRestore(args []string, stdout io.Writer) error {
+	fs := newFlagSet("restore")
+	from := fs.String("from", "", "backup file to restore, gzipped or not")
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireSQLite(cfg); err != nil {
+		return err
+	}
+	if *from == "" {
+		return errors.New("restore: -from is required")
+	}
+
+	ctx := context.Background()
+	kept, err := backup.Restore(ctx, *from, cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "restored %s from %s\n", cfg.DBPath, *from)
+	if kept != "" {
+		fmt.Fprintf(stdout, "previous database kept at %s\n", kept)
+	}
+
+	db, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		fmt.Fprintf(stdout, "the backup is %d migration(s) behind: run \"migrate up\" before serving\n", len(pending))
+	}
+	return nil
+}
+
+func requireSQLite(cfg *config.Config) error {
+	if cfg.DBDriver != connection.DriverSQLite {
+		return fmt.Errorf("backups need db_driver sqlite, got %q: back PostgreSQL up with pg_dump", cfg.DBDriver)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package cli implements the commands of the customer-api binary. Commands
+// that touch customers go through customer.Service, so they are audited and
+// publish their events through the outbox exactly like the HTTP API.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+
+	"case-itau/api"
+	"case-itau/config"
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+	"case-itau/repositories/migrations"
+	"case-itau/services/audit"
+	"case-itau/services/customer"
+	"case-itau/services/events"
+	l "case-itau/utils/logger"
+	"case-itau/utils/requestctx"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Usage lists the commands. Every command also takes the configuration flags,
+// which override the matching environment variable and config file setting.
+const Usage = `usage:
+  customer-api [serve] [flags]                   serve the API
+  customer-api config print [flags]              print the effective configuration
+  customer-api migrate up [flags]                apply every pending migration
+  customer-api migrate down [N] [flags]          revert the last N migrations (default 1)
+  customer-api migrate status [flags]            list migrations and when they were applied
+  customer-api seed [-count N] [flags]           create N fake customers with transaction histories
+  customer-api customers list [-json] [flags]    list every customer
+  customer-api customers create -name NAME -email EMAIL [flags]
+                                                 create a customer
+  customer-api deposit -customer ID|EMAIL -amount AMOUNT [flags]
+                                                 deposit into a customer account
+  customer-api export [-data customers|transactions] [-format csv|ndjson] [-output FILE] [flags]
+                                                 export customers or their transactions
+  customer-api backup [flags]                    back the SQLite database up while it serves
+  customer-api restore -from FILE [flags]        restore a verified backup; stop the API first
+
+Run a command with -h to list its flags.`
+
+// Run runs the command named by args, writing its results to stdout.
+func Run(args []string, stdout io.Writer) error {
+	if len(args) == 0 || len(args[0]) > 0 && args[0][0] == '-' {
+		return serve(args)
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "serve":
+		return serve(args)
+	case "config":
+		if len(args) == 0 || args[0] != "print" {
+			return errors.New(Usage)
+		}
+		cfg, err := config.Load(args[1:])
+		if err != nil {
+			return err
+		}
+		return cfg.Print(stdout)
+	case "migrate":
+		return runMigrate(args, stdout)
+	case "seed":
+		return runSeed(args, stdout)
+	case "customers":
+		return runCustomers(args, stdout)
+	case "deposit":
+		return runDeposit(args, stdout)
+	case "export":
+		return runExport(args, stdout)
+	case "backup":
+		return runBackup(args, stdout)
+	case "restore":
+		return runRestore(args, stdout)
+	case "help":
+		fmt.Fprintln(stdout, Usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, Usage)
+	}
+}
+
+func serve(args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	return api.Start(cfg)
+}
+
+// newFlagSet returns the flag set of a command, whose own flags are added
+// before config.LoadFlags adds the configuration ones.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("customer-api "+name, flag.ContinueOnError)
+}
+
+// env is what the data commands share: the configuration, the database and
+// the customer service on top of it.
+type env struct {
+	cfg       *config.Config
+	db        *gorm.DB
+	customers *customer.Service
+}
+
+// open connects to the configured database and refuses, like the API, to use
+// a schema that is behind unless MIGRATE_ON_START is set. The caller closes
+// the environment.
+func open(ctx context.Context, cfg *config.Config) (*env, error) {
+	l.NewLogger(cfg.LogLevel)
+
+	db, err := connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	e := &env{cfg: cfg, db: db}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		e.close()
+		return nil, err
+	}
+	applied, err := migrator.Ensure(ctx, cfg.MigrateOnStart)
+	for _, m := range applied {
+		l.Logger.Info("migration applied", zap.Int64("version", m.Version), zap.String("name", m.Name))
+	}
+	if err != nil {
+		e.close()
+		return nil, err
+	}
+
+	e.customers = customer.NewService(
+		repositories.NewTransactor(db),
+		repositories.NewGormRepository[repositories.Customers](db),
+		repositories.NewGormRepository[repositories.Transaction](db),
+		audit.NewService(repositories.NewGormRepository[repositories.AuditEntry](db)),
+		events.NewOutbox(repositories.NewGormRepository[repositories.OutboxEvent](db)),
+	)
+	return e, nil
+}
+
+// connect opens the configured database with SQL logging off, since gorm
+// logs to standard output, which holds the results of the command.
+func connect(cfg *config.Config) (*gorm.DB, error) {
+	db, err := connection.New(cfg.Database())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+	return db.Session(&gorm.Session{Logger: gormlogger.Discard}), nil
+}
+
+func (e *env) close() {
+	if sqlDB, err := e.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	l.Logger.Sync()
+}
+
+// operatorContext tags the audit entries of a command with the operating
+// system user running it, e.g. cli:root.
+func operatorContext(ctx context.Context) context.Context {
+	actor := "cli"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		actor += ":" + u.Username
+	} else if name := os.Getenv("USER"); name != "" {
+		actor += ":" + name
+	}
+	return requestctx.WithMetadata(ctx, requestctx.Metadata{
+		RequestID: uuid.NewString(),
+		Actor:     actor,
+	})
+}
@@ -0,0 +1,168 @@
+//go:build unit
+
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"case-itau/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCases_CLI_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Failure using a schema that is behind", testSchemaBehind},
+		{"Success creating, depositing and listing customers", testCustomersAndDeposit},
+		{"Failure creating an invalid or duplicate customer", testCreateRejected},
+		{"Success seeding customers with histories", testSeed},
+		{"Success exporting customers and transactions", testExport},
+		{"Failure running an unknown command", testUnknownCommand},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+// cli runs a command against the database at dbPath and returns its output.
+func cli(t *testing.T, dbPath string, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	args = append(args, "--db-path", dbPath, "--log-level", "error")
+	err := Run(args, &out)
+	return out.String(), err
+}
+
+// migratedDB returns the path of a database brought up to date.
+func migratedDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cli.db")
+	_, err := cli(t, path, "migrate", "up")
+	require.NoError(t, err)
+	return path
+}
+
+func testSchemaBehind(t *testing.T) {
+	t.Log("testSchemaBehind - Testing a failure clause for a data command on an empty database, and a success clause once told to migrate")
+	path := filepath.Join(t.TempDir(), "cli.db")
+
+	_, err := cli(t, path, "customers", "list")
+	assert.ErrorContains(t, err, "database schema is behind")
+
+	_, err = cli(t, path, "customers", "list", "--migrate-on-start")
+	assert.NoError(t, err)
+}
+
+func testCustomersAndDeposit(t *testing.T) {
+	t.Log("testCustomersAndDeposit - Testing a success clause for the customer commands going through the service")
+	path := migratedDB(t)
+
+	out, err := cli(t, path, "customers", "create", "-name", "Ana Lima", "-email", "ana@example.com")
+	require.NoError(t, err)
+	assert.Contains(t, out, "ana@example.com")
+
+	out, err = cli(t, path, "deposit", "-customer", "ana@example.com", "-amount", "150.25")
+	require.NoError(t, err)
+	assert.Contains(t, out, "150.25")
+
+	_, err = cli(t, path, "deposit", "-customer", "ana@example.com", "-amount", "-3")
+	assert.ErrorContains(t, err, "-amount must be a positive number")
+	_, err = cli(t, path, "deposit", "-customer", "bia@example.com", "-amount", "3")
+	assert.EqualError(t, err, "cliente não encontrado")
+
+	out, err = cli(t, path, "customers", "list", "-json")
+	require.NoError(t, err)
+	var c repositories.Customers
+	require.NoError(t, json.Unmarshal([]byte(out), &c))
+	assert.Equal(t, "Ana Lima", c.Name)
+	assert.Equal(t, "150.25", c.Balance.String())
+	assert.Equal(t, int64(2), c.Version)
+}
+
+func testCreateRejected(t *testing.T) {
+	t.Log("testCreateRejected - Testing a failure clause for the API validation rules and the unique e-mail")
+	path := migratedDB(t)
+
+	_, err := cli(t, path, "customers", "create", "-name", "Ana", "-email", "not-an-email")
+	assert.ErrorContains(t, err, "invalid field: 'Email'")
+
+	_, err = cli(t, path, "customers", "create", "-name", "Ana", "-email", "ana@example.com")
+	require.NoError(t, err)
+	_, err = cli(t, path, "customers", "create", "-name", "Outra Ana", "-email", "ana@example.com")
+	assert.EqualError(t, err, "e-mail já cadastrado")
+}
+
+func testSeed(t *testing.T) {
+	t.Log("testSeed - Testing a success clause for seeded customers whose balances match their histories")
+	path := migratedDB(t)
+
+	out, err := cli(t, path, "seed", "-count", "4", "-transactions", "6", "-seed", "42")
+	require.NoError(t, err)
+	assert.Contains(t, out, "created 4 customers")
+
+	out, err = cli(t, path, "customers", "list", "-json")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 4)
+	for _, line := range lines {
+		var c repositories.Customers
+		require.NoError(t, json.Unmarshal([]byte(line), &c))
+		assert.False(t, c.Balance.IsNegative(), "seeded accounts are never overdrawn")
+		assert.True(t, strings.HasSuffix(c.Email, "@example.com"))
+	}
+
+	_, err = cli(t, path, "seed", "-count", "0")
+	assert.ErrorContains(t, err, "-count must be at least 1")
+}
+
+func testExport(t *testing.T) {
+	t.Log("testExport - Testing a success clause for CSV and NDJSON exports of customers and transactions")
+	path := migratedDB(t)
+	_, err := cli(t, path, "customers", "create", "-name", "Ana Lima", "-email", "ana@example.com")
+	require.NoError(t, err)
+	for _, amount := range []string{"10", "20.5"} {
+		_, err = cli(t, path, "deposit", "-customer", "ana@example.com", "-amount", amount)
+		require.NoError(t, err)
+	}
+
+	out, err := cli(t, path, "export")
+	require.NoError(t, err)
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, []string{"id", "name", "email", "balance", "version"}, rows[0])
+	assert.Equal(t, []string{"Ana Lima", "ana@example.com", "30.5", "3"}, rows[1][1:])
+
+	file := filepath.Join(t.TempDir(), "transactions.ndjson")
+	out, err = cli(t, path, "export", "-data", "transactions", "-format", "ndjson", "-output", file)
+	require.NoError(t, err)
+	assert.Contains(t, out, "exported 2 transactions")
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(content)), "\n"), 2)
+
+	_, err = cli(t, path, "export", "-format", "xml")
+	assert.ErrorContains(t, err, `-format must be csv or ndjson, got "xml"`)
+}
+
+func testUnknownCommand(t *testing.T) {
+	t.Log("testUnknownCommand - Testing a failure clause for commands and subcommands that do not exist")
+	var out bytes.Buffer
+	assert.ErrorContains(t, Run([]string{"frobnicate"}, &out), `unknown command "frobnicate"`)
+	assert.ErrorContains(t, Run([]string{"customers", "delete"}, &out), `unknown customers command "delete"`)
+	assert.ErrorContains(t, Run([]string{"migrate"}, &out), "usage:")
+}
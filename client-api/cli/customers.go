@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"case-itau/api/types"
+	"case-itau/config"
+	"case-itau/repositories"
+	validations "case-itau/utils/validation"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// runCustomers runs "customers list" and "customers create".
+func runCustomers(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+	switch args[0] {
+	case "list":
+		return listCustomers(args[1:], stdout)
+	case "create":
+		return createCustomer(args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown customers command %q\n\n%s", args[0], Usage)
+	}
+}
+
+func listCustomers(args []string, stdout io.Writer) error {
+	fs := newFlagSet("customers list")
+	asJSON := fs.Bool("json", false, "print one JSON object per customer")
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	e, err := open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	customers, err := e.customers.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		for _, c := range customers {
+			if err := enc.Encode(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return printCustomers(stdout, customers...)
+}
+
+func createCustomer(args []string, stdout io.Writer) error {
+	fs := newFlagSet("customers create")
+	name := fs.String("name", "", "name of the customer")
+	email := fs.String("email", "", "e-mail of the customer, unique among customers")
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	req := types.CreateCustomerRequest{Name: *name, Email: *email}
+	if err := validations.Validate(req); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	e, err := open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	created, err := e.customers.Create(operatorContext(ctx), repositories.Customers{ID: uuid.New(), Name: req.Name, Email: req.Email})
+	if err != nil {
+		return err
+	}
+	return printCustomers(stdout, created)
+}
+
+func runDeposit(args []string, stdout io.Writer) error {
+	fs := newFlagSet("deposit")
+	ref := fs.String("customer", "", "ID or e-mail of the customer")
+	rawAmount := fs.String("amount", "", "positive amount to deposit, e.g. 150.25")
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *ref == "" {
+		return errors.New("deposit: -customer is required")
+	}
+	amount, err := decimal.NewFromString(*rawAmount)
+	if err != nil || !amount.IsPositive() {
+		return fmt.Errorf("deposit: -amount must be a positive number, got %q", *rawAmount)
+	}
+
+	ctx := context.Background()
+	e, err := open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	ctx = operatorContext(ctx)
+	c, err := e.customers.Resolve(ctx, *ref)
+	if err != nil {
+		return err
+	}
+	updated, err := e.customers.Transactions(ctx, c.ID.String(), 0, amount)
+	if err != nil {
+		return err
+	}
+	return printCustomers(stdout, *updated)
+}
+
+func printCustomers(w io.Writer, customers ...repositories.Customers) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tBALANCE\tVERSION")
+	for _, c := range customers {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", c.ID, c.Name, c.Email, c.Balance.StringFixed(2), c.Version)
+	}
+	return tw.Flush()
+}
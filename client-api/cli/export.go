@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"case-itau/config"
+	"case-itau/services/customer"
+)
+
+const (
+	exportCustomers    = "customers"
+	exportTransactions = "transactions"
+
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+
+	// exportPageSize is how many transactions are read per query.
+	exportPageSize = 500
+)
+
+// runExport writes every customer, or every transaction of every customer, as
+// CSV or newline delimited JSON.
+func runExport(args []string, stdout io.Writer) error {
+	fs := newFlagSet("export")
+	data := fs.String("data", exportCustomers, "what to export: customers or transactions")
+	format := fs.String("format", formatCSV, "output format: csv or ndjson")
+	output := fs.String("output", "", "file to write, standard output when empty")
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *data != exportCustomers && *data != exportTransactions {
+		return fmt.Errorf("export: -data must be customers or transactions, got %q", *data)
+	}
+	if *format != formatCSV && *format != formatNDJSON {
+		return fmt.Errorf("export: -format must be csv or ndjson, got %q", *format)
+	}
+
+	ctx := context.Background()
+	e, err := open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	out := stdout
+	var file *os.File
+	if *output != "" {
+		if file, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+
+	w := newRecordWriter(buffered, *format)
+	var n int
+	if *data == exportCustomers {
+		n, err = exportCustomerRows(ctx, e.customers, w)
+	} else {
+		n, err = exportTransactionRows(ctx, e.customers, w)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "exported %d %s to %s\n", n, *data, *output)
+	}
+	return nil
+}
+
+func exportCustomerRows(ctx context.Context, svc *customer.Service, w *recordWriter) (int, error) {
+	customers, err := svc.ListAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	w.header("id", "name", "email", "balance", "version")
+	for _, c := range customers {
+		err := w.write(c, c.ID.String(), c.Name, c.Email, c.Balance.String(), strconv.FormatInt(c.Version, 10))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(customers), nil
+}
+
+func exportTransactionRows(ctx context.Context, svc *customer.Service, w *recordWriter) (int, error) {
+	customers, err := svc.ListAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	w.header("transaction_id", "customer_id", "type", "amount", "created_at")
+	n := 0
+	for _, c := range customers {
+		for page := 1; ; page++ {
+			txs, total, err := svc.ListTransactions(ctx, c.ID.String(), page, exportPageSize)
+			if err != nil {
+				return n, err
+			}
+			for _, t := range txs {
+				err := w.write(t, t.TransactionID.String(), t.CustomerID.String(), t.Type, t.Amount.String(), t.CreatedAt.UTC().Format(time.RFC3339Nano))
+				if err != nil {
+					return n, err
+				}
+				n++
+			}
+			if len(txs) == 0 || int64(page*exportPageSize) >= total {
+				break
+			}
+		}
+	}
+	return n, nil
+}
+
+// recordWriter writes each record either as a CSV row or as the JSON of its
+// value.
+type recordWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newRecordWriter(w io.Writer, format string) *recordWriter {
+	if format == formatNDJSON {
+		return &recordWriter{json: json.NewEncoder(w)}
+	}
+	return &recordWriter{csv: csv.NewWriter(w)}
+}
+
+func (w *recordWriter) header(columns ...string) {
+	if w.csv != nil {
+		w.csv.Write(columns)
+	}
+}
+
+func (w *recordWriter) write(value any, row ...string) error {
+	if w.csv != nil {
+		return w.csv.Write(row)
+	}
+	return w.json.Encode(value)
+}
+
+func (w *recordWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return nil
+}
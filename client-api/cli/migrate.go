@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"case-itau/config"
+	"case-itau/repositories/migrations"
+)
+
+// runMigrate runs "migrate up", "migrate down [steps]" or "migrate status"
+// against the configured database.
+func runMigrate(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+	command, args := args[0], args[1:]
+
+	steps := 1
+	if command == "down" && len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			if n < 1 {
+				return fmt.Errorf("migrate down: steps must be at least 1, got %d", n)
+			}
+			steps, args = n, args[1:]
+		}
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	db, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		printMigrations(out, "applied", applied)
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		printMigrations(out, "reverted", reverted)
+		if err == nil && len(reverted) == 0 {
+			fmt.Fprintln(out, "no migration to revert")
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	default:
+		return errors.New(Usage)
+	}
+}
+
+func printMigrations(out io.Writer, verb string, ms []migrations.Migration) {
+	for _, m := range ms {
+		fmt.Fprintf(out, "%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
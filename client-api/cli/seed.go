@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+
+	"case-itau/config"
+	"case-itau/repositories"
+	"case-itau/services/customer"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	firstNames = []string{"Ana", "Bruno", "Carla", "Diego", "Elisa", "Felipe", "Gabriela", "Henrique", "Isabela", "João", "Larissa", "Marcos", "Natália", "Otávio", "Paula", "Rafael", "Sofia", "Tiago"}
+	lastNames  = []string{"Almeida", "Barbosa", "Cardoso", "Costa", "Ferreira", "Gomes", "Lima", "Martins", "Oliveira", "Pereira", "Ribeiro", "Rocha", "Santos", "Silva", "Souza"}
+)
+
+// runSeed creates fake customers, each with a random history of deposits
+// and withdrawals that never overdraws the account.
+func runSeed(args []string, stdout io.Writer) error {
+	fs := newFlagSet("seed")
+	count := fs.Int("count", 10, "number of customers to create")
+	maxTransactions := fs.Int("transactions", 5, "maximum number of transactions per customer")
+	seed := fs.Uint64("seed", 0, "seed of the generator, random when 0")
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *count < 1 {
+		return fmt.Errorf("seed: -count must be at least 1, got %d", *count)
+	}
+	if *maxTransactions < 0 {
+		return fmt.Errorf("seed: -transactions must not be negative, got %d", *maxTransactions)
+	}
+
+	ctx := context.Background()
+	e, err := open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	rng := rand.New(rand.NewPCG(*seed, rand.Uint64()))
+	if *seed != 0 {
+		rng = rand.New(rand.NewPCG(*seed, *seed))
+	}
+	s := seeder{customers: e.customers, rng: rng}
+
+	ctx = operatorContext(ctx)
+	var transactions int
+	for i := 0; i < *count; i++ {
+		n, err := s.customer(ctx, *maxTransactions)
+		transactions += n
+		if err != nil {
+			return fmt.Errorf("seed: created %d customers and %d transactions before failing: %w", i, transactions, err)
+		}
+	}
+	fmt.Fprintf(stdout, "created %d customers and %d transactions\n", *count, transactions)
+	return nil
+}
+
+type seeder struct {
+	customers *customer.Service
+	rng       *rand.Rand
+}
+
+// customer creates one customer and up to maxTransactions transactions,
+// returning how many transactions it booked.
+func (s seeder) customer(ctx context.Context, maxTransactions int) (int, error) {
+	first := firstNames[s.rng.IntN(len(firstNames))]
+	last := lastNames[s.rng.IntN(len(lastNames))]
+	id := uuid.New()
+	c, err := s.customers.Create(ctx, repositories.Customers{
+		ID:    id,
+		Name:  first + " " + last,
+		Email: fmt.Sprintf("%s.%s.%s@example.com", ascii(first), ascii(last), id.String()[:8]),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	balance := decimal.Zero
+	booked := 0
+	for range s.rng.IntN(maxTransactions + 1) {
+		// The first transaction of an empty account has to be a deposit.
+		amount := decimal.New(s.rng.Int64N(100_000)+100, -2)
+		if balance.IsPositive() && s.rng.IntN(3) == 0 {
+			amount = decimal.Min(amount, balance).Neg()
+		}
+		updated, err := s.customers.Transactions(ctx, c.ID.String(), 0, amount)
+		if errors.Is(err, customer.ErrInsufficientFunds) {
+			continue
+		}
+		if err != nil {
+			return booked, err
+		}
+		balance = updated.Balance
+		booked++
+	}
+	return booked, nil
+}
+
+// ascii lowers name and drops its accents so that it fits an e-mail address.
+func ascii(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case 'á', 'ã', 'â', 'à':
+			return 'a'
+		case 'é', 'ê':
+			return 'e'
+		case 'í':
+			return 'i'
+		case 'ó', 'õ', 'ô':
+			return 'o'
+		case 'ú':
+			return 'u'
+		case 'ç':
+			return 'c'
+		}
+		return r
+	}, strings.ToLower(name))
+}
@@ -0,0 +1,57 @@
+// Command devtoken mints bearer tokens for local testing. It signs with the
+// same key files the API verifies against, so it must never be given
+// production keys.
+//
+//	go run ./cmd/devtoken -secret-file jwt.secret -sub alice -roles admin
+//	go run ./cmd/devtoken -alg RS256 -private-key jwt.key -kid dev -sub alice
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"case-itau/utils/auth"
+)
+
+func main() {
+	alg := flag.String("alg", auth.HS256, "signing algorithm (HS256 or RS256)")
+	secretFile := flag.String("secret-file", os.Getenv("JWT_SECRET_FILE"), "HS256 secret file")
+	privateKey := flag.String("private-key", "", "RS256 PEM private key file")
+	kid := flag.String("kid", "", "key ID written to the token header (RS256 with JWKS)")
+	sub := flag.String("sub", "dev", "token subject")
+	roles := flag.String("roles", "", "comma separated roles")
+	ttl := flag.Duration("ttl", time.Hour, "token lifetime")
+	flag.Parse()
+
+	var key any
+	var err error
+	switch *alg {
+	case auth.HS256:
+		key, err = auth.LoadSecret(*secretFile)
+	case auth.RS256:
+		key, err = auth.LoadPrivateKey(*privateKey)
+	default:
+		err = fmt.Errorf("unsupported algorithm %q", *alg)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "devtoken:", err)
+		os.Exit(1)
+	}
+
+	var roleList []string
+	for _, r := range strings.Split(*roles, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roleList = append(roleList, r)
+		}
+	}
+
+	token, err := auth.Sign(*alg, key, *kid, *sub, roleList, *ttl)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "devtoken:", err)
+		os.Exit(1)
+	}
+	fmt.Println(token)
+}
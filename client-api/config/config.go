@@ -1,62 +1,192 @@
 package config
 
 import (
-	"case-itau/utils/logger"
-	"os"
-	"strconv"
+	"case-itau/repositories/connection"
+	"case-itau/services/backup"
+	"case-itau/utils/auth"
+	"case-itau/utils/tracing"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
 )
 
+// Config is the effective configuration of the API. Each field is read, in
+// increasing order of precedence, from its default, the config file (yaml
+// key), the environment (env) and the command line (the env name in lower
+// kebab case, e.g. --rate-limit-max). Fields tagged secret are redacted when
+// printed.
 type Config struct {
-	APIPort      string
-	RateLimitMax int64
-	DBPath       string
-}
+	APIPort  string `yaml:"api_port" env:"API_PORT"`
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL"`
 
-func Load() *Config {
-	godotenv.Load()
+	// DBDriver selects sqlite, stored at DBPath, or postgres, reached through
+	// DBDSN. The pool settings apply to both.
+	DBDriver          string        `yaml:"db_driver" env:"DB_DRIVER"`
+	DBPath            string        `yaml:"db_path" env:"DB_PATH" secret:"dsn"`
+	DBDSN             string        `yaml:"db_dsn" env:"DB_DSN" secret:"dsn"`
+	DBMaxOpenConns    int           `yaml:"db_max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns    int           `yaml:"db_max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime time.Duration `yaml:"db_conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime time.Duration `yaml:"db_conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
 
-	checkEnvs()
+	// MigrateOnStart applies pending migrations when the API starts. Without
+	// it the API refuses to start on a schema that is behind.
+	MigrateOnStart bool `yaml:"migrate_on_start" env:"MIGRATE_ON_START"`
 
-	port := os.Getenv("API_PORT")
-	if port == "" {
-		port = "3000"
-	}
+	// BackupDir receives the SQLite backups, gzipped when BackupCompress is
+	// set. Only the BackupKeep newest are kept; zero keeps them all.
+	BackupDir      string `yaml:"backup_dir" env:"BACKUP_DIR"`
+	BackupCompress bool   `yaml:"backup_compress" env:"BACKUP_COMPRESS"`
+	BackupKeep     int    `yaml:"backup_keep" env:"BACKUP_KEEP"`
 
-	rateLimit := os.Getenv("RATE_LIMIT_MAX")
-	if rateLimit == "" {
-		rateLimit = "100"
-	}
-	rateLimitMax, err := strconv.Atoi(rateLimit)
-	if err != nil {
-		rateLimitMax = 100
-	}
+	// CustomerCacheSize is how many customers lookups by ID keep in memory,
+	// each for CustomerCacheTTL. Zero disables the cache. The cache belongs to
+	// each instance, so with several of them a customer read from one may be
+	// up to CustomerCacheTTL behind a write made through another.
+	CustomerCacheSize int           `yaml:"customer_cache_size" env:"CUSTOMER_CACHE_SIZE"`
+	CustomerCacheTTL  time.Duration `yaml:"customer_cache_ttl" env:"CUSTOMER_CACHE_TTL"`
 
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "database.db"
-	}
+	// AdminPort serves the admin endpoints to the comma separated addresses
+	// or CIDR ranges of AdminAllowedIPs. An empty port disables it.
+	AdminPort       string `yaml:"admin_port" env:"ADMIN_PORT"`
+	AdminAllowedIPs string `yaml:"admin_allowed_ips" env:"ADMIN_ALLOWED_IPS"`
 
-	logger.NewLogger()
+	// HealthCheckTimeout bounds each readiness check. A report is reused for
+	// HealthCacheTTL before the checks run again, so probes cannot be used to
+	// load the database.
+	HealthCheckTimeout time.Duration `yaml:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	HealthCacheTTL     time.Duration `yaml:"health_cache_ttl" env:"HEALTH_CACHE_TTL"`
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	ShutdownDelay      time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
 
-	return &Config{
-		APIPort:      port,
-		RateLimitMax: int64(rateLimitMax),
-		DBPath:       dbPath,
-	}
+	// RateLimitMax is the read quota, and the write quota unless
+	// RateLimitWriteMax overrides it.
+	RateLimitMax      int64 `yaml:"rate_limit_max" env:"RATE_LIMIT_MAX"`
+	RateLimitWriteMax int64 `yaml:"rate_limit_write_max" env:"RATE_LIMIT_WRITE_MAX"`
+	RateLimitMoneyMax int64 `yaml:"rate_limit_money_max" env:"RATE_LIMIT_MONEY_MAX"`
+	// RateLimitAuthMax is the quota of logins and token refreshes, and of
+	// failed authentications per IP.
+	RateLimitAuthMax int64         `yaml:"rate_limit_auth_max" env:"RATE_LIMIT_AUTH_MAX"`
+	RateLimitWindow  time.Duration `yaml:"rate_limit_window" env:"RATE_LIMIT_WINDOW"`
+	RateLimitStorage string        `yaml:"rate_limit_storage" env:"RATE_LIMIT_STORAGE"`
+
+	EventsPublisher    string        `yaml:"events_publisher" env:"EVENTS_PUBLISHER"`
+	EventsFilePath     string        `yaml:"events_file_path" env:"EVENTS_FILE_PATH"`
+	OutboxPollInterval time.Duration `yaml:"outbox_poll_interval" env:"OUTBOX_POLL_INTERVAL"`
+	OutboxMaxAttempts  int           `yaml:"outbox_max_attempts" env:"OUTBOX_MAX_ATTEMPTS"`
+
+	WebhookTimeout      time.Duration `yaml:"webhook_timeout" env:"WEBHOOK_TIMEOUT"`
+	WebhookPollInterval time.Duration `yaml:"webhook_poll_interval" env:"WEBHOOK_POLL_INTERVAL"`
+	WebhookMaxAttempts  int           `yaml:"webhook_max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+
+	StreamMaxConnectionsPerClient int           `yaml:"stream_max_connections_per_client" env:"STREAM_MAX_CONNECTIONS_PER_CLIENT"`
+	StreamHeartbeatInterval       time.Duration `yaml:"stream_heartbeat_interval" env:"STREAM_HEARTBEAT_INTERVAL"`
+
+	BatchMaxOperations int `yaml:"batch_max_operations" env:"BATCH_MAX_OPERATIONS"`
+
+	AuthEnabled           bool          `yaml:"auth_enabled" env:"AUTH_ENABLED"`
+	JWT                   auth.Config   `yaml:"jwt"`
+	APIKeySignatureWindow time.Duration `yaml:"api_key_signature_window" env:"API_KEY_SIGNATURE_WINDOW"`
+	RBACPolicyFile        string        `yaml:"rbac_policy_file" env:"RBAC_POLICY_FILE"`
+
+	AccessTokenTTL         time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL        time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+	LoginMaxFailedAttempts int           `yaml:"login_max_failed_attempts" env:"LOGIN_MAX_FAILED_ATTEMPTS"`
+	LoginLockoutDuration   time.Duration `yaml:"login_lockout_duration" env:"LOGIN_LOCKOUT_DURATION"`
+
+	StepUpThreshold decimal.Decimal `yaml:"step_up_threshold" env:"STEP_UP_THRESHOLD"`
+	TOTPIssuer      string          `yaml:"totp_issuer" env:"TOTP_ISSUER"`
+
+	Tracing tracing.Config `yaml:"tracing"`
 }
 
-func checkEnvs() {
-	requiredEnvs := []string{
-		"API_PORT",
-		"RATE_LIMIT_MAX",
-		"DB_PATH",
+// Database returns the connection settings of the selected driver.
+func (c *Config) Database() connection.Config {
+	dsn := c.DBPath
+	if c.DBDriver == connection.DriverPostgres {
+		dsn = c.DBDSN
 	}
+	return connection.Config{
+		Driver:          c.DBDriver,
+		DSN:             dsn,
+		MaxOpenConns:    c.DBMaxOpenConns,
+		MaxIdleConns:    c.DBMaxIdleConns,
+		ConnMaxLifetime: c.DBConnMaxLifetime,
+		ConnMaxIdleTime: c.DBConnMaxIdleTime,
+	}
+}
+
+// Backup returns the backup settings.
+func (c *Config) Backup() backup.Config {
+	return backup.Config{Dir: c.BackupDir, Compress: c.BackupCompress, Keep: c.BackupKeep}
+}
+
+// Default returns the configuration used for every setting that no source
+// overrides.
+func Default() *Config {
+	return &Config{
+		APIPort:  "3000",
+		LogLevel: "info",
+
+		DBDriver:          connection.DriverSQLite,
+		DBPath:            "database.db",
+		DBMaxOpenConns:    25,
+		DBMaxIdleConns:    5,
+		DBConnMaxLifetime: 30 * time.Minute,
+		DBConnMaxIdleTime: 5 * time.Minute,
+
+		BackupDir:  "backups",
+		BackupKeep: 7,
+
+		CustomerCacheSize: 10000,
+		CustomerCacheTTL:  30 * time.Second,
+
+		AdminPort:       "9090",
+		AdminAllowedIPs: "127.0.0.1,::1",
+
+		HealthCheckTimeout: 2 * time.Second,
+		HealthCacheTTL:     2 * time.Second,
+		ShutdownTimeout:    30 * time.Second,
+
+		RateLimitMax:      100,
+		RateLimitMoneyMax: 30,
+		RateLimitAuthMax:  10,
+		RateLimitWindow:   time.Minute,
+		RateLimitStorage:  "database",
+
+		EventsPublisher:    "log",
+		EventsFilePath:     "events.ndjson",
+		OutboxPollInterval: time.Second,
+		OutboxMaxAttempts:  10,
+
+		WebhookTimeout:      10 * time.Second,
+		WebhookPollInterval: 5 * time.Second,
+		WebhookMaxAttempts:  8,
+
+		StreamMaxConnectionsPerClient: 5,
+		StreamHeartbeatInterval:       15 * time.Second,
+
+		BatchMaxOperations: 50,
+
+		AuthEnabled: true,
+		JWT: auth.Config{
+			Algorithm: auth.HS256,
+		},
+		APIKeySignatureWindow: 5 * time.Minute,
+
+		AccessTokenTTL:         15 * time.Minute,
+		RefreshTokenTTL:        30 * 24 * time.Hour,
+		LoginMaxFailedAttempts: 5,
+		LoginLockoutDuration:   15 * time.Minute,
+
+		StepUpThreshold: decimal.NewFromInt(1000),
+		TOTPIssuer:      "Customer API",
 
-	for _, env := range requiredEnvs {
-		if os.Getenv(env) == "" {
-			panic("Environment variable " + env + " is not set")
-		}
+		Tracing: tracing.Config{
+			Exporter:    tracing.ExporterNone,
+			FilePath:    "traces.ndjson",
+			ServiceName: "customer-api",
+			SampleRatio: 1,
+		},
 	}
 }
@@ -0,0 +1,194 @@
+//go:build unit
+
+package config
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCases_Config_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success loading the defaults", testLoadDefaults},
+		{"Success layering file, environment and flags", testLoadPrecedence},
+		{"Success reading a TOML file", testLoadTOML},
+		{"Failure reporting every problem at once", testLoadReportsAllErrors},
+		{"Success redacting secrets when printing", testPrintRedacts},
+		{"Success parsing the admin allowlist", testAdminAllowlist},
+		{"Success selecting the database driver", testDatabaseDriver},
+		{"Success sizing or disabling the customer cache", testCustomerCache},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func testLoadDefaults(t *testing.T) {
+	t.Log("testLoadDefaults - Testing a success clause for a configuration without any source")
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "3000", cfg.APIPort)
+	assert.Equal(t, int64(100), cfg.RateLimitMax)
+	assert.Equal(t, int64(100), cfg.RateLimitWriteMax, "the write quota falls back to the read quota")
+	assert.Equal(t, time.Minute, cfg.RateLimitWindow)
+	assert.True(t, cfg.AuthEnabled)
+}
+
+func testLoadPrecedence(t *testing.T) {
+	t.Log("testLoadPrecedence - Testing a success clause for flags over environment over file over defaults")
+	path := writeFile(t, "config.yaml", `
+api_port: 4000
+db_path: file.db
+rate_limit_max: 7
+tracing:
+  sample_ratio: 0.5
+`)
+	t.Setenv("DB_PATH", "env.db")
+	t.Setenv("RATE_LIMIT_MAX", "8")
+
+	cfg, err := Load([]string{"--config", path, "--rate-limit-max", "9", "--auth-enabled=false"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "4000", cfg.APIPort)
+	assert.Equal(t, "env.db", cfg.DBPath)
+	assert.Equal(t, int64(9), cfg.RateLimitMax)
+	assert.False(t, cfg.AuthEnabled)
+	assert.Equal(t, 0.5, cfg.Tracing.SampleRatio)
+	assert.Equal(t, 30*time.Second, cfg.ShutdownTimeout)
+}
+
+func testLoadTOML(t *testing.T) {
+	t.Log("testLoadTOML - Testing a success clause for nested TOML tables")
+	path := writeFile(t, "config.toml", `
+step_up_threshold = "2500.50"
+
+[jwt]
+algorithm = "RS256"
+`)
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "RS256", cfg.JWT.Algorithm)
+	assert.Equal(t, "2500.5", cfg.StepUpThreshold.String())
+}
+
+func testLoadReportsAllErrors(t *testing.T) {
+	t.Log("testLoadReportsAllErrors - Testing a failure clause for problems spread across every source")
+	path := writeFile(t, "config.yaml", `
+rate_limit_window: soon
+unknown_setting: 1
+`)
+	t.Setenv("RATE_LIMIT_MAX", "abc")
+	t.Setenv("OUTBOX_MAX_ATTEMPTS", "0")
+
+	_, err := Load([]string{"--config", path, "--tracing-exporter", "zipkin"})
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, `unknown setting "unknown_setting"`)
+	assert.Contains(t, msg, `rate_limit_window: expected a duration`)
+	assert.Contains(t, msg, `env RATE_LIMIT_MAX: expected an integer, got "abc"`)
+	assert.Contains(t, msg, `outbox_max_attempts: must be at least 1, got 0`)
+	assert.Contains(t, msg, `tracing.exporter: must be one of none, stdout, file, otlp, got "zipkin"`)
+}
+
+func testPrintRedacts(t *testing.T) {
+	t.Log("testPrintRedacts - Testing a success clause for credentials hidden from the printed configuration")
+	cfg := Default()
+	cfg.DBPath = "file:customers.db?_auth_user=api&_auth_pass=hunter2"
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+
+	assert.Contains(t, out.String(), "db_path: file:customers.db?_auth_user=api&_auth_pass=[REDACTED]")
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.Equal(t, "file:customers.db?_auth_user=api&_auth_pass=hunter2", cfg.DBPath, "printing must not change the configuration")
+
+	assert.Equal(t, "postgres://api:xxxxx@db:5432/customers", redactDSN("postgres://api:hunter2@db:5432/customers"))
+	assert.Equal(t, "host=db user=api password=[REDACTED] dbname=customers", redactDSN("host=db user=api password=hunter2 dbname=customers"))
+}
+
+func testAdminAllowlist(t *testing.T) {
+	t.Log("testAdminAllowlist - Testing a success clause for addresses and ranges, and a failure clause for garbage")
+	cfg := Default()
+	cfg.AdminAllowedIPs = "127.0.0.1, 10.0.0.0/8,::1"
+
+	allowed, err := cfg.AdminAllowlist()
+	require.NoError(t, err)
+	require.Len(t, allowed, 3)
+	assert.Equal(t, "127.0.0.1/32", allowed[0].String())
+	assert.True(t, allowed[1].Contains(netip.MustParseAddr("10.20.30.40")))
+	assert.Equal(t, "::1/128", allowed[2].String())
+
+	cfg.AdminAllowedIPs = "localhost"
+	assert.ErrorContains(t, cfg.Validate(), `admin_allowed_ips: expected IP addresses or CIDR ranges, got "localhost"`)
+
+	cfg.AdminAllowedIPs = "127.0.0.1"
+	cfg.AdminPort = cfg.APIPort
+	assert.ErrorContains(t, cfg.Validate(), "admin_port: must differ from api_port")
+}
+
+func testDatabaseDriver(t *testing.T) {
+	t.Log("testDatabaseDriver - Testing a success clause for the DSN of each driver, and a failure clause for missing ones")
+	t.Setenv("DB_DRIVER", "postgres")
+	t.Setenv("DB_DSN", "postgres://api:hunter2@db:5432/customers")
+
+	cfg, err := Load([]string{"--db-max-open-conns", "10"})
+	require.NoError(t, err)
+
+	db := cfg.Database()
+	assert.Equal(t, "postgres", db.Driver)
+	assert.Equal(t, "postgres://api:hunter2@db:5432/customers", db.DSN)
+	assert.Equal(t, 10, db.MaxOpenConns)
+	assert.Equal(t, 30*time.Minute, db.ConnMaxLifetime)
+
+	cfg.DBDSN = ""
+	assert.ErrorContains(t, cfg.Validate(), "db_dsn: must be set when db_driver is postgres")
+
+	cfg.DBDriver = "mysql"
+	cfg.DBMaxIdleConns = -1
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, `db_driver: must be one of sqlite, postgres, got "mysql"`)
+	assert.ErrorContains(t, err, "db_max_idle_conns: must not be negative, got -1")
+}
+
+func testCustomerCache(t *testing.T) {
+	t.Log("testCustomerCache - Testing a success clause for the cache settings, and a failure clause for unusable ones")
+	t.Setenv("CUSTOMER_CACHE_TTL", "5s")
+
+	cfg, err := Load([]string{"--customer-cache-size", "500"})
+	require.NoError(t, err)
+	assert.Equal(t, 500, cfg.CustomerCacheSize)
+	assert.Equal(t, 5*time.Second, cfg.CustomerCacheTTL)
+
+	cfg.CustomerCacheTTL = 0
+	assert.ErrorContains(t, cfg.Validate(), "customer_cache_ttl: must be a positive duration, got 0s")
+	cfg.CustomerCacheSize = 0
+	assert.NoError(t, cfg.Validate(), "a disabled cache needs no TTL")
+	cfg.CustomerCacheSize = -1
+	assert.ErrorContains(t, cfg.Validate(), "customer_cache_size: must not be negative, got -1")
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from the defaults, the config file, the
+// environment (including a .env file in the working directory) and the flags
+// in args, each overriding the previous one, and validates the result. The
+// config file is given by --config or CONFIG_FILE. Every problem found is
+// reported at once in the returned error.
+func Load(args []string) (*Config, error) {
+	return LoadFlags(flag.NewFlagSet("customer-api", flag.ContinueOnError), args)
+}
+
+// LoadFlags is Load with the flags of the configuration added to fs, so that
+// commands can parse their own flags from the same arguments.
+func LoadFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	godotenv.Load()
+
+	cfg := Default()
+	settings := fields(cfg)
+
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file (CONFIG_FILE)")
+	for _, f := range settings {
+		fs.Var(&flagValue{isBool: f.value.Kind() == reflect.Bool}, f.flagName(), fmt.Sprintf("%s (%s)", f.key, f.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	var errs []error
+	if *configFile != "" {
+		errs = append(errs, loadFile(*configFile, settings)...)
+	}
+	for _, f := range settings {
+		if raw := os.Getenv(f.env); raw != "" {
+			if err := f.set(raw); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", f.env, err))
+			}
+		}
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		for _, f := range settings {
+			if f.flagName() == fl.Name {
+				if err := f.set(fl.Value.String()); err != nil {
+					errs = append(errs, fmt.Errorf("flag --%s: %w", fl.Name, err))
+				}
+			}
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if cfg.RateLimitWriteMax == 0 {
+		cfg.RateLimitWriteMax = cfg.RateLimitMax
+	}
+	return cfg, nil
+}
+
+// setting is a leaf field of Config.
+type setting struct {
+	key    string // dotted path in the config file, e.g. jwt.algorithm
+	env    string
+	secret string
+	value  reflect.Value
+}
+
+// flagName is the command line flag of the setting, e.g. --rate-limit-max for
+// RATE_LIMIT_MAX.
+func (s setting) flagName() string {
+	return strings.ToLower(strings.ReplaceAll(s.env, "_", "-"))
+}
+
+var decimalType = reflect.TypeFor[decimal.Decimal]()
+
+// fields lists the settings of cfg, whose values can be set through them.
+func fields(cfg *Config) []setting {
+	var out []setting
+	walk(reflect.ValueOf(cfg).Elem(), "", &out)
+	return out
+}
+
+func walk(v reflect.Value, prefix string, out *[]setting) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("yaml")
+		if key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		env := sf.Tag.Get("env")
+		if env == "" && sf.Type.Kind() == reflect.Struct && sf.Type != decimalType {
+			walk(v.Field(i), key, out)
+			continue
+		}
+		*out = append(*out, setting{key: key, env: env, secret: sf.Tag.Get("secret"), value: v.Field(i)})
+	}
+}
+
+// set parses raw into the setting according to its type.
+func (s setting) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch p := s.value.Addr().Interface().(type) {
+	case *string:
+		*p = raw
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		*p = b
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		*p = n
+	case *int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		*p = f
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected a duration such as 30s or 5m, got %q", raw)
+		}
+		*p = d
+	case *decimal.Decimal:
+		d, err := decimal.NewFromString(raw)
+		if err != nil {
+			return fmt.Errorf("expected a decimal number, got %q", raw)
+		}
+		*p = d
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// flagValue records the raw value of a flag; it is parsed together with the
+// other sources once every flag has been read.
+type flagValue struct {
+	raw    string
+	isBool bool
+}
+
+func (v *flagValue) String() string       { return v.raw }
+func (v *flagValue) Set(raw string) error { v.raw = raw; return nil }
+func (v *flagValue) IsBoolFlag() bool     { return v.isBool }
+
+// loadFile applies the settings of a YAML or TOML file, chosen by extension.
+func loadFile(path string, settings []setting) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config file: %w", err)}
+	}
+
+	doc := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return []error{fmt.Errorf("config file %s: expected a .yaml, .yml or .toml extension", path)}
+	}
+	if err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+
+	values := make(map[string]any)
+	flatten("", doc, values)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var errs []error
+	for _, k := range keys {
+		i := slices.IndexFunc(settings, func(s setting) bool { return s.key == k })
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("config file %s: unknown setting %q", path, k))
+			continue
+		}
+		raw, err := scalar(values[k])
+		if err == nil {
+			err = settings[i].set(raw)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, k, err))
+		}
+	}
+	return errs
+}
+
+// flatten turns nested tables into dotted keys.
+func flatten(prefix string, doc map[string]any, out map[string]any) {
+	for k, v := range doc {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]any); ok {
+			flatten(k, nested, out)
+			continue
+		}
+		out[k] = v
+	}
+}
+
+// scalar renders a decoded file value the way it would be written in an
+// environment variable.
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("expected a single value, got %T", v)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Print writes the configuration as YAML in the config file format, with
+// secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	out := *c
+	for _, s := range fields(&out) {
+		switch s.secret {
+		case "":
+		case "dsn":
+			s.value.SetString(redactDSN(s.value.String()))
+		default:
+			if s.value.String() != "" {
+				s.value.SetString(redacted)
+			}
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// dsnPassword matches password parameters of key=value and query string DSNs,
+// such as SQLite's _auth_pass or Postgres' password.
+var dsnPassword = regexp.MustCompile(`(?i)((?:^|[?&\s])[a-z_]*(?:pass|password|secret)=)('[^']*'|[^&\s]*)`)
+
+// redactDSN hides the credentials of a database connection string.
+func redactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil {
+			dsn = u.Redacted()
+		}
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"case-itau/repositories/connection"
+	"case-itau/utils/auth"
+	"case-itau/utils/tracing"
+)
+
+// AdminAllowlist parses AdminAllowedIPs. Single addresses are taken as
+// ranges of one address.
+func (c *Config) AdminAllowlist() ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(c.AdminAllowedIPs, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if p, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("expected IP addresses or CIDR ranges, got %q", entry)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	if len(prefixes) == 0 {
+		return nil, errors.New("must list at least one address")
+	}
+	return prefixes, nil
+}
+
+// Validate checks the settings that parsed but cannot work, reporting all of
+// them at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	positive := func(key string, d time.Duration) {
+		if d <= 0 {
+			invalid(key, "must be a positive duration, got %s", d)
+		}
+	}
+	atLeastOne := func(key string, n int64) {
+		if n < 1 {
+			invalid(key, "must be at least 1, got %d", n)
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		if !slices.Contains(allowed, value) {
+			invalid(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+		}
+	}
+
+	if port, err := strconv.Atoi(c.APIPort); err != nil || port < 1 || port > 65535 {
+		invalid("api_port", "must be a TCP port between 1 and 65535, got %q", c.APIPort)
+	}
+	oneOf("db_driver", c.DBDriver, connection.DriverSQLite, connection.DriverPostgres)
+	if c.DBDriver == connection.DriverSQLite && c.DBPath == "" {
+		invalid("db_path", "must be set when db_driver is sqlite")
+	}
+	if c.DBDriver == connection.DriverPostgres && c.DBDSN == "" {
+		invalid("db_dsn", "must be set when db_driver is postgres")
+	}
+	if c.DBMaxOpenConns < 0 {
+		invalid("db_max_open_conns", "must not be negative, got %d", c.DBMaxOpenConns)
+	}
+	if c.DBMaxIdleConns < 0 {
+		invalid("db_max_idle_conns", "must not be negative, got %d", c.DBMaxIdleConns)
+	}
+	if c.DBConnMaxLifetime < 0 {
+		invalid("db_conn_max_lifetime", "must not be negative, got %s", c.DBConnMaxLifetime)
+	}
+	if c.DBConnMaxIdleTime < 0 {
+		invalid("db_conn_max_idle_time", "must not be negative, got %s", c.DBConnMaxIdleTime)
+	}
+	if c.DBDriver == connection.DriverSQLite && c.BackupDir == "" {
+		invalid("backup_dir", "must be set when db_driver is sqlite")
+	}
+	if c.BackupKeep < 0 {
+		invalid("backup_keep", "must not be negative, got %d", c.BackupKeep)
+	}
+	if c.CustomerCacheSize < 0 {
+		invalid("customer_cache_size", "must not be negative, got %d", c.CustomerCacheSize)
+	}
+	if c.CustomerCacheSize > 0 {
+		positive("customer_cache_ttl", c.CustomerCacheTTL)
+	}
+	oneOf("log_level", strings.ToLower(c.LogLevel), "debug", "info", "warn", "error")
+
+	if c.AdminPort != "" {
+		if port, err := strconv.Atoi(c.AdminPort); err != nil || port < 1 || port > 65535 {
+			invalid("admin_port", "must be empty or a TCP port between 1 and 65535, got %q", c.AdminPort)
+		} else if c.AdminPort == c.APIPort {
+			invalid("admin_port", "must differ from api_port")
+		}
+		if _, err := c.AdminAllowlist(); err != nil {
+			invalid("admin_allowed_ips", "%v", err)
+		}
+	}
+
+	positive("health_check_timeout", c.HealthCheckTimeout)
+	positive("health_cache_ttl", c.HealthCacheTTL)
+	positive("shutdown_timeout", c.ShutdownTimeout)
+	if c.ShutdownDelay < 0 {
+		invalid("shutdown_delay", "must not be negative, got %s", c.ShutdownDelay)
+	}
+
+	atLeastOne("rate_limit_max", c.RateLimitMax)
+	if c.RateLimitWriteMax < 0 {
+		invalid("rate_limit_write_max", "must not be negative, got %d", c.RateLimitWriteMax)
+	}
+	atLeastOne("rate_limit_money_max", c.RateLimitMoneyMax)
+	atLeastOne("rate_limit_auth_max", c.RateLimitAuthMax)
+	positive("rate_limit_window", c.RateLimitWindow)
+	oneOf("rate_limit_storage", c.RateLimitStorage, "database", "memory")
+
+	oneOf("events_publisher", c.EventsPublisher, "log", "file")
+	if c.EventsPublisher == "file" && c.EventsFilePath == "" {
+		invalid("events_file_path", "must be set when events_publisher is file")
+	}
+	positive("outbox_poll_interval", c.OutboxPollInterval)
+	atLeastOne("outbox_max_attempts", int64(c.OutboxMaxAttempts))
+
+	positive("webhook_timeout", c.WebhookTimeout)
+	positive("webhook_poll_interval", c.WebhookPollInterval)
+	atLeastOne("webhook_max_attempts", int64(c.WebhookMaxAttempts))
+
+	atLeastOne("stream_max_connections_per_client", int64(c.StreamMaxConnectionsPerClient))
+	positive("stream_heartbeat_interval", c.StreamHeartbeatInterval)
+
+	atLeastOne("batch_max_operations", int64(c.BatchMaxOperations))
+
+	oneOf("jwt.algorithm", c.JWT.Algorithm, auth.HS256, auth.RS256)
+	positive("api_key_signature_window", c.APIKeySignatureWindow)
+
+	positive("access_token_ttl", c.AccessTokenTTL)
+	positive("refresh_token_ttl", c.RefreshTokenTTL)
+	atLeastOne("login_max_failed_attempts", int64(c.LoginMaxFailedAttempts))
+	positive("login_lockout_duration", c.LoginLockoutDuration)
+
+	if c.StepUpThreshold.IsNegative() {
+		invalid("step_up_threshold", "must not be negative, got %s", c.StepUpThreshold)
+	}
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile, tracing.ExporterOTLP)
+	if c.Tracing.Exporter == tracing.ExporterFile && c.Tracing.FilePath == "" {
+		invalid("tracing.file_path", "must be set when tracing.exporter is file")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	return errors.Join(errs...)
+}
@@ -1,10 +1,28 @@
 // @title        Itau Case API
 // @version      1.0
 // @description  API para gerenciar clientes e suas contas
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 Token JWT no formato "Bearer {token}"
 package main
 
-import "case-itau/api"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"case-itau/cli"
+)
 
 func main() {
-	api.Start()
+	if err := cli.Run(os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
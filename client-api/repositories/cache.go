@@ -0,0 +1,335 @@
+package repositories
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	l "case-itau/utils/logger"
+	"case-itau/utils/metrics"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// CacheBackend stores the encoded entities of a cached repository. The
+// in-memory LRU returned by NewMemoryCache is private to the process; a
+// backend shared by every instance, such as Redis, keeps one instance from
+// serving entries that another one changed.
+type CacheBackend interface {
+	// Get returns the value stored under key and whether there was one.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key until ttl elapses.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Clear drops every entry, for writes that do not say which rows they
+	// change.
+	Clear(ctx context.Context) error
+}
+
+// cacheState is shared by a cached repository and its preloading views.
+// Every invalidation bumps epoch, and a lookup only stores what it read when
+// no invalidation happened since it started reading, so a row read just
+// before a concurrent write cannot land in the cache after that write
+// invalidated it.
+type cacheState struct {
+	mu    sync.Mutex
+	epoch uint64
+}
+
+type cachedRepository[T any] struct {
+	inner   IRepository[T]
+	backend CacheBackend
+	ttl     time.Duration
+	name    string
+	state   *cacheState
+	// reads is false for preloading views, whose lookups are not cached
+	// but whose writes still invalidate.
+	reads bool
+}
+
+// NewCachedRepository caches the lookups inner serves by ID for ttl. Only
+// FindOne calls whose where is a map holding nothing but a UUID "id" are
+// cached; every other read goes straight to inner.
+//
+// Writes invalidate the rows they name by "id", or the whole cache when they
+// name none, as soon as they are made and again when their transaction
+// commits. Lookups inside a transaction always read the database and are not
+// stored: services decide writes from what they read there, such as whether a
+// balance covers a withdrawal, and the cache may be behind changes made by
+// other instances or processes, while what a transaction reads may never
+// commit.
+func NewCachedRepository[T any](inner IRepository[T], backend CacheBackend, ttl time.Duration) IRepository[T] {
+	return &cachedRepository[T]{
+		inner:   inner,
+		backend: backend,
+		ttl:     ttl,
+		name:    strings.ToLower(reflect.TypeFor[T]().Name()),
+		state:   &cacheState{},
+		reads:   true,
+	}
+}
+
+func (r *cachedRepository[T]) WithPreload(associations ...string) IRepository[T] {
+	return &cachedRepository[T]{
+		inner:   r.inner.WithPreload(associations...),
+		backend: r.backend,
+		ttl:     r.ttl,
+		name:    r.name,
+		state:   r.state,
+	}
+}
+
+func (r *cachedRepository[T]) Find(ctx context.Context, where any, order string, limit, offset int) ([]T, error) {
+	return r.inner.Find(ctx, where, order, limit, offset)
+}
+
+func (r *cachedRepository[T]) Count(ctx context.Context, where any) (int64, error) {
+	return r.inner.Count(ctx, where)
+}
+
+func (r *cachedRepository[T]) FindOne(ctx context.Context, where any) (*T, error) {
+	key, ok := r.lookupKey(where)
+	if _, inTx := ctx.Value(txKey{}).(*txState); !ok || !r.reads || inTx {
+		return r.inner.FindOne(ctx, where)
+	}
+
+	if entity, ok := r.get(ctx, key); ok {
+		metrics.CacheLookups.WithLabelValues(r.name, "hit").Inc()
+		return entity, nil
+	}
+	metrics.CacheLookups.WithLabelValues(r.name, "miss").Inc()
+
+	r.state.mu.Lock()
+	epoch := r.state.epoch
+	r.state.mu.Unlock()
+
+	entity, err := r.inner.FindOne(ctx, where)
+	if err != nil {
+		return nil, err
+	}
+	r.fill(ctx, key, epoch, entity)
+	return entity, nil
+}
+
+func (r *cachedRepository[T]) InsertOne(ctx context.Context, entity *T) error {
+	// Misses are not cached, so a new row has nothing to invalidate.
+	return r.inner.InsertOne(ctx, entity)
+}
+
+func (r *cachedRepository[T]) UpdateOne(ctx context.Context, where any, updates map[string]any) error {
+	err := r.inner.UpdateOne(ctx, where, updates)
+	if ierr := r.invalidate(ctx, where); ierr != nil && err == nil {
+		return ierr
+	}
+	return err
+}
+
+func (r *cachedRepository[T]) DeleteOne(ctx context.Context, where any) error {
+	err := r.inner.DeleteOne(ctx, where)
+	if ierr := r.invalidate(ctx, where); ierr != nil && err == nil {
+		return ierr
+	}
+	return err
+}
+
+// get returns the entity cached under key. Backend and decoding failures are
+// logged and served as misses.
+func (r *cachedRepository[T]) get(ctx context.Context, key string) (*T, bool) {
+	raw, ok, err := r.backend.Get(ctx, key)
+	if err != nil {
+		metrics.CacheLookups.WithLabelValues(r.name, "error").Inc()
+		l.FromContext(ctx).Warn("cache lookup failed", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	var entity T
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&entity); err != nil {
+		metrics.CacheLookups.WithLabelValues(r.name, "error").Inc()
+		l.FromContext(ctx).Warn("dropping undecodable cache entry", zap.String("key", key), zap.Error(err))
+		r.backend.Delete(ctx, key)
+		return nil, false
+	}
+	return &entity, true
+}
+
+// fill stores entity under key unless the cache was invalidated since epoch.
+func (r *cachedRepository[T]) fill(ctx context.Context, key string, epoch uint64, entity *T) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entity); err != nil {
+		l.FromContext(ctx).Warn("failed to encode cache entry", zap.String("key", key), zap.Error(err))
+		return
+	}
+
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if r.state.epoch != epoch {
+		return
+	}
+	if err := r.backend.Set(ctx, key, buf.Bytes(), r.ttl); err != nil {
+		l.FromContext(ctx).Warn("failed to store cache entry", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// invalidate drops the rows named by where, or every row when it names none.
+// Inside a transaction it drops them again once the transaction commits, as
+// lookups made meanwhile by other requests still read the old row.
+func (r *cachedRepository[T]) invalidate(ctx context.Context, where any) error {
+	key, ok := r.writeKey(where)
+	drop := func(ctx context.Context) error {
+		r.state.mu.Lock()
+		defer r.state.mu.Unlock()
+		r.state.epoch++
+		if ok {
+			return r.backend.Delete(ctx, key)
+		}
+		return r.backend.Clear(ctx)
+	}
+
+	if _, inTx := ctx.Value(txKey{}).(*txState); inTx {
+		AfterCommit(ctx, func() {
+			if err := drop(context.WithoutCancel(ctx)); err != nil {
+				l.FromContext(ctx).Error("failed to invalidate cache after commit", zap.String("cache", r.name), zap.Error(err))
+			}
+		})
+	}
+	if err := drop(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate %s cache: %w", r.name, err)
+	}
+	return nil
+}
+
+// lookupKey returns the cache key of a lookup by ID alone.
+func (r *cachedRepository[T]) lookupKey(where any) (string, bool) {
+	m, ok := where.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	return r.writeKey(m)
+}
+
+// writeKey returns the cache key of the row a write names by ID, whatever
+// else it is conditioned on. IDs are normalised so that lookups and writes
+// spelling the same UUID differently share a key.
+func (r *cachedRepository[T]) writeKey(where any) (string, bool) {
+	m, ok := where.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	var id uuid.UUID
+	switch v := m["id"].(type) {
+	case uuid.UUID:
+		id = v
+	case string:
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			return "", false
+		}
+		id = parsed
+	default:
+		return "", false
+	}
+	return r.name + ":" + id.String(), true
+}
+
+// MemoryCache is a CacheBackend holding up to size entries in memory,
+// evicting the least recently used one to make room for a new one.
+type MemoryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// order has the most recently used entry at the front.
+	order *list.List
+	now   func() time.Time
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*memoryEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+		metrics.CacheEvictions.Inc()
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Clear(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.order.Init()
+	return nil
+}
+
+// Len returns the number of entries held, expired ones included until they
+// are looked up or evicted.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,345 @@
+//go:build unit
+
+package repositories_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+	"case-itau/repositories/migrations"
+	"case-itau/services/audit"
+	"case-itau/services/customer"
+	"case-itau/services/events"
+	l "case-itau/utils/logger"
+	"case-itau/utils/metrics"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCases_Cache_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success serving lookups by ID from the cache", testCacheHitsAndMisses},
+		{"Success invalidating customers on writes and commits", testCacheInvalidation},
+		{"Success withdrawing while the cache is behind the database", testCacheBehindDatabase},
+		{"Success discarding a row read before a concurrent write", testCacheStaleFill},
+		{"Success keeping the cache consistent under concurrent deposits", testCacheConcurrentDeposits},
+		{"Success evicting the least recently used and expired entries", testMemoryCache},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+// cacheFixture is a migrated database with a customer service whose
+// customers repository is cached.
+type cacheFixture struct {
+	db    *gorm.DB
+	repo  repositories.IRepository[repositories.Customers]
+	svc   *customer.Service
+	cache *repositories.MemoryCache
+}
+
+func newCacheFixture(t *testing.T, wrap func(repositories.IRepository[repositories.Customers]) repositories.IRepository[repositories.Customers]) *cacheFixture {
+	t.Helper()
+	l.Logger = zap.NewNop()
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	migrator, err := migrations.New(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	inner := repositories.NewGormRepository[repositories.Customers](db)
+	if wrap != nil {
+		inner = wrap(inner)
+	}
+	cache := repositories.NewMemoryCache(100)
+	repo := repositories.NewCachedRepository(inner, cache, time.Minute)
+	svc := customer.NewService(
+		repositories.NewTransactor(db),
+		repo,
+		repositories.NewGormRepository[repositories.Transaction](db),
+		audit.NewService(repositories.NewGormRepository[repositories.AuditEntry](db)),
+		events.NewOutbox(repositories.NewGormRepository[repositories.OutboxEvent](db)),
+	)
+	return &cacheFixture{db: db, repo: repo, svc: svc, cache: cache}
+}
+
+func (f *cacheFixture) create(t *testing.T, email string) repositories.Customers {
+	t.Helper()
+	c, err := f.svc.Create(context.Background(), repositories.Customers{ID: uuid.New(), Name: "Ana Lima", Email: email})
+	require.NoError(t, err)
+	return c
+}
+
+// stored reads a customer from the database, past the cache.
+func (f *cacheFixture) stored(t *testing.T, id uuid.UUID) repositories.Customers {
+	t.Helper()
+	var c repositories.Customers
+	require.NoError(t, f.db.First(&c, "id = ?", id.String()).Error)
+	return c
+}
+
+func lookups(result string) float64 {
+	return testutil.ToFloat64(metrics.CacheLookups.WithLabelValues("customers", result))
+}
+
+func testCacheHitsAndMisses(t *testing.T) {
+	t.Log("testCacheHitsAndMisses - Testing a success clause for a miss followed by hits, and lookups that bypass the cache")
+	f := newCacheFixture(t, nil)
+	c := f.create(t, "ana@example.com")
+	ctx := context.Background()
+	hits, misses := lookups("hit"), lookups("miss")
+
+	for range 3 {
+		got, err := f.svc.GetByID(ctx, c.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, c.ID, got.ID)
+		assert.True(t, c.Balance.Equal(got.Balance))
+	}
+	assert.Equal(t, 1.0, lookups("miss")-misses)
+	assert.Equal(t, 2.0, lookups("hit")-hits)
+	assert.Equal(t, 1, f.cache.Len())
+
+	_, err := f.svc.Resolve(ctx, "ana@example.com")
+	require.NoError(t, err)
+	_, err = f.svc.GetByID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, customer.ErrNotFound)
+	assert.Equal(t, 1, f.cache.Len(), "lookups by e-mail and misses are not cached")
+}
+
+func testCacheInvalidation(t *testing.T) {
+	t.Log("testCacheInvalidation - Testing a success clause for updates, deposits, rollbacks and deletes seen through the cache")
+	f := newCacheFixture(t, nil)
+	c := f.create(t, "ana@example.com")
+	ctx := context.Background()
+	id := c.ID.String()
+
+	_, err := f.svc.GetByID(ctx, id)
+	require.NoError(t, err)
+	_, err = f.svc.Transactions(ctx, id, 0, decimal.NewFromInt(50))
+	require.NoError(t, err)
+	got, err := f.svc.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "50", got.Balance.String())
+	assert.Equal(t, int64(2), got.Version)
+
+	_, err = f.svc.Update(ctx, id, 0, repositories.Customers{Name: "Ana Souza"})
+	require.NoError(t, err)
+	got, err = f.svc.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Ana Souza", got.Name)
+
+	_, err = f.svc.Transactions(ctx, id, 0, decimal.NewFromInt(-80))
+	assert.ErrorIs(t, err, customer.ErrInsufficientFunds)
+	got, err = f.svc.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "50", got.Balance.String(), "a rolled back withdrawal leaves the balance alone")
+	assert.Equal(t, f.stored(t, c.ID).Version, got.Version)
+
+	require.NoError(t, f.svc.Delete(ctx, id, 0))
+	_, err = f.svc.GetByID(ctx, id)
+	assert.ErrorIs(t, err, customer.ErrNotFound)
+}
+
+func testCacheBehindDatabase(t *testing.T) {
+	t.Log("testCacheBehindDatabase - Testing a success clause for money operations deciding from the database while the cache is behind it")
+	f := newCacheFixture(t, nil)
+	c := f.create(t, "ana@example.com")
+	ctx := context.Background()
+	id := c.ID.String()
+
+	cached, err := f.svc.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "0", cached.Balance.String())
+	// Another instance, or the CLI, deposits behind this one's cache.
+	require.NoError(t, f.db.Model(&repositories.Customers{}).Where("id = ?", id).
+		Updates(map[string]any{"balance": decimal.NewFromInt(10), "version": 2}).Error)
+
+	updated, err := f.svc.Transactions(ctx, id, 2, decimal.NewFromInt(-7))
+	require.NoError(t, err, "the withdrawal is checked against the stored balance and version")
+	assert.Equal(t, "3", updated.Balance.String())
+	assert.Equal(t, int64(3), updated.Version)
+
+	got, err := f.svc.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "3", got.Balance.String())
+}
+
+// pausingRepository holds the next FindOne after it read the row, until
+// released, to interleave a write between the read and the cache fill.
+type pausingRepository struct {
+	repositories.IRepository[repositories.Customers]
+	mu      sync.Mutex
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (p *pausingRepository) pauseNext() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.read = make(chan struct{})
+	p.release = make(chan struct{})
+}
+
+func (p *pausingRepository) FindOne(ctx context.Context, where any) (*repositories.Customers, error) {
+	c, err := p.IRepository.FindOne(ctx, where)
+	p.mu.Lock()
+	read, release := p.read, p.release
+	p.read, p.release = nil, nil
+	p.mu.Unlock()
+	if read != nil {
+		close(read)
+		<-release
+	}
+	return c, err
+}
+
+func testCacheStaleFill(t *testing.T) {
+	t.Log("testCacheStaleFill - Testing a success clause for a lookup racing a deposit that must not cache the old balance")
+	pausing := &pausingRepository{}
+	f := newCacheFixture(t, func(inner repositories.IRepository[repositories.Customers]) repositories.IRepository[repositories.Customers] {
+		pausing.IRepository = inner
+		return pausing
+	})
+	c := f.create(t, "ana@example.com")
+	ctx := context.Background()
+	id := c.ID.String()
+
+	pausing.pauseNext()
+	read, release := pausing.read, pausing.release
+	done := make(chan *repositories.Customers)
+	go func() {
+		got, err := f.svc.GetByID(ctx, id)
+		assert.NoError(t, err)
+		done <- got
+	}()
+	<-read
+
+	_, err := f.svc.Transactions(ctx, id, 0, decimal.NewFromInt(20))
+	require.NoError(t, err)
+	close(release)
+	assert.Equal(t, "0", (<-done).Balance.String(), "the racing lookup returns what it read")
+
+	got, err := f.svc.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "20", got.Balance.String())
+	assert.Equal(t, int64(2), got.Version)
+}
+
+func testCacheConcurrentDeposits(t *testing.T) {
+	t.Log("testCacheConcurrentDeposits - Testing a success clause for deposits and lookups racing on one customer")
+	f := newCacheFixture(t, nil)
+	c := f.create(t, "ana@example.com")
+	ctx := context.Background()
+	id := c.ID.String()
+
+	const depositors, deposits, readers = 8, 5, 4
+	stop := make(chan struct{})
+	var writers, lookers sync.WaitGroup
+	for range readers {
+		lookers.Add(1)
+		go func() {
+			defer lookers.Done()
+			var last int64
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				got, err := f.svc.GetByID(ctx, id)
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.GreaterOrEqual(t, got.Version, last, "a reader never goes back in time")
+				last = got.Version
+			}
+		}()
+	}
+	for range depositors {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for range deposits {
+				// Concurrent deposits may exhaust the retries of a write; the
+				// client would try again.
+				for {
+					_, err := f.svc.Transactions(ctx, id, 0, decimal.NewFromInt(1))
+					if err == customer.ErrVersionMismatch {
+						continue
+					}
+					assert.NoError(t, err)
+					break
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	close(stop)
+	lookers.Wait()
+
+	got, err := f.svc.GetByID(ctx, id)
+	require.NoError(t, err)
+	stored := f.stored(t, c.ID)
+	assert.Equal(t, "40", stored.Balance.String())
+	assert.Equal(t, int64(depositors*deposits+1), stored.Version)
+	assert.Equal(t, stored.Version, got.Version, "the cache ends up at the stored version")
+	assert.True(t, stored.Balance.Equal(got.Balance))
+}
+
+func testMemoryCache(t *testing.T) {
+	t.Log("testMemoryCache - Testing a success clause for LRU eviction, expiry, deletes and clears")
+	ctx := context.Background()
+	cache := repositories.NewMemoryCache(2)
+	evictions := testutil.ToFloat64(metrics.CacheEvictions)
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, cache.Set(ctx, "b", []byte("2"), time.Minute))
+	_, ok, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, cache.Set(ctx, "c", []byte("3"), time.Minute))
+
+	_, ok, _ = cache.Get(ctx, "b")
+	assert.False(t, ok, "the least recently used entry is evicted")
+	value, ok, _ := cache.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CacheEvictions)-evictions)
+
+	require.NoError(t, cache.Delete(ctx, "a"))
+	_, ok, _ = cache.Get(ctx, "a")
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Set(ctx, "d", []byte("4"), 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	_, ok, _ = cache.Get(ctx, "d")
+	assert.False(t, ok, "expired entries are not served")
+
+	require.NoError(t, cache.Clear(ctx))
+	assert.Equal(t, 0, cache.Len())
+}
@@ -1,15 +1,97 @@
 package connection
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// Drivers accepted in Config.Driver.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// sqliteDefaults makes concurrent writers wait for the lock instead of failing
+// with SQLITE_BUSY, and takes the write lock when a transaction begins so that
+// read-then-write transactions cannot deadlock each other.
+const sqliteDefaults = "_busy_timeout=5000&_txlock=immediate"
+
+// Config selects the database and sizes its connection pool. DSN is a file
+// path for SQLite and a URL or key=value connection string for PostgreSQL.
+// Zero pool settings keep the database/sql defaults.
+type Config struct {
+	Driver          string
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// New opens the database selected by cfg.Driver and configures its pool.
+func New(cfg Config) (*gorm.DB, error) {
+	var db *gorm.DB
+	var err error
+	switch cfg.Driver {
+	case DriverSQLite:
+		db, err = NewSqliteConnection(cfg.DSN)
+	case DriverPostgres:
+		db, err = NewPostgresConnection(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+	return db, nil
+}
+
 // NewSqliteConnection returns a gorm DB instance
 func NewSqliteConnection(path string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if !strings.Contains(path, "?") {
+		path += "?" + sqliteDefaults
+	}
+	db, err := gorm.Open(sqlite.Open(path), gormConfig())
 	if err != nil {
 		return nil, err
 	}
 	return db, nil
 }
+
+// NewPostgresConnection returns a gorm DB instance connected to PostgreSQL
+// through pgx.
+func NewPostgresConnection(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig())
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// gormConfig has gorm translate constraint violations into its own errors,
+// such as gorm.ErrDuplicatedKey, whatever the driver.
+func gormConfig() *gorm.Config {
+	return &gorm.Config{TranslateError: true}
+}
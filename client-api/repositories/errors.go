@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// pgInvalidTextRepresentation is the PostgreSQL error raised when a value
+// cannot be converted to the column type, such as a malformed UUID.
+const pgInvalidTextRepresentation = "22P02"
+
+// translate maps database errors to the repository errors, so that services
+// behave the same whatever the driver. Unique violations arrive as
+// gorm.ErrDuplicatedKey since connections enable TranslateError. A lookup key
+// that cannot be of the column type matches no row, as it does on SQLite.
+func translate(operation string, err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrRepoNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrRepoDuplicate
+	case operation != "InsertOne" && errors.As(err, &pgErr) && pgErr.Code == pgInvalidTextRepresentation:
+		return ErrRepoNotFound
+	}
+	return err
+}
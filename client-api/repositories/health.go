@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Models lists every table of the API, parents before children.
+var Models = []any{
+	&Customers{},
+	&Transaction{},
+	&AuditEntry{},
+	&OutboxEvent{},
+	&WebhookSubscription{},
+	&WebhookDelivery{},
+	&ImportJob{},
+	&APIKey{},
+	&APIKeyNonce{},
+	&Credential{},
+	&Session{},
+	&RefreshToken{},
+	&TOTPEnrollment{},
+	&RateLimitCounter{},
+}
+
+// Ping returns a readiness check that round-trips to the database.
+func Ping(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// SchemaMatchesModels returns a readiness check that every table and column
+// of Models exists in the database, which catches a model change shipped
+// without its migration.
+func SchemaMatchesModels(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		migrator := db.WithContext(ctx).Migrator()
+		for _, model := range Models {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(model); err != nil {
+				return err
+			}
+			if !migrator.HasTable(stmt.Table) {
+				return fmt.Errorf("table %s is missing", stmt.Table)
+			}
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+					return fmt.Errorf("column %s.%s is missing", stmt.Table, field.DBName)
+				}
+			}
+		}
+		return nil
+	}
+}
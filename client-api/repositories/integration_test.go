@@ -0,0 +1,308 @@
+//go:build integration
+
+package repositories_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+	"case-itau/repositories/migrations"
+	"case-itau/services/audit"
+	"case-itau/services/customer"
+	"case-itau/services/events"
+	l "case-itau/utils/logger"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// postgresDSN is the database the Postgres run uses: TEST_POSTGRES_DSN when
+// set, which must point to a disposable database since its tables are
+// dropped, or else an embedded server started by TestMain. It stays empty,
+// and the Postgres run is skipped, when neither is available.
+var postgresDSN string
+
+func TestMain(m *testing.M) {
+	l.Logger = zap.NewNop()
+	postgresDSN = os.Getenv("TEST_POSTGRES_DSN")
+	var stop func()
+	if postgresDSN == "" {
+		var err error
+		postgresDSN, stop, err = startEmbeddedPostgres()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "embedded postgres unavailable, skipping postgres tests: %v\n", err)
+		}
+	}
+	code := m.Run()
+	if stop != nil {
+		stop()
+	}
+	os.Exit(code)
+}
+
+func startEmbeddedPostgres() (string, func(), error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	port := uint32(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	dir, err := os.MkdirTemp("", "customer-api-pg-")
+	if err != nil {
+		return "", nil, err
+	}
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		StartTimeout(time.Minute).
+		Logger(io.Discard))
+	if err := pg.Start(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	dsn := fmt.Sprintf("host=127.0.0.1 port=%d user=postgres password=postgres dbname=postgres sslmode=disable", port)
+	return dsn, func() {
+		pg.Stop()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// forEachDriver runs test against a database of every driver brought up to
+// date by the embedded migrations.
+func forEachDriver(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	t.Run(connection.DriverSQLite, func(t *testing.T) {
+		db, err := connection.New(connection.Config{Driver: connection.DriverSQLite, DSN: filepath.Join(t.TempDir(), "it.db")})
+		require.NoError(t, err)
+		migrate(t, db)
+		test(t, db)
+	})
+	t.Run(connection.DriverPostgres, func(t *testing.T) {
+		if postgresDSN == "" {
+			t.Skip("no PostgreSQL available: set TEST_POSTGRES_DSN")
+		}
+		db, err := connection.New(connection.Config{Driver: connection.DriverPostgres, DSN: postgresDSN, MaxOpenConns: 10})
+		require.NoError(t, err)
+		require.NoError(t, db.Migrator().DropTable(append(repositories.Models, "schema_migrations")...))
+		migrate(t, db)
+		test(t, db)
+	})
+}
+
+func migrate(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+}
+
+func TestCases_Repositories_Integration(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T, *gorm.DB)
+	}{
+		{"Success migrating a schema that matches the models", testSchemaMatchesModels},
+		{"Failure inserting a duplicate unique key", testDuplicateKey},
+		{"Failure looking up a malformed key", testMalformedKey},
+		{"Success guarding updates by version", testVersionedUpdate},
+		{"Success rolling back a failed transaction", testTransactionRollback},
+		{"Success counting concurrent rate limit hits", testRateLimitConcurrency},
+		{"Failure creating a customer with a taken e-mail", testCustomerUniqueEmail},
+		{"Success applying concurrent deposits", testCustomerConcurrentDeposits},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			forEachDriver(t, tt.testFunc)
+		})
+	}
+}
+
+func newCustomer(email string) *repositories.Customers {
+	return &repositories.Customers{ID: uuid.New(), Name: "Ana", Email: email, Balance: decimal.Zero, Version: 1}
+}
+
+func testSchemaMatchesModels(t *testing.T, db *gorm.DB) {
+	t.Log("testSchemaMatchesModels - Testing a success clause for the readiness checks of a migrated database")
+	ctx := context.Background()
+	assert.NoError(t, repositories.Ping(db)(ctx))
+	assert.NoError(t, repositories.SchemaMatchesModels(db)(ctx))
+
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	assert.NoError(t, m.Check(ctx))
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	_, err = m.Up(ctx)
+	assert.NoError(t, err)
+}
+
+func testDuplicateKey(t *testing.T, db *gorm.DB) {
+	t.Log("testDuplicateKey - Testing a failure clause for a unique violation mapped to ErrRepoDuplicate")
+	repo := repositories.NewGormRepository[repositories.Customers](db)
+	ctx := context.Background()
+
+	require.NoError(t, repo.InsertOne(ctx, newCustomer("ana@example.com")))
+	err := repo.InsertOne(ctx, newCustomer("ana@example.com"))
+	assert.ErrorIs(t, err, repositories.ErrRepoDuplicate)
+}
+
+func testMalformedKey(t *testing.T, db *gorm.DB) {
+	t.Log("testMalformedKey - Testing a failure clause for a key that is not a UUID matching no row")
+	repo := repositories.NewGormRepository[repositories.Customers](db)
+	ctx := context.Background()
+
+	_, err := repo.FindOne(ctx, map[string]any{"id": "not-a-uuid"})
+	assert.ErrorIs(t, err, repositories.ErrRepoNotFound)
+	err = repo.UpdateOne(ctx, map[string]any{"id": "not-a-uuid"}, map[string]any{"name": "x"})
+	assert.ErrorIs(t, err, repositories.ErrRepoNotFound)
+	err = repo.DeleteOne(ctx, map[string]any{"id": "not-a-uuid"})
+	assert.ErrorIs(t, err, repositories.ErrRepoNotFound)
+}
+
+func testVersionedUpdate(t *testing.T, db *gorm.DB) {
+	t.Log("testVersionedUpdate - Testing a success clause for a decimal balance written under a version guard")
+	repo := repositories.NewGormRepository[repositories.Customers](db)
+	ctx := context.Background()
+	c := newCustomer("versioned@example.com")
+	require.NoError(t, repo.InsertOne(ctx, c))
+
+	where := map[string]any{"id": c.ID.String(), "version": int64(1)}
+	require.NoError(t, repo.UpdateOne(ctx, where, map[string]any{"balance": decimal.RequireFromString("10.25"), "version": int64(2)}))
+	assert.ErrorIs(t, repo.UpdateOne(ctx, where, map[string]any{"version": int64(3)}), repositories.ErrRepoNotFound)
+
+	got, err := repo.FindOne(ctx, map[string]any{"id": c.ID.String()})
+	require.NoError(t, err)
+	assert.Equal(t, "10.25", got.Balance.String())
+	assert.Equal(t, int64(2), got.Version)
+}
+
+func testTransactionRollback(t *testing.T, db *gorm.DB) {
+	t.Log("testTransactionRollback - Testing a success clause for writes and hooks discarded on rollback")
+	repo := repositories.NewGormRepository[repositories.Customers](db)
+	tx := repositories.NewTransactor(db)
+	ctx := context.Background()
+
+	committed := false
+	err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, repo.InsertOne(ctx, newCustomer("rollback@example.com")))
+		repositories.AfterCommit(ctx, func() { committed = true })
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.False(t, committed)
+
+	n, err := repo.Count(ctx, map[string]any{"email": "rollback@example.com"})
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func testRateLimitConcurrency(t *testing.T, db *gorm.DB) {
+	t.Log("testRateLimitConcurrency - Testing a success clause for concurrent upserts losing no hits")
+	store := repositories.NewRateLimitStore(db)
+	window := time.Now().Truncate(time.Minute)
+
+	const hits = 20
+	var wg sync.WaitGroup
+	for range hits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Increment(context.Background(), "ip:10.0.0.1", window)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	count, err := store.Increment(context.Background(), "ip:10.0.0.1", window)
+	require.NoError(t, err)
+	assert.Equal(t, hits+1, count)
+}
+
+func newCustomerService(db *gorm.DB) *customer.Service {
+	return customer.NewService(
+		repositories.NewTransactor(db),
+		repositories.NewGormRepository[repositories.Customers](db),
+		repositories.NewGormRepository[repositories.Transaction](db),
+		audit.NewService(repositories.NewGormRepository[repositories.AuditEntry](db)),
+		events.NewOutbox(repositories.NewGormRepository[repositories.OutboxEvent](db)),
+	)
+}
+
+func testCustomerUniqueEmail(t *testing.T, db *gorm.DB) {
+	t.Log("testCustomerUniqueEmail - Testing a failure clause for ErrUniqueEmail raised by either driver")
+	svc := newCustomerService(db)
+	ctx := context.Background()
+
+	first, err := svc.Create(ctx, repositories.Customers{ID: uuid.New(), Name: "Ana", Email: "ana@example.com"})
+	require.NoError(t, err)
+	_, err = svc.Create(ctx, repositories.Customers{ID: uuid.New(), Name: "Outra Ana", Email: "ana@example.com"})
+	assert.ErrorIs(t, err, customer.ErrUniqueEmail)
+
+	second, err := svc.Create(ctx, repositories.Customers{ID: uuid.New(), Name: "Bia", Email: "bia@example.com"})
+	require.NoError(t, err)
+	_, err = svc.Update(ctx, second.ID.String(), 0, repositories.Customers{Email: first.Email})
+	assert.ErrorIs(t, err, customer.ErrUniqueEmail)
+
+	_, err = svc.GetByID(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, customer.ErrNotFound)
+}
+
+func testCustomerConcurrentDeposits(t *testing.T, db *gorm.DB) {
+	t.Log("testCustomerConcurrentDeposits - Testing a success clause for concurrent deposits all reaching the balance")
+	svc := newCustomerService(db)
+	ctx := context.Background()
+	c, err := svc.Create(ctx, repositories.Customers{ID: uuid.New(), Name: "Ana", Email: "deposits@example.com"})
+	require.NoError(t, err)
+
+	const deposits = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, deposits)
+	for range deposits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Transactions(ctx, c.ID.String(), 0, decimal.NewFromInt(10))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, customer.ErrVersionMismatch)
+	}
+	got, err := svc.GetByID(ctx, c.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, decimal.NewFromInt(int64(10*succeeded)).String(), got.Balance.String())
+	assert.Equal(t, int64(1+succeeded), got.Version)
+	assert.Positive(t, succeeded)
+}
@@ -12,6 +12,7 @@ import (
 var (
 	ErrRepoNotFound         = errors.New("cliente não encontrado")
 	ErrRepoInsufficientFund = errors.New("saldo insuficiente")
+	ErrRepoDuplicate        = errors.New("registro duplicado")
 )
 
 type Customers struct {
@@ -19,6 +20,7 @@ type Customers struct {
 	Name    string          `gorm:"not null" json:"name"`
 	Email   string          `gorm:"not null;unique" json:"email"`
 	Balance decimal.Decimal `gorm:"type:TEXT;not null" json:"balance"`
+	Version int64           `gorm:"not null;default:1" json:"version"`
 }
 
 type Transaction struct {
@@ -27,11 +29,171 @@ type Transaction struct {
 	Customer      Customers       `gorm:"foreignKey:CustomerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 	Amount        decimal.Decimal `gorm:"type:text;not null" json:"amount"`
 	Type          string          `gorm:"type:text;not null" json:"type"`
-	CreatedAt     time.Time       `gorm:"autoCreateTime" json:"created_at"`
+	// Reference identifies the transaction in the system it was imported
+	// from. Each one is booked once.
+	Reference *string   `gorm:"type:text;uniqueIndex:idx_transactions_reference" json:"reference,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// AuditEntry is an append-only record of a change made to a customer. Changes
+// holds a JSON object mapping each changed field to its before/after values.
+type AuditEntry struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	CustomerID uuid.UUID `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Action     string    `gorm:"type:text;not null" json:"action"`
+	Actor      string    `gorm:"type:text;not null" json:"actor"`
+	RequestID  string    `gorm:"type:text" json:"request_id"`
+	ClientIP   string    `gorm:"type:text" json:"client_ip"`
+	Changes    string    `gorm:"type:text;not null" json:"changes"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// OutboxEvent is a domain event written in the same transaction as the state
+// change it describes. The dispatcher delivers events in ID order and stamps
+// DeliveredAt, or FailedAt once it gives up after too many attempts.
+type OutboxEvent struct {
+	ID            uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	AggregateID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"aggregate_id"`
+	Type          string     `gorm:"type:text;not null" json:"type"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error"`
+	NextAttemptAt time.Time  `gorm:"not null" json:"next_attempt_at"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	DeliveredAt   *time.Time `gorm:"index" json:"delivered_at"`
+	FailedAt      *time.Time `json:"failed_at"`
+}
+
+// WebhookSubscription registers a URL to receive the events listed in
+// EventTypes, a comma separated list where "*" matches every event.
+type WebhookSubscription struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	URL        string    `gorm:"type:text;not null" json:"url"`
+	EventTypes string    `gorm:"type:text;not null" json:"event_types"`
+	Secret     string    `gorm:"type:text;not null" json:"-"`
+	Active     bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// WebhookDelivery tracks the delivery of one event to one subscription.
+// Status moves from pending to delivered, or to dead once the attempts run out.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index;uniqueIndex:idx_webhook_deliveries_subscription_event" json:"subscription_id"`
+	EventID        uint64     `gorm:"not null;index;uniqueIndex:idx_webhook_deliveries_subscription_event" json:"event_id"`
+	EventType      string     `gorm:"type:text;not null" json:"event_type"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"type:text;not null;index" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `gorm:"type:text" json:"last_error"`
+	NextAttemptAt  time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// ImportJob is the outcome of a bulk transaction import. Report holds the JSON
+// array of per-row results.
+type ImportJob struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Mode      string    `gorm:"type:text;not null" json:"mode"`
+	Status    string    `gorm:"type:text;not null" json:"status"`
+	TotalRows int       `gorm:"not null" json:"total_rows"`
+	Succeeded int       `gorm:"not null" json:"succeeded"`
+	Failed    int       `gorm:"not null" json:"failed"`
+	Actor     string    `gorm:"type:text;not null" json:"actor"`
+	Report    string    `gorm:"type:text;not null" json:"report"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// APIKey is a credential for machine clients. Only the SHA-256 hash of the key
+// is stored; Prefix is the public part used to look it up. Scopes is a comma
+// separated list of the operations the key may perform.
+type APIKey struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Name             string     `gorm:"type:text;not null" json:"name"`
+	Prefix           string     `gorm:"type:text;not null;uniqueIndex" json:"prefix"`
+	Hash             string     `gorm:"type:text;not null" json:"-"`
+	SigningSecret    string     `gorm:"type:text;not null;default:''" json:"-"`
+	Scopes           string     `gorm:"type:text;not null" json:"scopes"`
+	RequireSignature bool       `gorm:"not null;default:false" json:"require_signature"`
+	CreatedBy        string     `gorm:"type:text;not null" json:"created_by"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// APIKeyNonce remembers the nonces of signed requests for as long as their
+// timestamp is accepted, so that a captured request cannot be replayed.
+type APIKeyNonce struct {
+	KeyID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"key_id"`
+	Nonce     string    `gorm:"type:text;primaryKey" json:"nonce"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// Credential holds the password a customer logs in with, along with the
+// failed attempts counted towards a lockout.
+type Credential struct {
+	CustomerID     uuid.UUID  `gorm:"type:uuid;primaryKey" json:"customer_id"`
+	Customer       Customers  `gorm:"foreignKey:CustomerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	PasswordHash   string     `gorm:"type:text;not null" json:"-"`
+	FailedAttempts int        `gorm:"not null;default:0" json:"failed_attempts"`
+	LockedUntil    *time.Time `json:"locked_until"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Session is a customer login. It lasts until ExpiresAt unless revoked, and is
+// extended one refresh token at a time.
+type Session struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	CustomerID uuid.UUID  `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Customer   Customers  `gorm:"foreignKey:CustomerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	ClientIP   string     `gorm:"type:text" json:"client_ip"`
+	UserAgent  string     `gorm:"type:text" json:"user_agent"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt time.Time  `gorm:"not null" json:"last_used_at"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// RefreshToken is a single-use token of a session, stored as its SHA-256
+// hash. UsedAt is set when it is exchanged; presenting it again revokes the
+// whole session, since only a stolen copy would be replayed.
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SessionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"session_id"`
+	Hash      string     `gorm:"type:text;not null;uniqueIndex" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TOTPEnrollment is the authenticator a customer confirms high-value
+// withdrawals with. It only counts once ConfirmedAt is set; LastUsedStep keeps
+// a code from being accepted twice, and invalid codes count towards a lockout
+// like failed logins do.
+type TOTPEnrollment struct {
+	CustomerID     uuid.UUID  `gorm:"type:uuid;primaryKey" json:"customer_id"`
+	Customer       Customers  `gorm:"foreignKey:CustomerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Secret         string     `gorm:"type:text;not null" json:"-"`
+	ConfirmedAt    *time.Time `json:"confirmed_at"`
+	LastUsedStep   int64      `gorm:"not null;default:0" json:"-"`
+	FailedAttempts int        `gorm:"not null;default:0" json:"-"`
+	LockedUntil    *time.Time `json:"-"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// RateLimitCounter is the number of requests a client made to a class of
+// routes in the window starting at WindowStart, a Unix timestamp.
+type RateLimitCounter struct {
+	Bucket      string `gorm:"type:text;primaryKey"`
+	WindowStart int64  `gorm:"primaryKey;autoIncrement:false;index"`
+	Count       int    `gorm:"not null"`
 }
 
 type IRepository[T any] interface {
-	WithPreload(associations ...string) *gormRepository[T]
+	WithPreload(associations ...string) IRepository[T]
 	Find(ctx context.Context, where any, order string, limit, offset int) ([]T, error)
 	FindOne(ctx context.Context, where any) (*T, error)
 	InsertOne(ctx context.Context, entity *T) error
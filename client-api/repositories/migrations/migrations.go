@@ -0,0 +1,344 @@
+// Package migrations versions the database schema with SQL files embedded in
+// the binary, one directory per driver. Each version is a pair of files named
+// NNNN_name.up.sql and NNNN_name.down.sql whose statements end with a
+// semicolon at the end of a line.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+// Migration is one version of the schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration together with when it was applied, nil while it is
+// pending.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// createVersionTable is valid on every driver, so the version table needs no
+// migration of its own.
+const createVersionTable = "CREATE TABLE IF NOT EXISTS schema_migrations " +
+	"(version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP NOT NULL)"
+
+// schemaMigration is a row of the version table, one per applied migration.
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// legacyColumn is a column that databases created by AutoMigrate may lack
+// because it was added to a table after the table was first created. The
+// CREATE TABLE IF NOT EXISTS statements of the migration introducing it leave
+// such tables alone, so Up adds the column when the migration is applied.
+type legacyColumn struct {
+	Version    int64
+	Table      string
+	Column     string
+	Definition map[string]string
+}
+
+var legacyColumns = []legacyColumn{
+	{Version: 1, Table: "customers", Column: "version", Definition: map[string]string{
+		"sqlite":   "integer NOT NULL DEFAULT 1",
+		"postgres": "bigint NOT NULL DEFAULT 1",
+	}},
+}
+
+// Migrator applies and rolls back the migrations of the driver of db.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New loads the migrations of the driver db is connected with.
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(driver string) ([]Migration, error) {
+	names, err := fs.Glob(files, driver+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, name := range names {
+		base := path.Base(name)
+		stem, direction, ok := cutDirection(base)
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected a .up.sql or .down.sql suffix", name)
+		}
+		prefix, label, _ := strings.Cut(stem, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s: expected a positive version prefix", name)
+		}
+		body, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func cutDirection(name string) (stem, direction string, ok bool) {
+	if stem, ok := strings.CutSuffix(name, ".up.sql"); ok {
+		return stem, "up", true
+	}
+	if stem, ok := strings.CutSuffix(name, ".down.sql"); ok {
+		return stem, "down", true
+	}
+	return "", "", false
+}
+
+// Status lists every known migration in version order, followed by any
+// version recorded in the database that this binary does not know about.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			at := row.AppliedAt
+			s.AppliedAt = &at
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, row := range applied {
+		at := row.AppliedAt
+		statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &at})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending returns the migrations not applied yet, in the order Up applies
+// them.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check fails while migrations are pending, for use as a readiness check.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migration(s), starting at %04d_%s", len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// Ensure returns an error naming the first pending migration while the schema
+// is behind, unless apply is set, in which case it applies them and returns
+// those it applied.
+func (m *Migrator) Ensure(ctx context.Context, apply bool) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the schema version: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	if !apply {
+		return nil, fmt.Errorf("database schema is behind: %d pending migration(s), starting at %04d_%s; run \"migrate up\" or set MIGRATE_ON_START=true",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+	return m.Up(ctx)
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction, and returns those it applied. It stops at the first failure.
+// Concurrent runs cannot apply a migration twice: the version row of the
+// loser conflicts and rolls its transaction back.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		err := m.apply(ctx, migration.Up, func(tx *gorm.DB) error {
+			if err := m.adopt(tx, migration.Version); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns those it rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, errors.New("steps must be at least 1")
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err := m.apply(ctx, migration.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&schemaMigration{Version: migration.Version}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s down: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// apply runs the statements of script and then record in one transaction, so
+// that the schema and the version table cannot disagree.
+func (m *Migrator) apply(ctx context.Context, script string, record func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+// adopt adds the legacy columns of version that the tables of a database
+// created by AutoMigrate are missing.
+func (m *Migrator) adopt(tx *gorm.DB, version int64) error {
+	driver := m.db.Dialector.Name()
+	for _, c := range legacyColumns {
+		if c.Version != version || tx.Migrator().HasColumn(c.Table, c.Column) {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.Table, c.Column, c.Definition[driver])
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", c.Table, c.Column, err)
+		}
+	}
+	return nil
+}
+
+// applied reads the version table, creating it on first use.
+func (m *Migrator) applied(ctx context.Context) (map[int64]schemaMigration, error) {
+	db := m.db.WithContext(ctx)
+	if err := db.Exec(createVersionTable).Error; err != nil {
+		return nil, fmt.Errorf("failed to create the schema version table: %w", err)
+	}
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// statements splits script at semicolons ending a line, dropping comment
+// lines, so that each statement goes to the driver on its own.
+func statements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(line, ";") {
+			if stmt := strings.TrimSpace(current.String()); stmt != ";" {
+				stmts = append(stmts, stmt)
+			}
+			current.Reset()
+		}
+	}
+	if stmt := strings.TrimSpace(current.String()); stmt != "" {
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
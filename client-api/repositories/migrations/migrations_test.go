@@ -0,0 +1,231 @@
+//go:build unit
+
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCases_Migrations_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success loading the migrations of every driver", testLoad},
+		{"Success applying and reverting every migration", testUpDownStatus},
+		{"Success matching the schema of the models", testSchemaMatchesModels},
+		{"Success adopting a schema created by AutoMigrate", testAdoptAutoMigrated},
+		{"Success adopting the baseline schema and its missing columns", testAdoptBaseline},
+		{"Success dropping duplicate webhook deliveries before indexing them", testDeduplicateDeliveries},
+		{"Failure rolling back a broken migration", testFailedMigrationRollsBack},
+		{"Success splitting scripts into statements", testStatements},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func newDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "migrations.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func testLoad(t *testing.T) {
+	t.Log("testLoad - Testing a success clause for paired, ordered migrations with the same versions on every driver")
+	sqlite, err := load(connection.DriverSQLite)
+	require.NoError(t, err)
+	postgres, err := load(connection.DriverPostgres)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, sqlite)
+	require.Len(t, postgres, len(sqlite))
+	for i := range sqlite {
+		assert.Equal(t, sqlite[i].Version, postgres[i].Version)
+		assert.Equal(t, sqlite[i].Name, postgres[i].Name)
+		if i > 0 {
+			assert.Greater(t, sqlite[i].Version, sqlite[i-1].Version)
+		}
+	}
+
+	_, err = load("mysql")
+	assert.ErrorContains(t, err, `no migrations for driver "mysql"`)
+}
+
+func testUpDownStatus(t *testing.T) {
+	t.Log("testUpDownStatus - Testing a success clause for up, status and down round trips")
+	db := newDB(t)
+	m, err := New(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.Error(t, m.Check(ctx))
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.migrations))
+	assert.NoError(t, m.Check(ctx))
+
+	again, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, again, "a second run has nothing to apply")
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(m.migrations))
+	for _, s := range statuses {
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	reverted, err := m.Down(ctx, len(m.migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(m.migrations))
+	assert.Equal(t, m.migrations[len(m.migrations)-1].Version, reverted[0].Version, "the newest migration is reverted first")
+	assert.False(t, db.Migrator().HasTable("customers"))
+
+	pending, err := m.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, len(m.migrations))
+
+	_, err = m.Down(ctx, 0)
+	assert.Error(t, err)
+}
+
+func testSchemaMatchesModels(t *testing.T) {
+	t.Log("testSchemaMatchesModels - Testing a success clause for migrations covering every model column")
+	db := newDB(t)
+	m, err := New(db)
+	require.NoError(t, err)
+
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, repositories.SchemaMatchesModels(db)(context.Background()))
+}
+
+func testAdoptAutoMigrated(t *testing.T) {
+	t.Log("testAdoptAutoMigrated - Testing a success clause for the first migration applied over an existing schema")
+	db := newDB(t)
+	require.NoError(t, db.AutoMigrate(repositories.Models...))
+	// AutoMigrate databases predate the columns later migrations add.
+	require.NoError(t, db.Migrator().DropColumn(&repositories.APIKey{}, "signing_secret"))
+	require.NoError(t, db.Migrator().DropIndex(&repositories.Transaction{}, "idx_transactions_reference"))
+	require.NoError(t, db.Migrator().DropColumn(&repositories.Transaction{}, "reference"))
+	require.NoError(t, db.Exec("INSERT INTO customers (id, name, email, balance, version) VALUES ('c1', 'Ana', 'ana@example.com', '10', 1)").Error)
+
+	m, err := New(db)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+
+	var count int64
+	require.NoError(t, db.Table("customers").Count(&count).Error)
+	assert.Equal(t, int64(1), count, "existing rows are kept")
+}
+
+func testAdoptBaseline(t *testing.T) {
+	t.Log("testAdoptBaseline - Testing a success clause for the first migration applied over the schema that predates customer versions")
+	db := newDB(t)
+	for _, stmt := range []string{
+		"CREATE TABLE `customers` (`id` uuid,`name` text NOT NULL,`email` text NOT NULL,`balance` TEXT NOT NULL,PRIMARY KEY (`id`),CONSTRAINT `uni_customers_email` UNIQUE (`email`))",
+		"CREATE TABLE `transactions` (`transaction_id` uuid,`customer_id` uuid NOT NULL,`amount` text NOT NULL,`type` text NOT NULL,`created_at` datetime,PRIMARY KEY (`transaction_id`),CONSTRAINT `fk_transactions_customer` FOREIGN KEY (`customer_id`) REFERENCES `customers`(`id`) ON DELETE CASCADE ON UPDATE CASCADE)",
+		"CREATE INDEX `idx_transactions_customer_id` ON `transactions`(`customer_id`)",
+		"INSERT INTO customers (id, name, email, balance) VALUES ('c1', 'Ana', 'ana@example.com', '10')",
+	} {
+		require.NoError(t, db.Exec(stmt).Error)
+	}
+	ctx := context.Background()
+
+	m, err := New(db)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	var version int64
+	require.NoError(t, db.Raw("SELECT version FROM customers WHERE id = 'c1'").Scan(&version).Error)
+	assert.Equal(t, int64(1), version, "existing customers start at version 1")
+	require.NoError(t, db.Exec("INSERT INTO customers (id, name, email, balance, version) VALUES ('c2', 'Bia', 'bia@example.com', '0', 1)").Error)
+	assert.NoError(t, repositories.SchemaMatchesModels(db)(ctx))
+
+	_, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	assert.NoError(t, err, "a schema created by the migration needs no adoption")
+}
+
+func testDeduplicateDeliveries(t *testing.T) {
+	t.Log("testDeduplicateDeliveries - Testing a success clause for the earliest delivery of an event kept per subscription")
+	db := newDB(t)
+	m, err := New(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	// Revert to the schema before deliveries were indexed, version 2.
+	_, err = m.Down(ctx, len(m.migrations)-2)
+	require.NoError(t, err)
+	for _, d := range []struct{ id, sub, created string }{
+		{"d1", "s1", "2024-01-01 10:00:01"},
+		{"d2", "s1", "2024-01-01 10:00:00"},
+		{"d3", "s1", "2024-01-01 10:00:00"},
+		{"d4", "s2", "2024-01-01 10:00:02"},
+	} {
+		require.NoError(t, db.Exec("INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at) VALUES (?, ?, 1, 'deposit.booked', '{}', 'pending', ?, ?)", d.id, d.sub, d.created, d.created).Error)
+	}
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	var ids []string
+	require.NoError(t, db.Raw("SELECT id FROM webhook_deliveries ORDER BY id").Scan(&ids).Error)
+	assert.Equal(t, []string{"d2", "d4"}, ids)
+	assert.Error(t, db.Exec("INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, next_attempt_at) VALUES ('d5', 's2', 1, 'deposit.booked', '{}', 'pending', '2024-01-01 10:00:03')").Error)
+}
+
+func testFailedMigrationRollsBack(t *testing.T) {
+	t.Log("testFailedMigrationRollsBack - Testing a failure clause for a migration failing halfway through")
+	db := newDB(t)
+	m := &Migrator{db: db, migrations: []Migration{{
+		Version: 1,
+		Name:    "broken",
+		Up:      "CREATE TABLE first (id integer);\nCREATE TABLE broken (;\n",
+		Down:    "DROP TABLE first;\n",
+	}}}
+	ctx := context.Background()
+
+	_, err := m.Up(ctx)
+	require.ErrorContains(t, err, "migration 0001_broken up")
+	assert.False(t, db.Migrator().HasTable("first"), "statements before the failure are rolled back")
+
+	pending, err := m.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
+
+func testStatements(t *testing.T) {
+	t.Log("testStatements - Testing a success clause for comments, multi-line statements and a missing final semicolon")
+	script := "-- comment; ignored\nCREATE TABLE a (\n  id integer\n);\r\n\nCREATE INDEX i ON a(id);\nDROP TABLE b"
+
+	assert.Equal(t, []string{
+		"CREATE TABLE a (\n  id integer\n);",
+		"CREATE INDEX i ON a(id);",
+		"DROP TABLE b",
+	}, statements(script))
+}
@@ -0,0 +1,47 @@
+-- The schema the API created with AutoMigrate before versioned migrations.
+-- Every statement is guarded so that databases created that way adopt this
+-- version; the columns added to their tables over time are added by the
+-- migrator, see legacyColumns.
+
+CREATE TABLE IF NOT EXISTS "customers" ("id" uuid,"name" text NOT NULL,"email" text NOT NULL,"balance" TEXT NOT NULL,"version" bigint NOT NULL DEFAULT 1,PRIMARY KEY ("id"),CONSTRAINT "uni_customers_email" UNIQUE ("email"));
+
+CREATE TABLE IF NOT EXISTS "transactions" ("transaction_id" uuid,"customer_id" uuid NOT NULL,"amount" text NOT NULL,"type" text NOT NULL,"created_at" timestamptz,PRIMARY KEY ("transaction_id"),CONSTRAINT "fk_transactions_customer" FOREIGN KEY ("customer_id") REFERENCES "customers"("id") ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_transactions_customer_id" ON "transactions" ("customer_id");
+
+CREATE TABLE IF NOT EXISTS "audit_entries" ("id" uuid,"customer_id" uuid NOT NULL,"action" text NOT NULL,"actor" text NOT NULL,"request_id" text,"client_ip" text,"changes" text NOT NULL,"created_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_audit_entries_created_at" ON "audit_entries" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_entries_customer_id" ON "audit_entries" ("customer_id");
+
+CREATE TABLE IF NOT EXISTS "outbox_events" ("id" bigserial,"aggregate_id" uuid NOT NULL,"type" text NOT NULL,"payload" text NOT NULL,"attempts" bigint NOT NULL DEFAULT 0,"last_error" text,"next_attempt_at" timestamptz NOT NULL,"created_at" timestamptz,"delivered_at" timestamptz,"failed_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_delivered_at" ON "outbox_events" ("delivered_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_aggregate_id" ON "outbox_events" ("aggregate_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_subscriptions" ("id" uuid,"url" text NOT NULL,"event_types" text NOT NULL,"secret" text NOT NULL,"active" boolean NOT NULL DEFAULT true,"created_at" timestamptz,"updated_at" timestamptz,PRIMARY KEY ("id"));
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" ("id" uuid,"subscription_id" uuid NOT NULL,"event_id" bigint NOT NULL,"event_type" text NOT NULL,"payload" text NOT NULL,"status" text NOT NULL,"attempts" bigint NOT NULL DEFAULT 0,"last_status_code" bigint,"last_error" text,"next_attempt_at" timestamptz NOT NULL,"created_at" timestamptz,"delivered_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_next_attempt_at" ON "webhook_deliveries" ("next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_status" ON "webhook_deliveries" ("status");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_event_id" ON "webhook_deliveries" ("event_id");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_subscription_id" ON "webhook_deliveries" ("subscription_id");
+
+CREATE TABLE IF NOT EXISTS "import_jobs" ("id" uuid,"mode" text NOT NULL,"status" text NOT NULL,"total_rows" bigint NOT NULL,"succeeded" bigint NOT NULL,"failed" bigint NOT NULL,"actor" text NOT NULL,"report" text NOT NULL,"created_at" timestamptz,PRIMARY KEY ("id"));
+
+CREATE TABLE IF NOT EXISTS "api_keys" ("id" uuid,"name" text NOT NULL,"prefix" text NOT NULL,"hash" text NOT NULL,"scopes" text NOT NULL,"require_signature" boolean NOT NULL DEFAULT false,"created_by" text NOT NULL,"expires_at" timestamptz,"revoked_at" timestamptz,"last_used_at" timestamptz,"created_at" timestamptz,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_keys_prefix" ON "api_keys" ("prefix");
+
+CREATE TABLE IF NOT EXISTS "api_key_nonces" ("key_id" uuid,"nonce" text,"created_at" timestamptz,PRIMARY KEY ("key_id","nonce"));
+CREATE INDEX IF NOT EXISTS "idx_api_key_nonces_created_at" ON "api_key_nonces" ("created_at");
+
+CREATE TABLE IF NOT EXISTS "credentials" ("customer_id" uuid,"password_hash" text NOT NULL,"failed_attempts" bigint NOT NULL DEFAULT 0,"locked_until" timestamptz,"updated_at" timestamptz,PRIMARY KEY ("customer_id"),CONSTRAINT "fk_credentials_customer" FOREIGN KEY ("customer_id") REFERENCES "customers"("id") ON DELETE CASCADE ON UPDATE CASCADE);
+
+CREATE TABLE IF NOT EXISTS "sessions" ("id" uuid,"customer_id" uuid NOT NULL,"client_ip" text,"user_agent" text,"expires_at" timestamptz NOT NULL,"revoked_at" timestamptz,"last_used_at" timestamptz NOT NULL,"created_at" timestamptz,PRIMARY KEY ("id"),CONSTRAINT "fk_sessions_customer" FOREIGN KEY ("customer_id") REFERENCES "customers"("id") ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_sessions_customer_id" ON "sessions" ("customer_id");
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" ("id" uuid,"session_id" uuid NOT NULL,"hash" text NOT NULL,"used_at" timestamptz,"created_at" timestamptz,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refresh_tokens_hash" ON "refresh_tokens" ("hash");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_session_id" ON "refresh_tokens" ("session_id");
+
+CREATE TABLE IF NOT EXISTS "totp_enrollments" ("customer_id" uuid,"secret" text NOT NULL,"confirmed_at" timestamptz,"last_used_step" bigint NOT NULL DEFAULT 0,"failed_attempts" bigint NOT NULL DEFAULT 0,"locked_until" timestamptz,"created_at" timestamptz,PRIMARY KEY ("customer_id"),CONSTRAINT "fk_totp_enrollments_customer" FOREIGN KEY ("customer_id") REFERENCES "customers"("id") ON DELETE CASCADE ON UPDATE CASCADE);
+
+CREATE TABLE IF NOT EXISTS "rate_limit_counters" ("bucket" text,"window_start" bigint,"count" bigint NOT NULL,PRIMARY KEY ("bucket","window_start"));
+CREATE INDEX IF NOT EXISTS "idx_rate_limit_counters_window_start" ON "rate_limit_counters" ("window_start");
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "idx_transactions_reference";
+ALTER TABLE "transactions" DROP COLUMN "reference";
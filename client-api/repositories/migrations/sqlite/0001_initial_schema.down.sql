@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS `rate_limit_counters`;
+DROP TABLE IF EXISTS `totp_enrollments`;
+DROP TABLE IF EXISTS `refresh_tokens`;
+DROP TABLE IF EXISTS `sessions`;
+DROP TABLE IF EXISTS `credentials`;
+DROP TABLE IF EXISTS `api_key_nonces`;
+DROP TABLE IF EXISTS `api_keys`;
+DROP TABLE IF EXISTS `import_jobs`;
+DROP TABLE IF EXISTS `webhook_deliveries`;
+DROP TABLE IF EXISTS `webhook_subscriptions`;
+DROP TABLE IF EXISTS `outbox_events`;
+DROP TABLE IF EXISTS `audit_entries`;
+DROP TABLE IF EXISTS `transactions`;
+DROP TABLE IF EXISTS `customers`;
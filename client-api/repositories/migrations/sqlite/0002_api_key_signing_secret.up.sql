@@ -0,0 +1,5 @@
+-- Requests are signed with a secret issued with the key instead of a hash of
+-- the key itself, which travels with every request. Keys issued before keep an
+-- empty secret and cannot sign until reissued.
+
+ALTER TABLE `api_keys` ADD COLUMN `signing_secret` text NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+-- The outbox publishes an event again when a previous attempt failed part way,
+-- so each subscription holds at most one delivery per event. Copies inserted
+-- before the index existed are dropped, keeping the earliest one.
+
+DELETE FROM `webhook_deliveries` WHERE EXISTS (SELECT 1 FROM `webhook_deliveries` AS `o` WHERE `o`.`subscription_id` = `webhook_deliveries`.`subscription_id` AND `o`.`event_id` = `webhook_deliveries`.`event_id` AND (`o`.`created_at` < `webhook_deliveries`.`created_at` OR (`o`.`created_at` = `webhook_deliveries`.`created_at` AND `o`.`id` < `webhook_deliveries`.`id`)));
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_webhook_deliveries_subscription_event` ON `webhook_deliveries`(`subscription_id`,`event_id`);
@@ -0,0 +1,6 @@
+-- Imported transactions keep the reference they have in the system they come
+-- from, and each reference is booked once so that uploading a file again does
+-- not book its rows twice.
+
+ALTER TABLE `transactions` ADD COLUMN `reference` text;
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_transactions_reference` ON `transactions`(`reference`);
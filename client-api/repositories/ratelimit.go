@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RateLimitStore keeps rate limit counters in the database, so that they
+// survive restarts and are shared by every instance using it. Counts are made
+// outside any transaction of the caller, so that rolling it back does not undo
+// them.
+type RateLimitStore struct {
+	db *gorm.DB
+}
+
+func NewRateLimitStore(db *gorm.DB) *RateLimitStore {
+	return &RateLimitStore{db: db}
+}
+
+// Increment upserts the counter in a single statement, so that concurrent
+// requests cannot lose hits.
+func (s *RateLimitStore) Increment(ctx context.Context, key string, windowStart time.Time) (int, error) {
+	var count int
+	err := s.db.WithContext(ctx).Raw(
+		"INSERT INTO rate_limit_counters (bucket, window_start, count) VALUES (?, ?, 1) "+
+			"ON CONFLICT (bucket, window_start) DO UPDATE SET count = rate_limit_counters.count + 1 "+
+			"RETURNING count",
+		key, windowStart.Unix(),
+	).Scan(&count).Error
+	return count, err
+}
+
+func (s *RateLimitStore) Count(ctx context.Context, key string, windowStart time.Time) (int, error) {
+	var count int
+	err := s.db.WithContext(ctx).Model(&RateLimitCounter{}).
+		Select("count").
+		Where("bucket = ? AND window_start = ?", key, windowStart.Unix()).
+		Scan(&count).Error
+	return count, err
+}
+
+func (s *RateLimitStore) Purge(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).Where("window_start < ?", before.Unix()).Delete(&RateLimitCounter{}).Error
+}
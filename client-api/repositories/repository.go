@@ -2,29 +2,94 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"reflect"
+	"time"
 
+	l "case-itau/utils/logger"
+	"case-itau/utils/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type gormRepository[T any] struct {
-	db *gorm.DB
+	db       *gorm.DB
+	preloads []string
+}
+
+// Expr builds a raw SQL condition with ? placeholders that can be passed as the
+// where argument of IRepository methods, for filters a map cannot express.
+func Expr(sql string, args ...any) any {
+	return gorm.Expr(sql, args...)
 }
 
 func NewGormRepository[T any](db *gorm.DB) IRepository[T] {
 	return &gormRepository[T]{db: db}
 }
 
-func (r *gormRepository[T]) WithPreload(associations ...string) *gormRepository[T] {
-	db := r.db
-	for _, assoc := range associations {
+func (r *gormRepository[T]) WithPreload(associations ...string) IRepository[T] {
+	preloads := append(append([]string{}, r.preloads...), associations...)
+	return &gormRepository[T]{db: r.db, preloads: preloads}
+}
+
+// session returns the connection to use for ctx, joining the transaction
+// started by a Transactor when there is one.
+func (r *gormRepository[T]) session(ctx context.Context) *gorm.DB {
+	db := conn(ctx, r.db)
+	for _, assoc := range r.preloads {
 		db = db.Preload(assoc)
 	}
-	return &gormRepository[T]{db: db}
+	return db
+}
+
+// call is a repository operation being traced and logged.
+type call struct {
+	ctx       context.Context
+	span      trace.Span
+	entity    string
+	operation string
+	start     time.Time
+}
+
+// begin opens the span of a repository operation, named after the entity.
+func (r *gormRepository[T]) begin(ctx context.Context, operation string) (context.Context, *call) {
+	entity := reflect.TypeFor[T]().Name()
+	ctx, span := tracing.Start(ctx, "repository."+entity+"."+operation,
+		attribute.String("db.operation.name", operation),
+		attribute.String("db.entity", entity),
+	)
+	return ctx, &call{ctx: ctx, span: span, entity: entity, operation: operation, start: time.Now()}
+}
+
+// end translates the error of the operation, ends its span and logs it
+// through the request logger. ErrRepoNotFound and ErrRepoDuplicate are
+// expected outcomes rather than failures.
+func (c *call) end(err *error) {
+	*err = translate(c.operation, *err)
+
+	lg := l.FromContext(c.ctx).With(
+		zap.String("entity", c.entity),
+		zap.String("operation", c.operation),
+		zap.Duration("duration", time.Since(c.start)),
+	)
+	if *err != nil && !errors.Is(*err, ErrRepoNotFound) && !errors.Is(*err, ErrRepoDuplicate) {
+		lg.Error("repository call failed", zap.Error(*err))
+		tracing.End(c.span, err)
+		return
+	}
+	lg.Debug("repository call")
+	c.span.End()
 }
 
-func (r *gormRepository[T]) Find(ctx context.Context, where any, order string, limit, offset int) ([]T, error) {
+func (r *gormRepository[T]) Find(ctx context.Context, where any, order string, limit, offset int) (_ []T, err error) {
+	ctx, c := r.begin(ctx, "Find")
+	defer c.end(&err)
+
 	var results []T
-	tx := r.db.WithContext(ctx)
+	tx := r.session(ctx)
 	if where != nil {
 		tx = tx.Where(where)
 	}
@@ -43,9 +108,12 @@ func (r *gormRepository[T]) Find(ctx context.Context, where any, order string, l
 	return results, nil
 }
 
-func (r *gormRepository[T]) FindOne(ctx context.Context, where any) (*T, error) {
+func (r *gormRepository[T]) FindOne(ctx context.Context, where any) (_ *T, err error) {
+	ctx, c := r.begin(ctx, "FindOne")
+	defer c.end(&err)
+
 	var result T
-	tx := r.db.WithContext(ctx)
+	tx := r.session(ctx)
 	if where != nil {
 		tx = tx.Where(where)
 	}
@@ -55,21 +123,51 @@ func (r *gormRepository[T]) FindOne(ctx context.Context, where any) (*T, error)
 	return &result, nil
 }
 
-func (r *gormRepository[T]) InsertOne(ctx context.Context, entity *T) error {
-	return r.db.WithContext(ctx).Create(entity).Error
+func (r *gormRepository[T]) InsertOne(ctx context.Context, entity *T) (err error) {
+	ctx, c := r.begin(ctx, "InsertOne")
+	defer c.end(&err)
+
+	return r.session(ctx).Create(entity).Error
 }
 
-func (r *gormRepository[T]) UpdateOne(ctx context.Context, where any, updates map[string]any) error {
-	return r.db.WithContext(ctx).Model(new(T)).Where(where).Updates(updates).Error
+// UpdateOne applies updates to the rows matching where and returns
+// ErrRepoNotFound when nothing matched.
+func (r *gormRepository[T]) UpdateOne(ctx context.Context, where any, updates map[string]any) (err error) {
+	ctx, c := r.begin(ctx, "UpdateOne")
+	defer c.end(&err)
+
+	tx := r.session(ctx).Model(new(T)).Where(where).Updates(updates)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return ErrRepoNotFound
+	}
+	return nil
 }
 
-func (r *gormRepository[T]) DeleteOne(ctx context.Context, where any) error {
-	return r.db.WithContext(ctx).Where(where).Delete(new(T)).Error
+// DeleteOne removes the rows matching where and returns ErrRepoNotFound when
+// nothing matched.
+func (r *gormRepository[T]) DeleteOne(ctx context.Context, where any) (err error) {
+	ctx, c := r.begin(ctx, "DeleteOne")
+	defer c.end(&err)
+
+	tx := r.session(ctx).Where(where).Delete(new(T))
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return ErrRepoNotFound
+	}
+	return nil
 }
 
-func (r *gormRepository[T]) Count(ctx context.Context, where any) (int64, error) {
+func (r *gormRepository[T]) Count(ctx context.Context, where any) (_ int64, err error) {
+	ctx, c := r.begin(ctx, "Count")
+	defer c.end(&err)
+
 	var count int64
-	tx := r.db.WithContext(ctx)
+	tx := r.session(ctx)
 	if where != nil {
 		tx = tx.Where(where)
 	}
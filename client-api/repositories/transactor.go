@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Transactor runs a unit of work inside a database transaction. Repositories
+// created by NewGormRepository pick the transaction up from the context, so
+// services only need to pass the context they receive in fn.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// txState is the transaction bound to a context plus the callbacks waiting for
+// it to commit.
+type txState struct {
+	db          *gorm.DB
+	afterCommit []func()
+}
+
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &gormTransactor{db: db}
+}
+
+// WithinTransaction commits when fn returns nil and rolls back otherwise.
+// Calls nested inside an existing transaction join it instead of opening a
+// new one.
+func (t *gormTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	state := &txState{}
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state.db = tx
+		return fn(context.WithValue(ctx, txKey{}, state))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range state.afterCommit {
+		hook()
+	}
+	return nil
+}
+
+// AfterCommit schedules fn to run once the transaction bound to ctx commits.
+// It is dropped if the transaction rolls back, and runs immediately when ctx
+// carries no transaction.
+func AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// conn returns the transaction bound to ctx, or db when there is none.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.db.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
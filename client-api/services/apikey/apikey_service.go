@@ -0,0 +1,250 @@
+package apikey
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/utils/requestctx"
+
+	"github.com/google/uuid"
+)
+
+// Scopes grantable to a key.
+const (
+	ScopeRead     = "read"
+	ScopeWrite    = "write"
+	ScopeDeposit  = "deposit"
+	ScopeWithdraw = "withdraw"
+	ScopeImport   = "import"
+)
+
+// Scopes lists every scope, in the order they are documented.
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeDeposit, ScopeWithdraw, ScopeImport}
+
+// Headers carrying the key and the optional request signature.
+const (
+	HeaderAPIKey    = "X-API-Key"
+	HeaderSignature = "X-Signature"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+)
+
+// keyPrefix starts every key so that leaked keys are easy to spot in logs and
+// by secret scanners.
+const keyPrefix = "ck"
+
+var (
+	ErrNotFound          = errors.New("chave de API não encontrada")
+	ErrInvalidKey        = errors.New("chave de API inválida")
+	ErrKeyExpired        = errors.New("chave de API expirada")
+	ErrKeyRevoked        = errors.New("chave de API revogada")
+	ErrSignatureRequired = errors.New("assinatura da requisição obrigatória")
+	ErrInvalidSignature  = errors.New("assinatura da requisição inválida")
+	ErrStaleTimestamp    = errors.New("timestamp da requisição fora da janela aceita")
+	ErrReplayedNonce     = errors.New("nonce já utilizado")
+)
+
+// SignedRequest holds the parts of a request covered by its signature.
+type SignedRequest struct {
+	Method    string
+	Path      string
+	Timestamp string
+	Nonce     string
+	Body      []byte
+	Signature string
+}
+
+type Service struct {
+	keys   repositories.IRepository[repositories.APIKey]
+	nonces repositories.IRepository[repositories.APIKeyNonce]
+	window time.Duration
+
+	lastPurge atomic.Int64
+}
+
+// NewService builds the key service. Signed requests are accepted while their
+// timestamp is within window of the server clock.
+func NewService(keys repositories.IRepository[repositories.APIKey], nonces repositories.IRepository[repositories.APIKeyNonce], window time.Duration) *Service {
+	return &Service{keys: keys, nonces: nonces, window: window}
+}
+
+// Create issues a key along with the secret that signs its requests. The
+// plain key is only ever returned by this call, and the signing secret only
+// leaves the server in its response.
+func (s *Service) Create(ctx context.Context, name string, scopes []string, expiresAt *time.Time, requireSignature bool) (*repositories.APIKey, string, error) {
+	prefix, err := randomString(6, hex.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	signingSecret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	raw := keyPrefix + "_" + prefix + "_" + secret
+
+	key := &repositories.APIKey{
+		ID:               uuid.New(),
+		Name:             name,
+		Prefix:           prefix,
+		Hash:             hashKey(raw),
+		SigningSecret:    signingSecret,
+		Scopes:           strings.Join(scopes, ","),
+		RequireSignature: requireSignature,
+		CreatedBy:        requestctx.FromContext(ctx).Actor,
+		ExpiresAt:        expiresAt,
+	}
+	if err := s.keys.InsertOne(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, raw, nil
+}
+
+func (s *Service) List(ctx context.Context) ([]repositories.APIKey, error) {
+	return s.keys.Find(ctx, nil, "created_at ASC", 0, 0)
+}
+
+// Revoke disables a key for good. Revoking an already revoked key is a no-op.
+func (s *Service) Revoke(ctx context.Context, id string) error {
+	key, err := s.keys.FindOne(ctx, map[string]any{"id": id})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return s.keys.UpdateOne(ctx, map[string]any{"id": id}, map[string]any{"revoked_at": time.Now()})
+}
+
+// Authenticate returns the active key that made req, whose X-API-Key header
+// is raw. Unsigned requests must carry the whole key and are refused for keys
+// requiring a signature. Signed requests only need to name the key, as
+// ck_<prefix>, since the signature made with its signing secret proves who
+// sent them; when the whole key is sent anyway, it must match.
+func (s *Service) Authenticate(ctx context.Context, raw string, req SignedRequest) (*repositories.APIKey, error) {
+	parts := strings.SplitN(raw, "_", 3)
+	if len(parts) < 2 || parts[0] != keyPrefix || (len(parts) == 2 && req.Signature == "") {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.keys.FindOne(ctx, map[string]any{"prefix": parts[1]})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+	if len(parts) == 3 && subtle.ConstantTimeCompare([]byte(hashKey(raw)), []byte(key.Hash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrKeyRevoked
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, ErrKeyExpired
+	}
+	if err := s.verifySignature(ctx, key, req, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.keys.UpdateOne(ctx, map[string]any{"id": key.ID}, map[string]any{"last_used_at": now}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// verifySignature checks the HMAC of a request made with key and records its
+// nonce. Unsigned requests pass unless the key requires signing. Keys issued
+// before signing secrets existed cannot sign and have to be reissued.
+func (s *Service) verifySignature(ctx context.Context, key *repositories.APIKey, req SignedRequest, now time.Time) error {
+	if req.Signature == "" {
+		if key.RequireSignature {
+			return ErrSignatureRequired
+		}
+		return nil
+	}
+	if req.Nonce == "" || len(req.Nonce) > 128 || key.SigningSecret == "" {
+		return ErrInvalidSignature
+	}
+
+	ts, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleTimestamp
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > s.window || skew < -s.window {
+		return ErrStaleTimestamp
+	}
+
+	expected := Sign([]byte(key.SigningSecret), req)
+	if !hmac.Equal([]byte(expected), []byte(req.Signature)) {
+		return ErrInvalidSignature
+	}
+
+	s.purgeNonces(ctx, now)
+	if err := s.nonces.InsertOne(ctx, &repositories.APIKeyNonce{KeyID: key.ID, Nonce: req.Nonce}); err != nil {
+		if errors.Is(err, repositories.ErrRepoDuplicate) {
+			return ErrReplayedNonce
+		}
+		return err
+	}
+	return nil
+}
+
+// hashKey is what is stored of a key, so that the server never needs to keep
+// the key itself.
+func hashKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign returns the "sha256=<hex>" signature of req: an HMAC-SHA256 keyed with
+// the signing secret of the key over the method, path, timestamp, nonce and
+// hex SHA-256 of the body, one per line.
+func Sign(signingSecret []byte, req SignedRequest) string {
+	body := sha256.Sum256(req.Body)
+	mac := hmac.New(sha256.New, signingSecret)
+	mac.Write([]byte(strings.Join([]string{
+		strings.ToUpper(req.Method),
+		req.Path,
+		req.Timestamp,
+		req.Nonce,
+		hex.EncodeToString(body[:]),
+	}, "\n")))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// purgeNonces drops nonces too old to matter, at most once per window.
+func (s *Service) purgeNonces(ctx context.Context, now time.Time) {
+	last := s.lastPurge.Load()
+	if now.UnixNano()-last < int64(s.window) || !s.lastPurge.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	// A failed purge is retried by a later request, so the error is dropped.
+	_ = s.nonces.DeleteOne(ctx, repositories.Expr("created_at < ?", now.Add(-2*s.window)))
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
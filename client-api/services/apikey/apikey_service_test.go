@@ -0,0 +1,169 @@
+//go:build unit
+
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+	l "case-itau/utils/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newService(t *testing.T) *Service {
+	l.Logger = zap.NewNop()
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "keys.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&repositories.APIKey{}, &repositories.APIKeyNonce{}))
+
+	return NewService(repositories.NewGormRepository[repositories.APIKey](db), repositories.NewGormRepository[repositories.APIKeyNonce](db), time.Minute)
+}
+
+func TestCases_APIKey_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success authenticating a key and checking its scopes", testAuthenticate},
+		{"Failure authenticating revoked, expired and unknown keys", testAuthenticateRejects},
+		{"Success verifying a signature and rejecting its replay", testSignatureReplay},
+		{"Failure verifying tampered, stale and missing signatures", testSignatureRejects},
+		{"Failure signing with the key instead of its signing secret", testSignatureNeedsSecret},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testAuthenticate(t *testing.T) {
+	t.Log("testAuthenticate - Testing that the plain key maps back to its scopes")
+	svc := newService(t)
+	ctx := context.Background()
+	created, raw, err := svc.Create(ctx, "batch", []string{ScopeRead, ScopeDeposit}, nil, false)
+	require.NoError(t, err)
+	assert.NotContains(t, created.Hash, raw)
+	assert.NotEmpty(t, created.SigningSecret)
+	assert.NotContains(t, raw, created.SigningSecret)
+
+	key, err := svc.Authenticate(ctx, raw, SignedRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+	assert.Equal(t, "read,deposit", key.Scopes)
+}
+
+func testAuthenticateRejects(t *testing.T) {
+	t.Log("testAuthenticateRejects - Testing a failure clause for each unusable key")
+	svc := newService(t)
+	ctx := context.Background()
+
+	revoked, raw, err := svc.Create(ctx, "revoked", []string{ScopeRead}, nil, false)
+	require.NoError(t, err)
+	require.NoError(t, svc.Revoke(ctx, revoked.ID.String()))
+	_, err = svc.Authenticate(ctx, raw, SignedRequest{})
+	assert.ErrorIs(t, err, ErrKeyRevoked)
+
+	past := time.Now().Add(-time.Minute)
+	_, raw, err = svc.Create(ctx, "expired", []string{ScopeRead}, &past, false)
+	require.NoError(t, err)
+	_, err = svc.Authenticate(ctx, raw, SignedRequest{})
+	assert.ErrorIs(t, err, ErrKeyExpired)
+
+	_, err = svc.Authenticate(ctx, raw[:len(raw)-1]+"x", SignedRequest{})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = svc.Authenticate(ctx, "not-a-key", SignedRequest{})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func signed(key *repositories.APIKey, nonce string, at time.Time) SignedRequest {
+	req := SignedRequest{
+		Method:    "POST",
+		Path:      "/clientes/1/depositar",
+		Timestamp: strconv.FormatInt(at.Unix(), 10),
+		Nonce:     nonce,
+		Body:      []byte(`{"amount":10}`),
+	}
+	req.Signature = Sign([]byte(key.SigningSecret), req)
+	return req
+}
+
+// keyID is the X-API-Key value of a signed request that leaves out the secret
+// part of the key.
+func keyID(key *repositories.APIKey) string {
+	return keyPrefix + "_" + key.Prefix
+}
+
+func testSignatureReplay(t *testing.T) {
+	t.Log("testSignatureReplay - Testing that a nonce is accepted only once")
+	svc := newService(t)
+	ctx := context.Background()
+	key, _, err := svc.Create(ctx, "signed", []string{ScopeDeposit}, nil, true)
+	require.NoError(t, err)
+
+	req := signed(key, "n-1", time.Now())
+	got, err := svc.Authenticate(ctx, keyID(key), req)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, got.ID)
+	_, err = svc.Authenticate(ctx, keyID(key), req)
+	assert.ErrorIs(t, err, ErrReplayedNonce)
+}
+
+func testSignatureRejects(t *testing.T) {
+	t.Log("testSignatureRejects - Testing a failure clause for each unusable signature")
+	svc := newService(t)
+	ctx := context.Background()
+	key, raw, err := svc.Create(ctx, "signed", []string{ScopeDeposit}, nil, true)
+	require.NoError(t, err)
+
+	tampered := signed(key, "n-1", time.Now())
+	tampered.Body = []byte(`{"amount":1000}`)
+	_, err = svc.Authenticate(ctx, keyID(key), tampered)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	stale := signed(key, "n-2", time.Now().Add(-2*time.Minute))
+	_, err = svc.Authenticate(ctx, keyID(key), stale)
+	assert.ErrorIs(t, err, ErrStaleTimestamp)
+
+	_, err = svc.Authenticate(ctx, raw, SignedRequest{Method: "GET", Path: "/clientes"})
+	assert.ErrorIs(t, err, ErrSignatureRequired)
+	_, err = svc.Authenticate(ctx, keyID(key), SignedRequest{Method: "GET", Path: "/clientes"})
+	assert.ErrorIs(t, err, ErrInvalidKey, "the key ID alone does not authenticate an unsigned request")
+}
+
+func testSignatureNeedsSecret(t *testing.T) {
+	t.Log("testSignatureNeedsSecret - Testing a failure clause for signatures made from the key instead of its signing secret")
+	svc := newService(t)
+	ctx := context.Background()
+	key, raw, err := svc.Create(ctx, "signed", []string{ScopeDeposit}, nil, true)
+	require.NoError(t, err)
+
+	// Whoever sees a request carrying the whole key must not be able to sign
+	// new ones with it.
+	req := signed(key, "n-1", time.Now())
+	sum := sha256.Sum256([]byte(raw))
+	req.Signature = Sign(sum[:], req)
+	_, err = svc.Authenticate(ctx, raw, req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	req.Signature = Sign([]byte(raw), req)
+	_, err = svc.Authenticate(ctx, raw, req)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	legacy, legacyRaw, err := svc.Create(ctx, "legacy", []string{ScopeDeposit}, nil, false)
+	require.NoError(t, err)
+	legacy.SigningSecret = ""
+	require.NoError(t, svc.keys.UpdateOne(ctx, map[string]any{"id": legacy.ID}, map[string]any{"signing_secret": ""}))
+	_, err = svc.Authenticate(ctx, legacyRaw, signed(legacy, "n-2", time.Now()))
+	assert.ErrorIs(t, err, ErrInvalidSignature, "keys without a signing secret cannot sign")
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"case-itau/repositories"
+	"case-itau/utils/requestctx"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionDeposit  = "deposit"
+	ActionWithdraw = "withdraw"
+)
+
+// FieldChange holds the value of a single field before and after a mutation.
+// Before is nil for creations and After is nil for deletions.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type Service struct {
+	repo repositories.IRepository[repositories.AuditEntry]
+}
+
+func NewService(repo repositories.IRepository[repositories.AuditEntry]) *Service {
+	return &Service{repo: repo}
+}
+
+// Record appends an entry describing the transition of a customer from before
+// to after, tagged with the actor, request ID and client IP found in ctx.
+// Pass nil as before for creations and nil as after for deletions.
+func (s *Service) Record(ctx context.Context, action string, before, after *repositories.Customers) error {
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+
+	changes, err := json.Marshal(Diff(before, after))
+	if err != nil {
+		return err
+	}
+
+	meta := requestctx.FromContext(ctx)
+	entry := &repositories.AuditEntry{
+		ID:         uuid.New(),
+		CustomerID: subject.ID,
+		Action:     action,
+		Actor:      meta.Actor,
+		RequestID:  meta.RequestID,
+		ClientIP:   meta.ClientIP,
+		Changes:    string(changes),
+	}
+	return s.repo.InsertOne(ctx, entry)
+}
+
+// List returns a page of the audit trail of a customer, newest first, together
+// with the total number of entries.
+func (s *Service) List(ctx context.Context, customerID string, page, size int) ([]repositories.AuditEntry, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 10
+	}
+
+	offset := (page - 1) * size
+
+	total, err := s.repo.Count(ctx, map[string]any{"customer_id": customerID})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, err := s.repo.Find(
+		ctx,
+		map[string]any{"customer_id": customerID},
+		"created_at DESC",
+		size,
+		offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// Diff returns the audited fields whose values differ between before and after.
+func Diff(before, after *repositories.Customers) map[string]FieldChange {
+	b, a := fields(before), fields(after)
+
+	changes := make(map[string]FieldChange)
+	for name := range b {
+		if b[name] != a[name] {
+			changes[name] = FieldChange{Before: b[name], After: a[name]}
+		}
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			changes[name] = FieldChange{After: a[name]}
+		}
+	}
+	return changes
+}
+
+func fields(c *repositories.Customers) map[string]any {
+	if c == nil {
+		return map[string]any{}
+	}
+	return map[string]any{
+		"name":    c.Name,
+		"email":   c.Email,
+		"balance": c.Balance.String(),
+	}
+}
@@ -0,0 +1,64 @@
+//go:build unit
+
+package audit
+
+import (
+	"testing"
+
+	"case-itau/repositories"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCases_Diff_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success diffing an update keeps only changed fields", testDiffUpdate},
+		{"Success diffing a creation has no before values", testDiffCreate},
+		{"Success diffing a deletion has no after values", testDiffDelete},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func newCustomer() *repositories.Customers {
+	return &repositories.Customers{ID: uuid.New(), Name: "John Doe", Email: "john.doe@example.com", Balance: decimal.NewFromInt(10)}
+}
+
+func testDiffUpdate(t *testing.T) {
+	t.Log("testDiffUpdate - Testing that only the e-mail shows up when it is the only changed field")
+	before := newCustomer()
+	after := *before
+	after.Email = "john@example.com"
+
+	changes := Diff(before, &after)
+	assert.Equal(t, map[string]FieldChange{
+		"email": {Before: "john.doe@example.com", After: "john@example.com"},
+	}, changes)
+}
+
+func testDiffCreate(t *testing.T) {
+	t.Log("testDiffCreate - Testing that every field is reported with a nil before value")
+	changes := Diff(nil, newCustomer())
+	assert.Len(t, changes, 3)
+	for _, change := range changes {
+		assert.Nil(t, change.Before)
+		assert.NotNil(t, change.After)
+	}
+}
+
+func testDiffDelete(t *testing.T) {
+	t.Log("testDiffDelete - Testing that every field is reported with a nil after value")
+	changes := Diff(newCustomer(), nil)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, FieldChange{Before: "10", After: nil}, changes["balance"])
+}
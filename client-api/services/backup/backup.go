@@ -0,0 +1,329 @@
+// Package backup copies a live SQLite database with VACUUM INTO, which reads
+// it in a single transaction and so yields a consistent snapshot while the
+// API keeps serving, and restores such copies after checking their integrity.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"case-itau/repositories/connection"
+
+	"gorm.io/gorm"
+)
+
+const (
+	prefix     = "backup-"
+	extension  = ".db"
+	gzipSuffix = ".gz"
+	// stampLayout sorts lexically in time order, which retention relies on.
+	stampLayout = "20060102T150405.000Z"
+)
+
+var ErrBackupInProgress = errors.New("backup already in progress")
+
+// Config says where backups go, whether they are gzipped and how many of the
+// newest ones are kept. Keep zero keeps every backup.
+type Config struct {
+	Dir      string
+	Compress bool
+	Keep     int
+}
+
+// File is a backup on disk.
+type File struct {
+	Path       string
+	Size       int64
+	Compressed bool
+	CreatedAt  time.Time
+}
+
+// Result is a backup just taken and the older ones retention removed.
+type Result struct {
+	File
+	Removed []string
+}
+
+type Service struct {
+	db  *gorm.DB
+	cfg Config
+	mu  sync.Mutex
+}
+
+// NewService backs up db, which must be a SQLite database.
+func NewService(db *gorm.DB, cfg Config) *Service {
+	return &Service{db: db, cfg: cfg}
+}
+
+// Backup writes a snapshot of the database to a new timestamped file in the
+// backup directory and then applies retention. Only one backup runs at a time;
+// a concurrent call fails with ErrBackupInProgress.
+func (s *Service) Backup(ctx context.Context) (*Result, error) {
+	if !s.mu.TryLock() {
+		return nil, ErrBackupInProgress
+	}
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.cfg.Dir, 0o750); err != nil {
+		return nil, err
+	}
+	createdAt := time.Now().UTC()
+	path := filepath.Join(s.cfg.Dir, prefix+createdAt.Format(stampLayout)+extension)
+	if s.cfg.Compress {
+		path += gzipSuffix
+	}
+
+	// VACUUM INTO refuses to overwrite, and an interrupted run leaves the
+	// temporary file behind, so clear it first.
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	defer os.Remove(tmp)
+	if err := s.db.WithContext(ctx).Exec("VACUUM INTO ?", tmp).Error; err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	if s.cfg.Compress {
+		if err := compress(tmp, path); err != nil {
+			return nil, err
+		}
+	} else if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := s.prune()
+	if err != nil {
+		return nil, fmt.Errorf("backup written to %s, but retention failed: %w", path, err)
+	}
+	return &Result{
+		File:    File{Path: path, Size: info.Size(), Compressed: s.cfg.Compress, CreatedAt: createdAt},
+		Removed: removed,
+	}, nil
+}
+
+// List returns the backups in the backup directory, newest first.
+func (s *Service) List() ([]File, error) {
+	return list(s.cfg.Dir)
+}
+
+// prune removes the oldest backups beyond cfg.Keep.
+func (s *Service) prune() ([]string, error) {
+	if s.cfg.Keep <= 0 {
+		return nil, nil
+	}
+	files, err := list(s.cfg.Dir)
+	if err != nil || len(files) <= s.cfg.Keep {
+		return nil, err
+	}
+	var removed []string
+	for _, f := range files[s.cfg.Keep:] {
+		if err := os.Remove(f.Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, f.Path)
+	}
+	return removed, nil
+}
+
+func list(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, e := range entries {
+		name := e.Name()
+		stamp, compressed, ok := parseName(name)
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Path: filepath.Join(dir, name), Size: info.Size(), Compressed: compressed, CreatedAt: stamp})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.After(files[j].CreatedAt) })
+	return files, nil
+}
+
+// parseName recognises the files written by Backup.
+func parseName(name string) (time.Time, bool, bool) {
+	compressed := strings.HasSuffix(name, gzipSuffix)
+	stem, ok := strings.CutPrefix(strings.TrimSuffix(name, gzipSuffix), prefix)
+	if !ok {
+		return time.Time{}, false, false
+	}
+	stem, ok = strings.CutSuffix(stem, extension)
+	if !ok {
+		return time.Time{}, false, false
+	}
+	stamp, err := time.Parse(stampLayout, stem)
+	if err != nil {
+		return time.Time{}, false, false
+	}
+	return stamp, compressed, true
+}
+
+func compress(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// Restore replaces the database at dbPath with the backup at from, gzipped or
+// not, once the copy passes SQLite's integrity and foreign key checks. The
+// database it replaces, with its journal files, is kept next to it under a
+// .pre-restore-<time> suffix, which is returned. The API must not be running
+// against dbPath while it restores.
+func Restore(ctx context.Context, from, dbPath string) (string, error) {
+	candidate := dbPath + ".restore"
+	os.Remove(candidate)
+	defer os.Remove(candidate)
+
+	if err := extract(from, candidate); err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	if err := Verify(ctx, candidate); err != nil {
+		return "", fmt.Errorf("backup failed verification: %w", err)
+	}
+
+	var kept string
+	if _, err := os.Stat(dbPath); err == nil {
+		kept = dbPath + ".pre-restore-" + time.Now().UTC().Format(stampLayout)
+		for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+			err := os.Rename(dbPath+suffix, kept+suffix)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("failed to set the current database aside: %w", err)
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	if err := os.Rename(candidate, dbPath); err != nil {
+		return kept, fmt.Errorf("failed to swap the backup in: %w", err)
+	}
+	return kept, syncDir(filepath.Dir(dbPath))
+}
+
+// Verify checks that the file at path is a database of this API that passes
+// SQLite's integrity and foreign key checks, failing with what they report.
+func Verify(ctx context.Context, path string) error {
+	db, err := connection.NewSqliteConnection("file:" + path + "?mode=ro")
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var result string
+	if err := db.Raw("PRAGMA integrity_check(1)").Scan(&result).Error; err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+
+	var violations []struct {
+		Table  string
+		Rowid  int64
+		Parent string
+	}
+	if err := db.Raw("PRAGMA foreign_key_check").Scan(&violations).Error; err != nil {
+		return fmt.Errorf("foreign key check failed: %w", err)
+	}
+	if len(violations) > 0 {
+		v := violations[0]
+		return fmt.Errorf("foreign key check failed: %d rows reference missing parents, e.g. row %d of %s references %s", len(violations), v.Rowid, v.Table, v.Parent)
+	}
+
+	if !db.Migrator().HasTable("schema_migrations") {
+		return errors.New("not a database of this API: it has no schema_migrations table")
+	}
+	return nil
+}
+
+// extract copies the backup at src to dst, decompressing it when gzipped.
+func extract(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if strings.HasSuffix(src, gzipSuffix) {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
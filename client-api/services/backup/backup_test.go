@@ -0,0 +1,182 @@
+//go:build unit
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"case-itau/repositories/connection"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCases_Backup_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success taking a consistent backup under concurrent writes", testBackupUnderWrites},
+		{"Success compressing and pruning old backups", testCompressAndRetention},
+		{"Success restoring a backup and keeping the replaced database", testRestore},
+		{"Failure restoring a corrupt or foreign file", testRestoreRejected},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+// newDB returns a SQLite database at path with a schema_migrations table, like
+// the ones Restore accepts, and a table of numbered rows.
+func newDB(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	db, err := connection.NewSqliteConnection(path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY)").Error)
+	require.NoError(t, db.Exec("CREATE TABLE IF NOT EXISTS items (id INTEGER PRIMARY KEY, name TEXT NOT NULL)").Error)
+	return db
+}
+
+func countItems(t *testing.T, path string) int64 {
+	t.Helper()
+	db, err := connection.NewSqliteConnection(path)
+	require.NoError(t, err)
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+	var n int64
+	require.NoError(t, db.Table("items").Count(&n).Error)
+	return n
+}
+
+func testBackupUnderWrites(t *testing.T) {
+	t.Log("testBackupUnderWrites - Testing a success clause for a backup taken while rows are being inserted")
+	dir := t.TempDir()
+	db := newDB(t, filepath.Join(dir, "live.db"))
+	svc := NewService(db, Config{Dir: filepath.Join(dir, "backups")})
+
+	const rows = 200
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range rows {
+			assert.NoError(t, db.Exec("INSERT INTO items (name) VALUES (?)", fmt.Sprint("item ", i)).Error)
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	result, err := svc.Backup(context.Background())
+	wg.Wait()
+	require.NoError(t, err)
+
+	assert.FileExists(t, result.Path)
+	assert.Positive(t, result.Size)
+	require.NoError(t, Verify(context.Background(), result.Path))
+	n := countItems(t, result.Path)
+	assert.LessOrEqual(t, n, int64(rows), "the backup holds a prefix of the inserts")
+
+	files, err := svc.List()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, result.Path, files[0].Path)
+}
+
+func testCompressAndRetention(t *testing.T) {
+	t.Log("testCompressAndRetention - Testing a success clause for gzipped backups of which only the newest are kept")
+	dir := t.TempDir()
+	db := newDB(t, filepath.Join(dir, "live.db"))
+	backups := filepath.Join(dir, "backups")
+	svc := NewService(db, Config{Dir: backups, Compress: true, Keep: 2})
+	require.NoError(t, os.MkdirAll(backups, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(backups, "notes.txt"), []byte("kept"), 0o600))
+
+	var taken []string
+	for range 3 {
+		result, err := svc.Backup(context.Background())
+		require.NoError(t, err)
+		assert.True(t, result.Compressed)
+		assert.Equal(t, ".gz", filepath.Ext(result.Path))
+		taken = append(taken, result.Path)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	files, err := svc.List()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, taken[2], files[0].Path, "newest first")
+	assert.Equal(t, taken[1], files[1].Path)
+	assert.NoFileExists(t, taken[0])
+	assert.FileExists(t, filepath.Join(backups, "notes.txt"), "files that are not backups are left alone")
+}
+
+func testRestore(t *testing.T) {
+	t.Log("testRestore - Testing a success clause for a gzipped backup swapped in place of a newer database")
+	dir := t.TempDir()
+	livePath := filepath.Join(dir, "live.db")
+	db := newDB(t, livePath)
+	require.NoError(t, db.Exec("INSERT INTO items (name) VALUES ('before')").Error)
+
+	result, err := NewService(db, Config{Dir: filepath.Join(dir, "backups"), Compress: true}).Backup(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("INSERT INTO items (name) VALUES ('after')").Error)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	kept, err := Restore(context.Background(), result.Path, livePath)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), countItems(t, livePath))
+	require.NotEmpty(t, kept)
+	assert.Equal(t, int64(2), countItems(t, kept), "the replaced database is kept")
+	assert.NoFileExists(t, livePath+".restore")
+}
+
+func testRestoreRejected(t *testing.T) {
+	t.Log("testRestoreRejected - Testing a failure clause for files that must not replace the database")
+	dir := t.TempDir()
+	livePath := filepath.Join(dir, "live.db")
+	newDB(t, livePath)
+
+	corrupt := filepath.Join(dir, "corrupt.db")
+	require.NoError(t, os.WriteFile(corrupt, []byte("not a database"), 0o600))
+	_, err := Restore(context.Background(), corrupt, livePath)
+	assert.ErrorContains(t, err, "backup failed verification")
+
+	foreign := filepath.Join(dir, "foreign.db")
+	other, err := connection.NewSqliteConnection(foreign)
+	require.NoError(t, err)
+	require.NoError(t, other.Exec("CREATE TABLE things (id INTEGER)").Error)
+	sqlDB, err := other.DB()
+	require.NoError(t, err)
+	sqlDB.Close()
+	_, err = Restore(context.Background(), foreign, livePath)
+	assert.ErrorContains(t, err, "no schema_migrations table")
+
+	_, err = Restore(context.Background(), filepath.Join(dir, "missing.db"), livePath)
+	assert.ErrorContains(t, err, "failed to read backup")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), "pre-restore", "a rejected backup leaves the database in place")
+	}
+}
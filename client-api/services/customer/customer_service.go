@@ -3,34 +3,55 @@ package customer
 import (
 	"context"
 	"errors"
-	"strings"
 
 	"case-itau/repositories"
+	"case-itau/services/audit"
+	"case-itau/services/events"
+	l "case-itau/utils/logger"
+	"case-itau/utils/metrics"
+	"case-itau/utils/tracing"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 var (
-	ErrNotFound          = errors.New("cliente não encontrado")
-	ErrInsufficientFunds = errors.New("saldo insuficiente")
-	ErrUniqueEmail       = errors.New("UNIQUE constraint failed: customers.email")
+	ErrNotFound           = errors.New("cliente não encontrado")
+	ErrInsufficientFunds  = errors.New("saldo insuficiente")
+	ErrUniqueEmail        = errors.New("e-mail já cadastrado")
+	ErrVersionMismatch    = errors.New("versão do cliente desatualizada")
+	ErrDuplicateReference = errors.New("referência de transação já utilizada")
 )
 
+// maxWriteAttempts bounds how many times an unconditional write is retried
+// after losing a race against a concurrent update of the same customer.
+const maxWriteAttempts = 3
+
 type Service struct {
+	tx        repositories.Transactor
 	repoCli   repositories.IRepository[repositories.Customers]
 	repoTrans repositories.IRepository[repositories.Transaction]
+	audit     *audit.Service
+	outbox    *events.Outbox
 }
 
-func NewService(repoCli repositories.IRepository[repositories.Customers], repoTrans repositories.IRepository[repositories.Transaction]) *Service {
-	return &Service{repoCli: repoCli, repoTrans: repoTrans}
+func NewService(tx repositories.Transactor, repoCli repositories.IRepository[repositories.Customers], repoTrans repositories.IRepository[repositories.Transaction], auditSvc *audit.Service, outbox *events.Outbox) *Service {
+	return &Service{tx: tx, repoCli: repoCli, repoTrans: repoTrans, audit: auditSvc, outbox: outbox}
 }
 
-func (s *Service) ListAll(ctx context.Context) ([]repositories.Customers, error) {
+func (s *Service) ListAll(ctx context.Context) (_ []repositories.Customers, err error) {
+	ctx, span := tracing.Start(ctx, "customer.ListAll")
+	defer tracing.End(span, &err)
+
 	return s.repoCli.Find(ctx, nil, "", 0, 0)
 }
 
-func (s *Service) GetByID(ctx context.Context, id string) (*repositories.Customers, error) {
+func (s *Service) GetByID(ctx context.Context, id string) (_ *repositories.Customers, err error) {
+	ctx, span := tracing.Start(ctx, "customer.GetByID", attribute.String("customer.id", id))
+	defer tracing.End(span, &err)
+
 	c, err := s.repoCli.FindOne(ctx, map[string]any{"id": id})
 	if err != nil {
 		if errors.Is(err, repositories.ErrRepoNotFound) {
@@ -41,11 +62,48 @@ func (s *Service) GetByID(ctx context.Context, id string) (*repositories.Custome
 	return c, nil
 }
 
-func (s *Service) Create(ctx context.Context, input repositories.Customers) (repositories.Customers, error) {
+// Resolve finds a customer by ID or, when ref is not a UUID, by e-mail.
+func (s *Service) Resolve(ctx context.Context, ref string) (_ *repositories.Customers, err error) {
+	ctx, span := tracing.Start(ctx, "customer.Resolve")
+	defer tracing.End(span, &err)
+
+	if _, err := uuid.Parse(ref); err == nil {
+		return s.GetByID(ctx, ref)
+	}
+
+	c, err := s.repoCli.FindOne(ctx, map[string]any{"email": ref})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) Create(ctx context.Context, input repositories.Customers) (_ repositories.Customers, err error) {
+	ctx, span := tracing.Start(ctx, "customer.Create")
+	defer tracing.End(span, &err)
+
 	input.Balance = decimal.Zero
+	input.Version = 1
 
-	if err := s.repoCli.InsertOne(ctx, &input); err != nil {
-		if strings.Contains(err.Error(), ErrUniqueEmail.Error()) {
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repoCli.InsertOne(ctx, &input); err != nil {
+			return err
+		}
+		span.SetAttributes(attribute.String("customer.id", input.ID.String()))
+		if err := s.audit.Record(ctx, audit.ActionCreate, nil, &input); err != nil {
+			return err
+		}
+		repositories.AfterCommit(ctx, func() {
+			metrics.CustomersCreated.Inc()
+			l.FromContext(ctx).Info("customer created", zap.String("customer_id", input.ID.String()))
+		})
+		return s.outbox.Enqueue(ctx, events.TypeCustomerCreated, input.ID, events.NewCustomerPayload(&input))
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoDuplicate) {
 			return repositories.Customers{}, ErrUniqueEmail
 		}
 		return repositories.Customers{}, err
@@ -53,91 +111,219 @@ func (s *Service) Create(ctx context.Context, input repositories.Customers) (rep
 	return input, nil
 }
 
-func (s *Service) Update(ctx context.Context, id string, input repositories.Customers) (*repositories.Customers, error) {
-	update := make(map[string]any)
-	if input.Name != "" {
-		update["name"] = input.Name
-	}
+// Update changes the name and/or e-mail of a customer. A non-zero version makes
+// the write conditional on the customer still being at that version.
+func (s *Service) Update(ctx context.Context, id string, version int64, input repositories.Customers) (_ *repositories.Customers, err error) {
+	ctx, span := tracing.Start(ctx, "customer.Update", attribute.String("customer.id", id))
+	defer tracing.End(span, &err)
 
-	if input.Email != "" {
-		update["email"] = input.Email
-	}
+	var updated *repositories.Customers
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, after, err := s.mutate(ctx, id, version, func(_ *repositories.Customers) (map[string]any, error) {
+			update := make(map[string]any)
+			if input.Name != "" {
+				update["name"] = input.Name
+			}
 
-	if err := s.repoCli.UpdateOne(ctx, map[string]any{"id": id}, update); err != nil {
-		if errors.Is(err, repositories.ErrRepoNotFound) {
-			return nil, err
+			if input.Email != "" {
+				update["email"] = input.Email
+			}
+			return update, nil
+		})
+		if err != nil {
+			return err
 		}
-		if strings.Contains(err.Error(), ErrUniqueEmail.Error()) {
-			return nil, ErrUniqueEmail
+		updated = after
+		if err := s.audit.Record(ctx, audit.ActionUpdate, before, after); err != nil {
+			return err
 		}
-		return nil, err
-	}
-
-	updated, err := s.repoCli.FindOne(ctx, map[string]any{"id": id})
+		repositories.AfterCommit(ctx, func() {
+			l.FromContext(ctx).Info("customer updated",
+				zap.String("customer_id", after.ID.String()),
+				zap.Int64("version", after.Version),
+			)
+		})
+		return s.outbox.Enqueue(ctx, events.TypeCustomerUpdated, after.ID, events.NewCustomerPayload(after))
+	})
 	if err != nil {
+		if errors.Is(err, repositories.ErrRepoDuplicate) {
+			return nil, ErrUniqueEmail
+		}
 		return nil, err
 	}
 	return updated, nil
 }
 
-func (s *Service) Delete(ctx context.Context, id string) error {
-	if err := s.repoCli.DeleteOne(ctx, map[string]any{"id": id}); err != nil {
-		if errors.Is(err, repositories.ErrRepoNotFound) {
-			return ErrNotFound
-		}
-		return err
-	}
-	return nil
-}
+// Delete removes a customer. A non-zero version makes the delete conditional
+// on the customer still being at that version.
+func (s *Service) Delete(ctx context.Context, id string, version int64) (err error) {
+	ctx, span := tracing.Start(ctx, "customer.Delete", attribute.String("customer.id", id))
+	defer tracing.End(span, &err)
 
-func (s *Service) Transactions(ctx context.Context, id string, delta decimal.Decimal) (*repositories.Customers, error) {
-	c, err := s.repoCli.FindOne(ctx, map[string]any{"id": id})
-	if err != nil {
-		if errors.Is(err, repositories.ErrRepoNotFound) {
-			return nil, ErrNotFound
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		c, err := s.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && c.Version != version {
+			return ErrVersionMismatch
 		}
-		return nil, err
-	}
-
-	newBalance := c.Balance.Add(delta)
-	if newBalance.IsNegative() {
-		return nil, ErrInsufficientFunds
-	}
-
-	updates := map[string]any{
-		"balance": newBalance,
-	}
 
-	err = s.repoCli.UpdateOne(ctx, map[string]any{"id": c.ID.String()}, updates)
-	if err != nil {
-		return nil, err
-	}
+		if err := s.repoCli.DeleteOne(ctx, map[string]any{"id": c.ID.String(), "version": c.Version}); err != nil {
+			if errors.Is(err, repositories.ErrRepoNotFound) {
+				return ErrVersionMismatch
+			}
+			return err
+		}
+		if err := s.audit.Record(ctx, audit.ActionDelete, c, nil); err != nil {
+			return err
+		}
+		repositories.AfterCommit(ctx, func() {
+			l.FromContext(ctx).Info("customer deleted", zap.String("customer_id", c.ID.String()))
+		})
+		return s.outbox.Enqueue(ctx, events.TypeCustomerDeleted, c.ID, events.NewCustomerPayload(c))
+	})
+}
 
-	updated, err := s.repoCli.FindOne(ctx, map[string]any{"id": c.ID.String()})
-	if err != nil {
-		return nil, err
-	}
+// Transactions applies delta to the customer's balance and books the matching
+// transaction. A non-zero version makes the operation conditional on the
+// customer still being at that version.
+func (s *Service) Transactions(ctx context.Context, id string, version int64, delta decimal.Decimal) (*repositories.Customers, error) {
+	return s.TransactionsWithReference(ctx, id, version, delta, "")
+}
 
+// TransactionsWithReference is Transactions for a transaction that carries the
+// reference it has in another system. A reference already booked is rejected
+// with ErrDuplicateReference; an empty one is not recorded.
+func (s *Service) TransactionsWithReference(ctx context.Context, id string, version int64, delta decimal.Decimal, reference string) (_ *repositories.Customers, err error) {
 	transactionType := "withdraw"
 	if delta.IsPositive() {
 		transactionType = "deposit"
 	}
+	ctx, span := tracing.Start(ctx, "customer.Transactions",
+		attribute.String("customer.id", id),
+		attribute.String("transaction.type", transactionType),
+	)
+	defer tracing.End(span, &err)
 
-	t := &repositories.Transaction{
-		TransactionID: uuid.New(),
-		CustomerID:    c.ID,
-		Amount:        delta,
-		Type:          transactionType,
-	}
+	var updated *repositories.Customers
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, after, err := s.mutate(ctx, id, version, func(c *repositories.Customers) (map[string]any, error) {
+			newBalance := c.Balance.Add(delta)
+			if newBalance.IsNegative() {
+				return nil, ErrInsufficientFunds
+			}
+			return map[string]any{"balance": newBalance}, nil
+		})
+		if err != nil {
+			return err
+		}
+		updated = after
+
+		action := audit.ActionWithdraw
+		eventType := events.TypeWithdrawalBooked
+		if delta.IsPositive() {
+			action = audit.ActionDeposit
+			eventType = events.TypeDepositBooked
+		}
 
-	if err := s.repoTrans.InsertOne(ctx, t); err != nil {
+		t := &repositories.Transaction{
+			TransactionID: uuid.New(),
+			CustomerID:    after.ID,
+			Amount:        delta,
+			Type:          transactionType,
+		}
+		if reference != "" {
+			t.Reference = &reference
+		}
+
+		if err := s.repoTrans.InsertOne(ctx, t); err != nil {
+			if errors.Is(err, repositories.ErrRepoDuplicate) {
+				return ErrDuplicateReference
+			}
+			return err
+		}
+		if err := s.audit.Record(ctx, action, before, after); err != nil {
+			return err
+		}
+		repositories.AfterCommit(ctx, func() {
+			metrics.RecordTransaction(transactionType, delta)
+			l.FromContext(ctx).Info("transaction booked",
+				zap.String("customer_id", after.ID.String()),
+				zap.String("transaction_id", t.TransactionID.String()),
+				zap.String("type", transactionType),
+				zap.String("amount", delta.String()),
+			)
+		})
+		return s.outbox.Enqueue(ctx, eventType, after.ID, events.TransactionPayload{
+			TransactionID: t.TransactionID,
+			CustomerID:    after.ID,
+			Amount:        t.Amount,
+			Balance:       after.Balance,
+			Version:       after.Version,
+			CreatedAt:     t.CreatedAt,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientFunds) {
+			metrics.InsufficientFunds.Inc()
+			l.FromContext(ctx).Info("withdrawal rejected for insufficient funds",
+				zap.String("customer_id", id),
+				zap.String("amount", delta.Neg().String()),
+			)
+		}
 		return nil, err
 	}
 
 	return updated, nil
 }
 
-func (s *Service) ListTransactions(ctx context.Context, customerID string, page, size int) ([]repositories.Transaction, int64, error) {
+// mutate loads the customer, asks apply for the columns to change and writes
+// them together with a version bump, guarded by the version that was read.
+// It returns the customer as read and as written. When version is zero a write
+// that loses a race is retried; otherwise a stale or lost version is reported
+// as ErrVersionMismatch.
+func (s *Service) mutate(ctx context.Context, id string, version int64, apply func(c *repositories.Customers) (map[string]any, error)) (*repositories.Customers, *repositories.Customers, error) {
+	for attempt := 1; ; attempt++ {
+		c, err := s.GetByID(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if version != 0 && c.Version != version {
+			return nil, nil, ErrVersionMismatch
+		}
+
+		updates, err := apply(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		updates["version"] = c.Version + 1
+
+		err = s.repoCli.UpdateOne(ctx, map[string]any{"id": c.ID.String(), "version": c.Version}, updates)
+		if err == nil {
+			updated, err := s.GetByID(ctx, id)
+			if err != nil {
+				return nil, nil, err
+			}
+			return c, updated, nil
+		}
+		if !errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, nil, err
+		}
+		if version != 0 || attempt >= maxWriteAttempts {
+			return nil, nil, ErrVersionMismatch
+		}
+		l.FromContext(ctx).Debug("customer changed concurrently, retrying write",
+			zap.String("customer_id", id),
+			zap.Int("attempt", attempt),
+		)
+	}
+}
+
+func (s *Service) ListTransactions(ctx context.Context, customerID string, page, size int) (_ []repositories.Transaction, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "customer.ListTransactions", attribute.String("customer.id", customerID))
+	defer tracing.End(span, &err)
+
 	if page < 1 {
 		page = 1
 	}
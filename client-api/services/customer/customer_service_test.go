@@ -0,0 +1,160 @@
+//go:build unit
+
+package customer_test
+
+import (
+	"context"
+	"testing"
+
+	"case-itau/repositories"
+	"case-itau/services/audit"
+	"case-itau/services/customer"
+	"case-itau/services/customer/customertest"
+	"case-itau/services/events"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// racingRepository bumps the version of the customer before each of the
+// service's next races writes, as a concurrent request committing first would.
+type racingRepository struct {
+	repositories.IRepository[repositories.Customers]
+	races   int
+	updates int
+}
+
+func (r *racingRepository) UpdateOne(ctx context.Context, where any, updates map[string]any) error {
+	r.updates++
+	if r.races > 0 {
+		r.races--
+		id := where.(map[string]any)["id"]
+		err := r.IRepository.UpdateOne(ctx, map[string]any{"id": id}, map[string]any{"version": repositories.Expr("version + 1")})
+		if err != nil {
+			return err
+		}
+	}
+	return r.IRepository.UpdateOne(ctx, where, updates)
+}
+
+type fixture struct {
+	svc   *customer.Service
+	repo  *racingRepository
+	id    string
+	store func(t *testing.T) repositories.Customers
+}
+
+func newFixture(t *testing.T) *fixture {
+	cf := customertest.New(t)
+	cust := cf.Create(t, "John Doe", "john.doe@example.com")
+	repo := &racingRepository{IRepository: repositories.NewGormRepository[repositories.Customers](cf.DB)}
+	svc := customer.NewService(cf.Tx, repo,
+		repositories.NewGormRepository[repositories.Transaction](cf.DB),
+		audit.NewService(repositories.NewGormRepository[repositories.AuditEntry](cf.DB)),
+		events.NewOutbox(repositories.NewGormRepository[repositories.OutboxEvent](cf.DB)),
+	)
+	return &fixture{
+		svc:  svc,
+		repo: repo,
+		id:   cust.ID.String(),
+		store: func(t *testing.T) repositories.Customers {
+			var c repositories.Customers
+			require.NoError(t, cf.DB.First(&c, "id = ?", cust.ID.String()).Error)
+			return c
+		},
+	}
+}
+
+func TestCases_Customer_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success bumping the version on every write", testVersionBump},
+		{"Failure writing with a stale version", testStaleVersion},
+		{"Success retrying an unconditional write that lost a race", testConflictRetry},
+		{"Failure exhausting the retries of an unconditional write", testConflictExhausted},
+		{"Failure retrying a conditional write that lost a race", testConditionalConflict},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testVersionBump(t *testing.T) {
+	t.Log("testVersionBump - Testing a success clause for updates, transactions and deletes each moving the version on by one")
+	f := newFixture(t)
+	ctx := context.Background()
+
+	updated, err := f.svc.Update(ctx, f.id, 1, repositories.Customers{Name: "John Smith"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+	assert.Equal(t, "John Smith", updated.Name)
+
+	updated, err = f.svc.Transactions(ctx, f.id, 2, decimal.NewFromInt(10))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), updated.Version)
+	assert.Equal(t, int64(3), f.store(t).Version)
+
+	require.NoError(t, f.svc.Delete(ctx, f.id, 3))
+	_, err = f.svc.GetByID(ctx, f.id)
+	assert.ErrorIs(t, err, customer.ErrNotFound)
+}
+
+func testStaleVersion(t *testing.T) {
+	t.Log("testStaleVersion - Testing a failure clause for writes conditioned on a version the customer moved past")
+	f := newFixture(t)
+	ctx := context.Background()
+	_, err := f.svc.Update(ctx, f.id, 0, repositories.Customers{Name: "John Smith"})
+	require.NoError(t, err)
+
+	_, err = f.svc.Update(ctx, f.id, 1, repositories.Customers{Name: "Johnny"})
+	assert.ErrorIs(t, err, customer.ErrVersionMismatch)
+	_, err = f.svc.Transactions(ctx, f.id, 1, decimal.NewFromInt(10))
+	assert.ErrorIs(t, err, customer.ErrVersionMismatch)
+	assert.ErrorIs(t, f.svc.Delete(ctx, f.id, 1), customer.ErrVersionMismatch)
+
+	stored := f.store(t)
+	assert.Equal(t, int64(2), stored.Version)
+	assert.Equal(t, "John Smith", stored.Name)
+	assert.True(t, stored.Balance.IsZero())
+}
+
+func testConflictRetry(t *testing.T) {
+	t.Log("testConflictRetry - Testing a success clause for an unconditional write applied over the version that won the race")
+	f := newFixture(t)
+	f.repo.races = 2
+
+	updated, err := f.svc.Transactions(context.Background(), f.id, 0, decimal.NewFromInt(10))
+	require.NoError(t, err)
+	assert.Equal(t, 3, f.repo.updates, "two lost races and the write that landed")
+	assert.Equal(t, int64(4), updated.Version, "two concurrent bumps and this one")
+	assert.True(t, decimal.NewFromInt(10).Equal(f.store(t).Balance))
+}
+
+func testConflictExhausted(t *testing.T) {
+	t.Log("testConflictExhausted - Testing a failure clause for an unconditional write losing every attempt")
+	f := newFixture(t)
+	f.repo.races = 10
+
+	_, err := f.svc.Transactions(context.Background(), f.id, 0, decimal.NewFromInt(10))
+	assert.ErrorIs(t, err, customer.ErrVersionMismatch)
+	assert.Equal(t, 3, f.repo.updates)
+	assert.True(t, f.store(t).Balance.IsZero())
+}
+
+func testConditionalConflict(t *testing.T) {
+	t.Log("testConditionalConflict - Testing a failure clause for a write conditioned on the version it lost to")
+	f := newFixture(t)
+	f.repo.races = 1
+
+	_, err := f.svc.Update(context.Background(), f.id, 1, repositories.Customers{Name: "John Smith"})
+	assert.ErrorIs(t, err, customer.ErrVersionMismatch)
+	assert.Equal(t, 1, f.repo.updates, "conditional writes are not retried")
+	assert.Equal(t, "John Doe", f.store(t).Name)
+}
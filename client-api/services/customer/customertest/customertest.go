@@ -0,0 +1,67 @@
+// Package customertest builds the customer service over a throwaway database,
+// for the tests of the services that depend on it.
+package customertest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+	"case-itau/repositories/migrations"
+	"case-itau/services/audit"
+	"case-itau/services/customer"
+	"case-itau/services/events"
+	l "case-itau/utils/logger"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Fixture is a migrated SQLite database and a customer service over it.
+type Fixture struct {
+	DB        *gorm.DB
+	Tx        repositories.Transactor
+	Customers *customer.Service
+}
+
+// New migrates a database in a temporary directory of t, which is closed when
+// the test ends, and builds the customer service over it. The global logger
+// is silenced.
+func New(t *testing.T) *Fixture {
+	t.Helper()
+	l.Logger = zap.NewNop()
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "customers.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	migrator, err := migrations.New(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	tx := repositories.NewTransactor(db)
+	return &Fixture{
+		DB: db,
+		Tx: tx,
+		Customers: customer.NewService(tx,
+			repositories.NewGormRepository[repositories.Customers](db),
+			repositories.NewGormRepository[repositories.Transaction](db),
+			audit.NewService(repositories.NewGormRepository[repositories.AuditEntry](db)),
+			events.NewOutbox(repositories.NewGormRepository[repositories.OutboxEvent](db)),
+		),
+	}
+}
+
+// Create registers a customer.
+func (f *Fixture) Create(t *testing.T, name, email string) repositories.Customers {
+	t.Helper()
+	c, err := f.Customers.Create(context.Background(), repositories.Customers{Name: name, Email: email})
+	require.NoError(t, err)
+	return c
+}
@@ -0,0 +1,138 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"case-itau/repositories"
+	l "case-itau/utils/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	dispatchBatchSize = 100
+	maxRetryBackoff   = time.Minute
+)
+
+// Dispatcher delivers pending outbox events to a Publisher in ID order. An
+// event that fails is retried with exponential backoff and holds back the ones
+// after it; once it exhausts maxAttempts it is marked as failed and skipped.
+type Dispatcher struct {
+	repo        repositories.IRepository[repositories.OutboxEvent]
+	publisher   Publisher
+	interval    time.Duration
+	maxAttempts int
+	wake        chan struct{}
+}
+
+func NewDispatcher(repo repositories.IRepository[repositories.OutboxEvent], publisher Publisher, interval time.Duration, maxAttempts int) *Dispatcher {
+	return &Dispatcher{
+		repo:        repo,
+		publisher:   publisher,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes the dispatcher up without waiting for the next poll.
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// dispatch delivers due events until it runs out of them or reaches one that
+// is waiting for a retry.
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		pending, err := d.repo.Find(ctx, map[string]any{"delivered_at": nil, "failed_at": nil}, "id ASC", dispatchBatchSize, 0)
+		if err != nil {
+			l.Logger.Error("failed to load outbox events", zap.Error(err))
+			return
+		}
+
+		for i := range pending {
+			if pending[i].NextAttemptAt.After(time.Now()) {
+				return
+			}
+			if err := d.deliver(ctx, &pending[i]); err != nil {
+				return
+			}
+		}
+
+		if len(pending) < dispatchBatchSize {
+			return
+		}
+	}
+}
+
+// deliver publishes a single event and records the outcome. It returns an
+// error only when the event must be retried later.
+func (d *Dispatcher) deliver(ctx context.Context, row *repositories.OutboxEvent) error {
+	now := time.Now()
+	attempts := row.Attempts + 1
+
+	pubErr := d.publisher.Publish(ctx, FromRow(row))
+	if pubErr == nil {
+		return d.repo.UpdateOne(ctx, map[string]any{"id": row.ID}, map[string]any{
+			"attempts":     attempts,
+			"last_error":   "",
+			"delivered_at": now,
+		})
+	}
+
+	updates := map[string]any{
+		"attempts":   attempts,
+		"last_error": pubErr.Error(),
+	}
+	if attempts >= d.maxAttempts {
+		l.Logger.Error("giving up on outbox event",
+			zap.Uint64("event_id", row.ID), zap.String("type", row.Type), zap.Int("attempts", attempts), zap.Error(pubErr))
+		updates["failed_at"] = now
+	} else {
+		l.Logger.Warn("failed to publish outbox event",
+			zap.Uint64("event_id", row.ID), zap.String("type", row.Type), zap.Int("attempts", attempts), zap.Error(pubErr))
+		updates["next_attempt_at"] = now.Add(Backoff(attempts, maxRetryBackoff))
+	}
+
+	if err := d.repo.UpdateOne(ctx, map[string]any{"id": row.ID}, updates); err != nil {
+		return err
+	}
+	if attempts >= d.maxAttempts {
+		return nil
+	}
+	return pubErr
+}
+
+// Backoff returns the delay before retry number attempts: one second doubled
+// on every attempt and capped at limit.
+func Backoff(attempts int, limit time.Duration) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
@@ -0,0 +1,123 @@
+//go:build unit
+
+package events
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+	l "case-itau/utils/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recordingPublisher struct {
+	failures map[uint64]int
+	got      []uint64
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, e Event) error {
+	if p.failures[e.ID] > 0 {
+		p.failures[e.ID]--
+		return errors.New("subscriber unavailable")
+	}
+	p.got = append(p.got, e.ID)
+	return nil
+}
+
+func TestCases_Dispatcher_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success delivering events in commit order", testDispatchInOrder},
+		{"Success holding back later events while one is retried", testDispatchRetryKeepsOrder},
+		{"Success giving up on an event after max attempts", testDispatchGivesUp},
+		{"Success capping the retry backoff", testBackoff},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func newOutboxRepo(t *testing.T) repositories.IRepository[repositories.OutboxEvent] {
+	l.Logger = zap.NewNop()
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "outbox.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&repositories.OutboxEvent{}))
+	return repositories.NewGormRepository[repositories.OutboxEvent](db)
+}
+
+func enqueue(t *testing.T, outbox *Outbox, n int) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, outbox.Enqueue(context.Background(), TypeCustomerCreated, uuid.New(), map[string]int{"n": i}))
+	}
+}
+
+func testDispatchInOrder(t *testing.T) {
+	t.Log("testDispatchInOrder - Testing that pending events are published by ascending ID")
+	repo := newOutboxRepo(t)
+	enqueue(t, NewOutbox(repo), 3)
+
+	pub := &recordingPublisher{}
+	NewDispatcher(repo, pub, time.Second, 3).dispatch(context.Background())
+
+	assert.Equal(t, []uint64{1, 2, 3}, pub.got)
+	delivered, err := repo.Count(context.Background(), "delivered_at IS NOT NULL")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), delivered)
+}
+
+func testDispatchRetryKeepsOrder(t *testing.T) {
+	t.Log("testDispatchRetryKeepsOrder - Testing that a failing event blocks the ones enqueued after it")
+	repo := newOutboxRepo(t)
+	enqueue(t, NewOutbox(repo), 2)
+
+	pub := &recordingPublisher{failures: map[uint64]int{1: 1}}
+	d := NewDispatcher(repo, pub, time.Second, 3)
+	d.dispatch(context.Background())
+	assert.Empty(t, pub.got)
+
+	row, err := repo.FindOne(context.Background(), map[string]any{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, row.Attempts)
+	assert.True(t, row.NextAttemptAt.After(time.Now()))
+
+	require.NoError(t, repo.UpdateOne(context.Background(), map[string]any{"id": 1}, map[string]any{"next_attempt_at": time.Now()}))
+	d.dispatch(context.Background())
+	assert.Equal(t, []uint64{1, 2}, pub.got)
+}
+
+func testDispatchGivesUp(t *testing.T) {
+	t.Log("testDispatchGivesUp - Testing that an event is marked failed once it runs out of attempts")
+	repo := newOutboxRepo(t)
+	enqueue(t, NewOutbox(repo), 2)
+
+	pub := &recordingPublisher{failures: map[uint64]int{1: 5}}
+	NewDispatcher(repo, pub, time.Second, 1).dispatch(context.Background())
+
+	assert.Equal(t, []uint64{2}, pub.got)
+	row, err := repo.FindOne(context.Background(), map[string]any{"id": 1})
+	require.NoError(t, err)
+	assert.NotNil(t, row.FailedAt)
+	assert.Equal(t, "subscriber unavailable", row.LastError)
+}
+
+func testBackoff(t *testing.T) {
+	t.Log("testBackoff - Testing the exponential retry schedule")
+	assert.Equal(t, time.Second, Backoff(1, time.Minute))
+	assert.Equal(t, 8*time.Second, Backoff(4, time.Minute))
+	assert.Equal(t, time.Minute, Backoff(30, time.Minute))
+}
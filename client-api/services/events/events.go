@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"case-itau/repositories"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	TypeCustomerCreated  = "CustomerCreated"
+	TypeCustomerUpdated  = "CustomerUpdated"
+	TypeCustomerDeleted  = "CustomerDeleted"
+	TypeDepositBooked    = "DepositBooked"
+	TypeWithdrawalBooked = "WithdrawalBooked"
+)
+
+// Event is the envelope handed to publishers. ID increases monotonically in
+// the order the events were committed.
+type Event struct {
+	ID          uint64          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID uuid.UUID       `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+// CustomerPayload is the body of the customer lifecycle events.
+type CustomerPayload struct {
+	ID      uuid.UUID       `json:"id"`
+	Name    string          `json:"name"`
+	Email   string          `json:"email"`
+	Balance decimal.Decimal `json:"balance"`
+	Version int64           `json:"version"`
+}
+
+// TransactionPayload is the body of DepositBooked and WithdrawalBooked. Amount
+// is signed like the stored transaction and Balance is the resulting balance.
+type TransactionPayload struct {
+	TransactionID uuid.UUID       `json:"transaction_id"`
+	CustomerID    uuid.UUID       `json:"customer_id"`
+	Amount        decimal.Decimal `json:"amount"`
+	Balance       decimal.Decimal `json:"balance"`
+	Version       int64           `json:"version"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// Publisher delivers events to the outside world. Delivery is at least once,
+// so implementations and their consumers should tolerate duplicates.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+func NewCustomerPayload(c *repositories.Customers) CustomerPayload {
+	return CustomerPayload{ID: c.ID, Name: c.Name, Email: c.Email, Balance: c.Balance, Version: c.Version}
+}
+
+// FromRow converts a stored outbox row into the event envelope.
+func FromRow(row *repositories.OutboxEvent) Event {
+	return Event{
+		ID:          row.ID,
+		Type:        row.Type,
+		AggregateID: row.AggregateID,
+		Payload:     json.RawMessage(row.Payload),
+		OccurredAt:  row.CreatedAt,
+	}
+}
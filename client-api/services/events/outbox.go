@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"case-itau/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Outbox stores domain events alongside the state change that produced them.
+type Outbox struct {
+	repo repositories.IRepository[repositories.OutboxEvent]
+
+	mu        sync.RWMutex
+	listeners []func(Event)
+}
+
+func NewOutbox(repo repositories.IRepository[repositories.OutboxEvent]) *Outbox {
+	return &Outbox{repo: repo}
+}
+
+// Subscribe registers fn to be called with every event once the transaction
+// that enqueued it commits. Listeners run on the committing goroutine and must
+// not block.
+func (o *Outbox) Subscribe(fn func(Event)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.listeners = append(o.listeners, fn)
+}
+
+// Enqueue writes an event for aggregateID using the transaction bound to ctx,
+// so the event is stored if and only if the surrounding change commits.
+func (o *Outbox) Enqueue(ctx context.Context, eventType string, aggregateID uuid.UUID, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	row := &repositories.OutboxEvent{
+		AggregateID:   aggregateID,
+		Type:          eventType,
+		Payload:       string(data),
+		NextAttemptAt: time.Now(),
+	}
+	if err := o.repo.InsertOne(ctx, row); err != nil {
+		return err
+	}
+
+	e := FromRow(row)
+	repositories.AfterCommit(ctx, func() {
+		o.mu.RLock()
+		defer o.mu.RUnlock()
+		for _, fn := range o.listeners {
+			fn(e)
+		}
+	})
+	return nil
+}
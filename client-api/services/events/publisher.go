@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// LogPublisher writes every event to the application log.
+type LogPublisher struct {
+	logger *zap.Logger
+}
+
+func NewLogPublisher(logger *zap.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, e Event) error {
+	p.logger.Info("domain event",
+		zap.Uint64("event_id", e.ID),
+		zap.String("type", e.Type),
+		zap.String("aggregate_id", e.AggregateID.String()),
+		zap.ByteString("payload", e.Payload),
+	)
+	return nil
+}
+
+// FilePublisher appends every event as one JSON line to a file, which is
+// handy for inspecting the event stream locally.
+type FilePublisher struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFilePublisher(path string) *FilePublisher {
+	return &FilePublisher{path: path}
+}
+
+func (p *FilePublisher) Publish(_ context.Context, e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// MultiPublisher hands every event to each of its publishers in turn. When one
+// of them fails the event is retried as a whole, so the others may see it again.
+type MultiPublisher []Publisher
+
+func (m MultiPublisher) Publish(ctx context.Context, e Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
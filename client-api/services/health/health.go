@@ -0,0 +1,178 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	l "case-itau/utils/logger"
+
+	"go.uber.org/zap"
+)
+
+// Report statuses.
+const (
+	StatusUp           = "up"
+	StatusDown         = "down"
+	StatusOK           = "ok"
+	StatusDegraded     = "degraded"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check probes one dependency of the API, returning nil when it is usable.
+type Check func(ctx context.Context) error
+
+// Component is the outcome of one check.
+type Component struct {
+	Status  string
+	Latency time.Duration
+	Err     error
+}
+
+// Report is the readiness of the API and of each of its components.
+type Report struct {
+	Status     string
+	Components map[string]Component
+}
+
+// Ready reports whether the API can take traffic.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Service runs the readiness checks. Once Drain is called every report is
+// marked as shutting down, so load balancers stop routing new requests to the
+// instance while in-flight ones finish.
+type Service struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	checks   []namedCheck
+	draining atomic.Bool
+
+	// mu serialises the checks, so that concurrent probes share one run.
+	mu        sync.Mutex
+	last      Report
+	checkedAt time.Time
+	now       func() time.Time
+}
+
+// NewService returns a Service giving each check at most timeout to answer
+// and reusing a report for cacheTTL before running the checks again.
+func NewService(timeout, cacheTTL time.Duration) *Service {
+	return &Service{timeout: timeout, cacheTTL: cacheTTL, now: time.Now}
+}
+
+// Register adds a check reported under name. Checks must be registered before
+// the service starts answering requests.
+func (s *Service) Register(name string, check Check) {
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+// Drain marks the instance as shutting down.
+func (s *Service) Drain() {
+	s.draining.Store(true)
+}
+
+// Draining reports whether Drain was called.
+func (s *Service) Draining() bool {
+	return s.draining.Load()
+}
+
+// Ready reports the instance as degraded when any check fails. The checks run
+// concurrently, at most once per cache interval; failures are logged when they
+// run, as the report is meant for public probes and carries no error details
+// out of the process.
+func (s *Service) Ready(ctx context.Context) Report {
+	s.mu.Lock()
+	if s.checkedAt.IsZero() || s.now().Sub(s.checkedAt) >= s.cacheTTL {
+		s.last = s.check(ctx)
+		s.checkedAt = s.now()
+	}
+	report := s.last
+	s.mu.Unlock()
+
+	if s.Draining() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// check runs every check and logs the ones that failed.
+func (s *Service) check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	components := make([]Component, len(s.checks))
+	var wg sync.WaitGroup
+	for i, nc := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			components[i] = run(ctx, nc.check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(s.checks))}
+	for i, nc := range s.checks {
+		report.Components[nc.name] = components[i]
+		if components[i].Status != StatusUp {
+			report.Status = StatusDegraded
+			l.Logger.Warn("readiness check failed", zap.String("component", nc.name), zap.Duration("latency", components[i].Latency), zap.Error(components[i].Err))
+		}
+	}
+	return report
+}
+
+// run executes check, turning a panic or a check outliving ctx into a failure.
+func run(ctx context.Context, check Check) (c Component) {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	c = Component{Status: StatusUp, Latency: time.Since(start), Err: err}
+	if err != nil {
+		c.Status = StatusDown
+	}
+	return c
+}
+
+// DiskWritable checks that a file can be created, written and synced in dir,
+// which is where the database and its journal live.
+func DiskWritable(dir string) Check {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".health-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		if _, err := f.WriteString("ok"); err != nil {
+			return err
+		}
+		return f.Sync()
+	}
+}
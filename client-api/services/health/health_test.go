@@ -0,0 +1,138 @@
+//go:build unit
+
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	l "case-itau/utils/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCases_Health_Unit(t *testing.T) {
+	l.Logger = zap.NewNop()
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success reporting every component up", testReadyAllUp},
+		{"Failure reporting a degraded component", testReadyDegraded},
+		{"Failure bounding slow and panicking checks", testReadyTimeoutAndPanic},
+		{"Failure reporting shutdown while draining", testReadyDraining},
+		{"Success reusing a report until the cache interval elapses", testReadyCached},
+		{"Success writing to a temporary directory", testDiskWritable},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func ok(context.Context) error { return nil }
+
+func testReadyAllUp(t *testing.T) {
+	t.Log("testReadyAllUp - Testing a success clause for a report with every check passing")
+	s := NewService(time.Second, 0)
+	s.Register("database", ok)
+	s.Register("disk", ok)
+
+	report := s.Ready(context.Background())
+
+	assert.True(t, report.Ready())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Components, 2)
+	assert.Equal(t, StatusUp, report.Components["database"].Status)
+}
+
+func testReadyDegraded(t *testing.T) {
+	t.Log("testReadyDegraded - Testing a failure clause for a report with one failing check")
+	s := NewService(time.Second, 0)
+	s.Register("database", ok)
+	s.Register("migrations", func(context.Context) error { return errors.New("table customers is missing") })
+
+	report := s.Ready(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusUp, report.Components["database"].Status)
+	assert.Equal(t, StatusDown, report.Components["migrations"].Status)
+	assert.EqualError(t, report.Components["migrations"].Err, "table customers is missing")
+}
+
+func testReadyTimeoutAndPanic(t *testing.T) {
+	t.Log("testReadyTimeoutAndPanic - Testing a failure clause for checks that hang or panic")
+	s := NewService(50*time.Millisecond, 0)
+	s.Register("slow", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	s.Register("broken", func(context.Context) error { panic("boom") })
+
+	start := time.Now()
+	report := s.Ready(context.Background())
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.ErrorIs(t, report.Components["slow"].Err, context.DeadlineExceeded)
+	assert.ErrorContains(t, report.Components["broken"].Err, "boom")
+}
+
+func testReadyDraining(t *testing.T) {
+	t.Log("testReadyDraining - Testing a failure clause for an instance that is shutting down")
+	s := NewService(time.Second, 0)
+	s.Register("database", ok)
+	s.Drain()
+
+	report := s.Ready(context.Background())
+
+	assert.True(t, s.Draining())
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusShuttingDown, report.Status)
+}
+
+func testReadyCached(t *testing.T) {
+	t.Log("testReadyCached - Testing a success clause for probes answered from the last report")
+	s := NewService(time.Second, 2*time.Second)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	runs := 0
+	s.Register("database", func(context.Context) error {
+		runs++
+		return nil
+	})
+
+	assert.True(t, s.Ready(context.Background()).Ready())
+	now = now.Add(time.Second)
+	assert.True(t, s.Ready(context.Background()).Ready())
+	assert.Equal(t, 1, runs)
+
+	s.Drain()
+	assert.Equal(t, StatusShuttingDown, s.Ready(context.Background()).Status, "draining does not wait for the cache")
+	assert.Equal(t, 1, runs)
+
+	now = now.Add(time.Second)
+	s.Ready(context.Background())
+	assert.Equal(t, 2, runs)
+}
+
+func testDiskWritable(t *testing.T) {
+	t.Log("testDiskWritable - Testing a success clause for a writable directory left clean")
+	dir := t.TempDir()
+
+	require.NoError(t, DiskWritable(dir)(context.Background()))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.Error(t, DiskWritable(dir+"/missing")(context.Background()))
+}
@@ -0,0 +1,233 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"case-itau/api/types"
+	"case-itau/repositories"
+	"case-itau/services/customer"
+	"case-itau/utils/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// ModeBestEffort applies every valid row on its own and reports the rest.
+	ModeBestEffort = "best_effort"
+	// ModeAllOrNothing applies the rows in a single transaction that is rolled
+	// back as soon as one of them fails.
+	ModeAllOrNothing = "all_or_nothing"
+
+	TypeDeposit  = "deposit"
+	TypeWithdraw = "withdraw"
+)
+
+// Row statuses reported back to the caller.
+const (
+	RowSuccess           = "success"
+	RowInvalid           = "invalid"
+	RowCustomerNotFound  = "customer_not_found"
+	RowInsufficientFunds = "insufficient_funds"
+	RowDuplicate         = "duplicate_reference"
+	RowError             = "error"
+	RowRolledBack        = "rolled_back"
+	RowSkipped           = "skipped"
+)
+
+// Job statuses.
+const (
+	JobCompleted = "completed"
+	JobPartial   = "partial"
+	JobFailed    = "failed"
+)
+
+var ErrJobNotFound = errors.New("importação não encontrada")
+
+// errRowFailed aborts an all-or-nothing import so that its transaction rolls back.
+var errRowFailed = errors.New("row failed")
+
+// RowResult is the outcome of a single row.
+type RowResult struct {
+	Line      int              `json:"line"`
+	Customer  string           `json:"customer"`
+	Type      string           `json:"type"`
+	Amount    decimal.Decimal  `json:"amount"`
+	Reference string           `json:"reference"`
+	Status    string           `json:"status"`
+	Message   string           `json:"message,omitempty"`
+	Balance   *decimal.Decimal `json:"balance,omitempty"`
+}
+
+type Service struct {
+	tx        repositories.Transactor
+	customers *customer.Service
+	jobs      repositories.IRepository[repositories.ImportJob]
+}
+
+func NewService(tx repositories.Transactor, customers *customer.Service, jobs repositories.IRepository[repositories.ImportJob]) *Service {
+	return &Service{tx: tx, customers: customers, jobs: jobs}
+}
+
+// Import applies rows according to mode and stores the resulting job.
+func (s *Service) Import(ctx context.Context, mode string, rows []types.TransactionBatchRow) (*repositories.ImportJob, []RowResult, error) {
+	var results []RowResult
+	if mode == ModeAllOrNothing {
+		results = s.importAtomically(ctx, rows)
+	} else {
+		mode = ModeBestEffort
+		results = make([]RowResult, 0, len(rows))
+		for _, row := range rows {
+			results = append(results, s.apply(ctx, row))
+		}
+	}
+
+	report, err := json.Marshal(results)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	job := &repositories.ImportJob{
+		ID:        uuid.New(),
+		Mode:      mode,
+		TotalRows: len(rows),
+		Actor:     requestctx.FromContext(ctx).Actor,
+		Report:    string(report),
+	}
+	for _, r := range results {
+		if r.Status == RowSuccess {
+			job.Succeeded++
+		} else {
+			job.Failed++
+		}
+	}
+	switch {
+	case job.Failed == 0:
+		job.Status = JobCompleted
+	case job.Succeeded == 0:
+		job.Status = JobFailed
+	default:
+		job.Status = JobPartial
+	}
+
+	if err := s.jobs.InsertOne(ctx, job); err != nil {
+		return nil, nil, err
+	}
+	return job, results, nil
+}
+
+// Get returns a stored job and its per-row report.
+func (s *Service) Get(ctx context.Context, id string) (*repositories.ImportJob, []RowResult, error) {
+	job, err := s.jobs.FindOne(ctx, map[string]any{"id": id})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, nil, ErrJobNotFound
+		}
+		return nil, nil, err
+	}
+
+	var results []RowResult
+	if err := json.Unmarshal([]byte(job.Report), &results); err != nil {
+		return nil, nil, err
+	}
+	return job, results, nil
+}
+
+// importAtomically applies every row inside one transaction. Invalid rows are
+// caught before anything is written; a row failing while being applied rolls
+// back the rows before it, and the rows after it are skipped.
+func (s *Service) importAtomically(ctx context.Context, rows []types.TransactionBatchRow) []RowResult {
+	results := make([]RowResult, len(rows))
+
+	invalid := false
+	for i, row := range rows {
+		results[i] = newResult(row, RowSkipped, "")
+		if row.Invalid != "" {
+			results[i] = newResult(row, RowInvalid, row.Invalid)
+			invalid = true
+		}
+	}
+	if invalid {
+		return results
+	}
+
+	failed := -1
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i, row := range rows {
+			results[i] = s.apply(ctx, row)
+			if results[i].Status != RowSuccess {
+				failed = i
+				return errRowFailed
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		return results
+	}
+
+	if failed < 0 {
+		// The commit itself failed, so none of the rows were applied.
+		for i, row := range rows {
+			results[i] = newResult(row, RowError, err.Error())
+		}
+		return results
+	}
+	for i := 0; i < failed; i++ {
+		results[i].Status = RowRolledBack
+		results[i].Balance = nil
+	}
+	return results
+}
+
+// apply books a single row through the customer service.
+func (s *Service) apply(ctx context.Context, row types.TransactionBatchRow) RowResult {
+	if row.Invalid != "" {
+		return newResult(row, RowInvalid, row.Invalid)
+	}
+
+	cust, err := s.customers.Resolve(ctx, row.Customer)
+	if err != nil {
+		if errors.Is(err, customer.ErrNotFound) {
+			return newResult(row, RowCustomerNotFound, "cliente não encontrado")
+		}
+		return newResult(row, RowError, err.Error())
+	}
+
+	delta := row.Amount
+	if row.Type == TypeWithdraw {
+		delta = delta.Neg()
+	}
+
+	updated, err := s.customers.TransactionsWithReference(ctx, cust.ID.String(), 0, delta, row.Reference)
+	if err != nil {
+		if errors.Is(err, customer.ErrInsufficientFunds) {
+			return newResult(row, RowInsufficientFunds, "saldo insuficiente")
+		}
+		if errors.Is(err, customer.ErrDuplicateReference) {
+			return newResult(row, RowDuplicate, "referência já importada")
+		}
+		if errors.Is(err, customer.ErrNotFound) {
+			return newResult(row, RowCustomerNotFound, "cliente não encontrado")
+		}
+		return newResult(row, RowError, err.Error())
+	}
+
+	result := newResult(row, RowSuccess, "")
+	result.Balance = &updated.Balance
+	return result
+}
+
+func newResult(row types.TransactionBatchRow, status, message string) RowResult {
+	return RowResult{
+		Line:      row.Line,
+		Customer:  row.Customer,
+		Type:      row.Type,
+		Amount:    row.Amount,
+		Reference: row.Reference,
+		Status:    status,
+		Message:   message,
+	}
+}
@@ -0,0 +1,192 @@
+//go:build unit
+
+package importer
+
+import (
+	"context"
+	"testing"
+
+	"case-itau/api/types"
+	"case-itau/repositories"
+	"case-itau/services/customer/customertest"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	svc *Service
+	cf  *customertest.Fixture
+}
+
+func newFixture(t *testing.T) *fixture {
+	cf := customertest.New(t)
+	cf.Create(t, "John Doe", "john.doe@example.com")
+	return &fixture{
+		svc: NewService(cf.Tx, cf.Customers, repositories.NewGormRepository[repositories.ImportJob](cf.DB)),
+		cf:  cf,
+	}
+}
+
+func (f *fixture) balance(t *testing.T) decimal.Decimal {
+	c, err := f.cf.Customers.Resolve(context.Background(), "john.doe@example.com")
+	require.NoError(t, err)
+	return c.Balance
+}
+
+func row(line int, kind string, amount int64, reference string) types.TransactionBatchRow {
+	return types.TransactionBatchRow{
+		Line:      line,
+		Customer:  "john.doe@example.com",
+		Type:      kind,
+		Amount:    decimal.NewFromInt(amount),
+		Reference: reference,
+	}
+}
+
+func statuses(results []RowResult) []string {
+	out := make([]string, 0, len(results))
+	for _, r := range results {
+		out = append(out, r.Status)
+	}
+	return out
+}
+
+func TestCases_Importer_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success applying every row of a valid file", testImportCompleted},
+		{"Success applying the valid rows of a file in best effort", testBestEffortPartial},
+		{"Failure rolling back an all-or-nothing import when a row fails", testAllOrNothingRollback},
+		{"Failure applying an all-or-nothing import holding an invalid row", testAllOrNothingInvalid},
+		{"Failure booking a reference imported before", testDuplicateReference},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testImportCompleted(t *testing.T) {
+	t.Log("testImportCompleted - Testing a success clause for a job completed by every row and stored with its report")
+	f := newFixture(t)
+	ctx := context.Background()
+
+	job, results, err := f.svc.Import(ctx, ModeAllOrNothing, []types.TransactionBatchRow{
+		row(2, TypeDeposit, 100, "A-1"),
+		row(3, TypeWithdraw, 40, "A-2"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, JobCompleted, job.Status)
+	assert.Equal(t, 2, job.Succeeded)
+	assert.Zero(t, job.Failed)
+	assert.Equal(t, []string{RowSuccess, RowSuccess}, statuses(results))
+	assert.True(t, decimal.NewFromInt(60).Equal(f.balance(t)))
+
+	stored, report, err := f.svc.Get(ctx, job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, ModeAllOrNothing, stored.Mode)
+	assert.Equal(t, results[1].Line, report[1].Line)
+	assert.True(t, results[1].Balance.Equal(*report[1].Balance))
+
+	_, _, err = f.svc.Get(ctx, "00000000-0000-0000-0000-000000000000")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func testBestEffortPartial(t *testing.T) {
+	t.Log("testBestEffortPartial - Testing a success clause for the valid rows applied and the others reported in a partial job")
+	f := newFixture(t)
+	invalid := row(4, "refund", 5, "")
+	invalid.Invalid = "tipo deve ser deposit ou withdraw"
+	unknown := row(5, TypeDeposit, 5, "")
+	unknown.Customer = "nobody@example.com"
+
+	job, results, err := f.svc.Import(context.Background(), ModeBestEffort, []types.TransactionBatchRow{
+		row(2, TypeDeposit, 50, ""),
+		row(3, TypeWithdraw, 80, ""),
+		invalid,
+		unknown,
+		row(6, TypeWithdraw, 20, ""),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RowSuccess, RowInsufficientFunds, RowInvalid, RowCustomerNotFound, RowSuccess}, statuses(results))
+	assert.Equal(t, JobPartial, job.Status)
+	assert.Equal(t, 2, job.Succeeded)
+	assert.Equal(t, 3, job.Failed)
+	assert.True(t, decimal.NewFromInt(30).Equal(f.balance(t)))
+
+	job, _, err = f.svc.Import(context.Background(), ModeBestEffort, []types.TransactionBatchRow{invalid})
+	require.NoError(t, err)
+	assert.Equal(t, JobFailed, job.Status)
+}
+
+func testAllOrNothingRollback(t *testing.T) {
+	t.Log("testAllOrNothingRollback - Testing a failure clause for the rows before a failing one rolled back and the rows after it skipped")
+	f := newFixture(t)
+
+	job, results, err := f.svc.Import(context.Background(), ModeAllOrNothing, []types.TransactionBatchRow{
+		row(2, TypeDeposit, 50, "B-1"),
+		row(3, TypeWithdraw, 80, "B-2"),
+		row(4, TypeDeposit, 10, "B-3"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RowRolledBack, RowInsufficientFunds, RowSkipped}, statuses(results))
+	assert.Nil(t, results[0].Balance, "rolled back rows report no balance")
+	assert.Equal(t, JobFailed, job.Status)
+	assert.Zero(t, job.Succeeded)
+	assert.Equal(t, 3, job.Failed)
+	assert.True(t, f.balance(t).IsZero())
+
+	// The references of rolled back rows were not booked.
+	_, results, err = f.svc.Import(context.Background(), ModeAllOrNothing, []types.TransactionBatchRow{row(2, TypeDeposit, 50, "B-1")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RowSuccess}, statuses(results))
+}
+
+func testAllOrNothingInvalid(t *testing.T) {
+	t.Log("testAllOrNothingInvalid - Testing a failure clause for an invalid row keeping every row from being applied")
+	f := newFixture(t)
+	invalid := row(3, TypeDeposit, 0, "")
+	invalid.Invalid = "valor da transação deve ser maior que zero"
+
+	job, results, err := f.svc.Import(context.Background(), ModeAllOrNothing, []types.TransactionBatchRow{
+		row(2, TypeDeposit, 50, ""),
+		invalid,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RowSkipped, RowInvalid}, statuses(results))
+	assert.Equal(t, invalid.Invalid, results[1].Message)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.True(t, f.balance(t).IsZero())
+}
+
+func testDuplicateReference(t *testing.T) {
+	t.Log("testDuplicateReference - Testing a failure clause for rows whose reference was booked by an earlier upload or row")
+	f := newFixture(t)
+	rows := []types.TransactionBatchRow{
+		row(2, TypeDeposit, 50, "C-1"),
+		row(3, TypeDeposit, 10, ""),
+	}
+
+	_, _, err := f.svc.Import(context.Background(), ModeBestEffort, rows)
+	require.NoError(t, err)
+	job, results, err := f.svc.Import(context.Background(), ModeBestEffort, append(rows, row(4, TypeDeposit, 5, "C-1")))
+	require.NoError(t, err)
+	assert.Equal(t, []string{RowDuplicate, RowSuccess, RowDuplicate}, statuses(results), "rows without a reference are not deduplicated")
+	assert.Equal(t, JobPartial, job.Status)
+	assert.True(t, decimal.NewFromInt(70).Equal(f.balance(t)))
+
+	_, results, err = f.svc.Import(context.Background(), ModeAllOrNothing, []types.TransactionBatchRow{
+		row(2, TypeDeposit, 5, "C-2"),
+		row(3, TypeDeposit, 5, "C-1"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RowRolledBack, RowDuplicate}, statuses(results))
+	assert.True(t, decimal.NewFromInt(70).Equal(f.balance(t)))
+}
@@ -0,0 +1,53 @@
+package maintenance
+
+import (
+	"sync"
+	"time"
+)
+
+// Status describes whether the API is read-only, since when and why.
+type Status struct {
+	Enabled bool
+	Reason  string
+	Since   time.Time
+}
+
+// Mode is the maintenance switch of one API instance. While it is enabled the
+// API serves reads but rejects every write.
+type Mode struct {
+	mu     sync.RWMutex
+	status Status
+}
+
+func NewMode() *Mode {
+	return &Mode{}
+}
+
+// Enable makes the API read-only. Enabling it again only updates the reason.
+func (m *Mode) Enable(reason string) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.status.Enabled {
+		m.status.Since = time.Now().UTC()
+	}
+	m.status.Enabled = true
+	m.status.Reason = reason
+	return m.status
+}
+
+// Disable lets the API accept writes again.
+func (m *Mode) Disable() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status = Status{}
+	return m.status
+}
+
+// Status returns the current state of the switch.
+func (m *Mode) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
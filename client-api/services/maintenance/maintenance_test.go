@@ -0,0 +1,51 @@
+//go:build unit
+
+package maintenance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCases_Maintenance_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success toggling maintenance mode", testToggle},
+		{"Success keeping the start time when enabled twice", testEnableTwice},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testToggle(t *testing.T) {
+	t.Log("testToggle - Testing a success clause for enabling and disabling maintenance mode")
+	m := NewMode()
+	assert.False(t, m.Status().Enabled)
+
+	status := m.Enable("database upgrade")
+	assert.True(t, status.Enabled)
+	assert.Equal(t, "database upgrade", status.Reason)
+	assert.False(t, status.Since.IsZero())
+	assert.Equal(t, status, m.Status())
+
+	assert.Equal(t, Status{}, m.Disable())
+	assert.False(t, m.Status().Enabled)
+}
+
+func testEnableTwice(t *testing.T) {
+	t.Log("testEnableTwice - Testing a success clause for updating the reason without resetting the start time")
+	m := NewMode()
+	first := m.Enable("backup")
+	second := m.Enable("backup and reindex")
+
+	assert.Equal(t, first.Since, second.Since)
+	assert.Equal(t, "backup and reindex", second.Reason)
+}
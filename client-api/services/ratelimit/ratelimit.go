@@ -0,0 +1,166 @@
+// Package ratelimit counts requests per client in fixed windows, with a
+// separate quota for each class of route.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	l "case-itau/utils/logger"
+
+	"go.uber.org/zap"
+)
+
+// Class groups routes that share a quota.
+type Class string
+
+const (
+	ClassRead  Class = "read"
+	ClassWrite Class = "write"
+	// ClassMoney covers the routes that move balances.
+	ClassMoney Class = "money"
+	// ClassAuth covers logins and token refreshes, to slow down credential
+	// stuffing.
+	ClassAuth Class = "auth"
+	// ClassAuthFailure covers the requests of an IP that failed to
+	// authenticate, to slow down guessing tokens and API keys.
+	ClassAuthFailure Class = "auth_failure"
+)
+
+// Store keeps the counters. Implementations shared by several instances, such
+// as the database one, make the limits apply to the whole deployment.
+type Store interface {
+	// Increment adds a hit to key in the window starting at windowStart and
+	// returns the hits counted so far in that window.
+	Increment(ctx context.Context, key string, windowStart time.Time) (int, error)
+	// Count returns the hits counted for key in the window starting at
+	// windowStart.
+	Count(ctx context.Context, key string, windowStart time.Time) (int, error)
+	// Purge drops the windows that started before the given time.
+	Purge(ctx context.Context, before time.Time) error
+}
+
+// Result describes the quota of a client after a request was counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+type Limiter struct {
+	store  Store
+	window time.Duration
+	quotas map[Class]int
+	now    func() time.Time
+}
+
+// NewLimiter builds a limiter allowing quotas[class] requests per window.
+// Classes without a quota are not limited.
+func NewLimiter(store Store, window time.Duration, quotas map[Class]int) *Limiter {
+	return &Limiter{store: store, window: window, quotas: quotas, now: time.Now}
+}
+
+// Window returns the length of the counting windows.
+func (li *Limiter) Window() time.Duration {
+	return li.window
+}
+
+// Allow counts a request of client against the quota of class.
+func (li *Limiter) Allow(ctx context.Context, class Class, client string) (Result, error) {
+	limit, ok := li.quotas[class]
+	if !ok || limit <= 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	start := li.now().Truncate(li.window)
+	count, err := li.store.Increment(ctx, string(class)+"|"+client, start)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:   count <= limit,
+		Limit:     limit,
+		Remaining: max(limit-count, 0),
+		Reset:     start.Add(li.window),
+	}, nil
+}
+
+// Check reports the quota of client for class without counting a request.
+// Allowed is false once the quota is spent.
+func (li *Limiter) Check(ctx context.Context, class Class, client string) (Result, error) {
+	limit, ok := li.quotas[class]
+	if !ok || limit <= 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	start := li.now().Truncate(li.window)
+	count, err := li.store.Count(ctx, string(class)+"|"+client, start)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:   count < limit,
+		Limit:     limit,
+		Remaining: max(limit-count, 0),
+		Reset:     start.Add(li.window),
+	}, nil
+}
+
+// Run purges expired windows once per window until ctx is done.
+func (li *Limiter) Run(ctx context.Context) {
+	ticker := time.NewTicker(li.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := li.store.Purge(ctx, li.now().Truncate(li.window)); err != nil {
+				l.Logger.Warn("failed to purge rate limit counters", zap.Error(err))
+			}
+		}
+	}
+}
+
+type memoryKey struct {
+	key   string
+	start int64
+}
+
+// MemoryStore keeps the counters in process, for single instance setups and
+// tests. They are lost on restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	counts map[memoryKey]int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counts: map[memoryKey]int{}}
+}
+
+func (s *MemoryStore) Increment(_ context.Context, key string, windowStart time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := memoryKey{key: key, start: windowStart.Unix()}
+	s.counts[k]++
+	return s.counts[k], nil
+}
+
+func (s *MemoryStore) Count(_ context.Context, key string, windowStart time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[memoryKey{key: key, start: windowStart.Unix()}], nil
+}
+
+func (s *MemoryStore) Purge(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range s.counts {
+		if k.start < before.Unix() {
+			delete(s.counts, k)
+		}
+	}
+	return nil
+}
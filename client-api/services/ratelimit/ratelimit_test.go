@@ -0,0 +1,152 @@
+//go:build unit
+
+package ratelimit
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCases_RateLimit_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success counting each class and client separately", testSeparateQuotas},
+		{"Success starting a new window once the previous one ends", testWindowRollover},
+		{"Success checking a quota without counting a request", testCheck},
+		{"Success keeping database counts across limiters and concurrent requests", testDatabaseStore},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func newDatabaseStore(t *testing.T) *repositories.RateLimitStore {
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "ratelimit.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&repositories.RateLimitCounter{}))
+	return repositories.NewRateLimitStore(db)
+}
+
+func testSeparateQuotas(t *testing.T) {
+	t.Log("testSeparateQuotas - Testing a success clause for independent quotas and a failure clause once one is spent")
+	li := NewLimiter(NewMemoryStore(), time.Minute, map[Class]int{ClassRead: 3, ClassMoney: 1})
+	ctx := context.Background()
+
+	for i := range 3 {
+		res, err := li.Allow(ctx, ClassRead, "user:a")
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+	res, err := li.Allow(ctx, ClassRead, "user:a")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, _ = li.Allow(ctx, ClassRead, "user:b")
+	assert.True(t, res.Allowed)
+	res, _ = li.Allow(ctx, ClassMoney, "user:a")
+	assert.True(t, res.Allowed)
+	res, _ = li.Allow(ctx, ClassMoney, "user:a")
+	assert.False(t, res.Allowed)
+
+	// Classes without a quota are not limited.
+	res, _ = li.Allow(ctx, ClassWrite, "user:a")
+	assert.True(t, res.Allowed)
+	assert.Zero(t, res.Limit)
+}
+
+func testWindowRollover(t *testing.T) {
+	t.Log("testWindowRollover - Testing a success clause for a spent quota being renewed by the next window")
+	now := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+	li := NewLimiter(NewMemoryStore(), time.Minute, map[Class]int{ClassRead: 1})
+	li.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	res, _ := li.Allow(ctx, ClassRead, "ip:1.2.3.4")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC), res.Reset)
+	res, _ = li.Allow(ctx, ClassRead, "ip:1.2.3.4")
+	assert.False(t, res.Allowed)
+
+	now = now.Add(30 * time.Second)
+	res, _ = li.Allow(ctx, ClassRead, "ip:1.2.3.4")
+	assert.True(t, res.Allowed)
+}
+
+func testCheck(t *testing.T) {
+	t.Log("testCheck - Testing a success clause for checks that leave the count alone and a failure clause once the quota is spent")
+	ctx := context.Background()
+	for _, store := range []Store{NewMemoryStore(), newDatabaseStore(t)} {
+		li := NewLimiter(store, time.Minute, map[Class]int{ClassAuthFailure: 2})
+		res, err := li.Check(ctx, ClassAuthFailure, "ip:1.2.3.4")
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2, res.Remaining)
+
+		li.Allow(ctx, ClassAuthFailure, "ip:1.2.3.4")
+		res, _ = li.Check(ctx, ClassAuthFailure, "ip:1.2.3.4")
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 1, res.Remaining)
+
+		li.Allow(ctx, ClassAuthFailure, "ip:1.2.3.4")
+		res, _ = li.Check(ctx, ClassAuthFailure, "ip:1.2.3.4")
+		assert.False(t, res.Allowed)
+		res, _ = li.Check(ctx, ClassAuthFailure, "ip:5.6.7.8")
+		assert.True(t, res.Allowed)
+	}
+}
+
+func testDatabaseStore(t *testing.T) {
+	t.Log("testDatabaseStore - Testing a success clause for counts shared through the database")
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "ratelimit.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&repositories.RateLimitCounter{}))
+	ctx := context.Background()
+
+	// Two limiters over the same database stand for two instances.
+	quotas := map[Class]int{ClassWrite: 20}
+	first := NewLimiter(repositories.NewRateLimitStore(db), time.Minute, quotas)
+	second := NewLimiter(repositories.NewRateLimitStore(db), time.Minute, quotas)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			li := first
+			if i%2 == 1 {
+				li = second
+			}
+			res, err := li.Allow(ctx, ClassWrite, "apikey:ck_1")
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+		}()
+	}
+	wg.Wait()
+
+	res, err := second.Allow(ctx, ClassWrite, "apikey:ck_1")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	store := repositories.NewRateLimitStore(db)
+	require.NoError(t, store.Purge(ctx, time.Now().Add(2*time.Minute)))
+	count, err := store.Increment(ctx, string(ClassWrite)+"|apikey:ck_1", time.Now().Truncate(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
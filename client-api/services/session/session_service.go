@@ -0,0 +1,376 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/services/customer"
+	"case-itau/utils/auth"
+	l "case-itau/utils/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CustomerRole is granted to the access tokens of logged in customers.
+const CustomerRole = "customer"
+
+var (
+	ErrInvalidCredentials  = errors.New("e-mail ou senha inválidos")
+	ErrAccountLocked       = errors.New("conta bloqueada temporariamente após tentativas de login inválidas")
+	ErrInvalidRefreshToken = errors.New("refresh token inválido ou expirado")
+	ErrSessionNotFound     = errors.New("sessão não encontrada")
+	ErrWrongPassword       = errors.New("senha atual incorreta")
+	ErrLoginUnavailable    = errors.New("login de clientes não configurado")
+)
+
+// dummyHash is compared against when the account does not exist, so that
+// unknown e-mails take as long to reject as wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// Config sets token lifetimes and the lockout policy.
+type Config struct {
+	AccessTokenTTL    time.Duration
+	RefreshTokenTTL   time.Duration
+	MaxFailedAttempts int
+	LockoutDuration   time.Duration
+}
+
+// Tokens is the result of a login or refresh.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+	SessionID    uuid.UUID
+	CustomerID   uuid.UUID
+}
+
+type Service struct {
+	tx        repositories.Transactor
+	customers *customer.Service
+	creds     repositories.IRepository[repositories.Credential]
+	sessions  repositories.IRepository[repositories.Session]
+	tokens    repositories.IRepository[repositories.RefreshToken]
+	issuer    *auth.Issuer
+	cfg       Config
+}
+
+// NewService builds the login service. With a nil issuer, logins fail with
+// ErrLoginUnavailable while passwords can still be managed.
+func NewService(tx repositories.Transactor, customers *customer.Service, creds repositories.IRepository[repositories.Credential], sessions repositories.IRepository[repositories.Session], tokens repositories.IRepository[repositories.RefreshToken], issuer *auth.Issuer, cfg Config) *Service {
+	return &Service{tx: tx, customers: customers, creds: creds, sessions: sessions, tokens: tokens, issuer: issuer, cfg: cfg}
+}
+
+// SetPassword sets the password of a customer and ends its sessions. When
+// selfService is set, the current password must be given if one exists.
+// Setting a password also lifts a lockout.
+func (s *Service) SetPassword(ctx context.Context, customerID string, current, password string, selfService bool) error {
+	cust, err := s.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		cred, err := s.creds.FindOne(ctx, map[string]any{"customer_id": cust.ID})
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return s.creds.InsertOne(ctx, &repositories.Credential{CustomerID: cust.ID, PasswordHash: string(hash)})
+		}
+		if err != nil {
+			return err
+		}
+		if selfService && bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(current)) != nil {
+			return ErrWrongPassword
+		}
+
+		err = s.creds.UpdateOne(ctx, map[string]any{"customer_id": cust.ID}, map[string]any{
+			"password_hash":   string(hash),
+			"failed_attempts": 0,
+			"locked_until":    nil,
+		})
+		if err != nil {
+			return err
+		}
+		return s.revokeAll(ctx, cust.ID)
+	})
+}
+
+// Login checks the credentials of a customer and opens a session.
+func (s *Service) Login(ctx context.Context, email, password, clientIP, userAgent string) (*Tokens, error) {
+	if s.issuer == nil {
+		return nil, ErrLoginUnavailable
+	}
+
+	cust, err := s.customers.Resolve(ctx, email)
+	if err != nil {
+		if errors.Is(err, customer.ErrNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	cred, err := s.creds.FindOne(ctx, map[string]any{"customer_id": cust.ID})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if cred.LockedUntil != nil && now.Before(*cred.LockedUntil) {
+		return nil, ErrAccountLocked
+	}
+
+	attempt, err := s.reserveAttempt(ctx, cust.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(password)) != nil {
+		return nil, s.recordFailure(ctx, cust.ID, attempt, now)
+	}
+	err = s.creds.UpdateOne(ctx, repositories.Expr("customer_id = ? AND (locked_until IS NULL OR locked_until <= ?)", cust.ID, now), map[string]any{"failed_attempts": 0, "locked_until": nil})
+	if errors.Is(err, repositories.ErrRepoNotFound) {
+		// A concurrent failed login locked the account.
+		return nil, ErrAccountLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &repositories.Session{
+		ID:         uuid.New(),
+		CustomerID: cust.ID,
+		ClientIP:   clientIP,
+		UserAgent:  userAgent,
+		ExpiresAt:  now.Add(s.cfg.RefreshTokenTTL),
+		LastUsedAt: now,
+	}
+	var refresh string
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.sessions.InsertOne(ctx, sess); err != nil {
+			return err
+		}
+		refresh, err = s.newRefreshToken(ctx, sess.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.tokensFor(sess, refresh)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+// Each refresh token works once; reusing one revokes its session.
+func (s *Service) Refresh(ctx context.Context, refresh string) (*Tokens, error) {
+	if s.issuer == nil {
+		return nil, ErrLoginUnavailable
+	}
+
+	token, err := s.tokens.FindOne(ctx, map[string]any{"hash": hashToken(refresh)})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	sess, err := s.activeSession(ctx, token.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if token.UsedAt != nil {
+		return nil, s.reused(ctx, sess)
+	}
+
+	now := time.Now()
+	var next string
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.tokens.UpdateOne(ctx, repositories.Expr("id = ? AND used_at IS NULL", token.ID), map[string]any{"used_at": now})
+		if err != nil {
+			return err
+		}
+		sess.ExpiresAt = now.Add(s.cfg.RefreshTokenTTL)
+		sess.LastUsedAt = now
+		err = s.sessions.UpdateOne(ctx, map[string]any{"id": sess.ID}, map[string]any{"expires_at": sess.ExpiresAt, "last_used_at": now})
+		if err != nil {
+			return err
+		}
+		next, err = s.newRefreshToken(ctx, sess.ID)
+		return err
+	})
+	if errors.Is(err, repositories.ErrRepoNotFound) {
+		// A concurrent request exchanged the same token first.
+		return nil, s.reused(ctx, sess)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.tokensFor(sess, next)
+}
+
+// Logout ends the session of a refresh token. Unknown tokens are ignored.
+func (s *Service) Logout(ctx context.Context, refresh string) error {
+	token, err := s.tokens.FindOne(ctx, map[string]any{"hash": hashToken(refresh)})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.revoke(ctx, token.SessionID)
+}
+
+// List returns the active sessions of a customer, most recently used first.
+func (s *Service) List(ctx context.Context, customerID string) ([]repositories.Session, error) {
+	return s.sessions.Find(ctx, repositories.Expr("customer_id = ? AND revoked_at IS NULL AND expires_at > ?", customerID, time.Now()), "last_used_at DESC", 0, 0)
+}
+
+// Revoke ends a session of a customer.
+func (s *Service) Revoke(ctx context.Context, customerID, sessionID string) error {
+	err := s.sessions.UpdateOne(ctx, repositories.Expr("id = ? AND customer_id = ? AND revoked_at IS NULL", sessionID, customerID), map[string]any{"revoked_at": time.Now()})
+	if errors.Is(err, repositories.ErrRepoNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// Active reports whether a session can still be used. Access tokens carry
+// their session ID so that revoking a session also cuts them off.
+func (s *Service) Active(ctx context.Context, sessionID string) (bool, error) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return false, nil
+	}
+	_, err = s.activeSession(ctx, id)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *Service) activeSession(ctx context.Context, id uuid.UUID) (*repositories.Session, error) {
+	sess, err := s.sessions.FindOne(ctx, map[string]any{"id": id})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if sess.RevokedAt != nil || time.Now().After(sess.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	return sess, nil
+}
+
+// reserveAttempt counts a login attempt before its password is checked and
+// returns its number since the last success or lockout. Counting first keeps
+// parallel guesses from all being checked against a count none of them has
+// raised yet: at most MaxFailedAttempts are checked before the account locks.
+func (s *Service) reserveAttempt(ctx context.Context, customerID uuid.UUID, now time.Time) (int, error) {
+	var attempt int
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.creds.UpdateOne(ctx,
+			repositories.Expr("customer_id = ? AND failed_attempts < ? AND (locked_until IS NULL OR locked_until <= ?)", customerID, s.cfg.MaxFailedAttempts, now),
+			map[string]any{"failed_attempts": repositories.Expr("failed_attempts + 1")},
+		)
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return ErrAccountLocked
+		}
+		if err != nil {
+			return err
+		}
+		cred, err := s.creds.FindOne(ctx, map[string]any{"customer_id": customerID})
+		if err != nil {
+			return err
+		}
+		attempt = cred.FailedAttempts
+		return nil
+	})
+	return attempt, err
+}
+
+// recordFailure locks the account when the failed login was the last attempt
+// allowed.
+func (s *Service) recordFailure(ctx context.Context, customerID uuid.UUID, attempt int, now time.Time) error {
+	if attempt < s.cfg.MaxFailedAttempts {
+		return ErrInvalidCredentials
+	}
+	l.FromContext(ctx).Warn("customer account locked", zap.String("customer_id", customerID.String()))
+	err := s.creds.UpdateOne(ctx, map[string]any{"customer_id": customerID}, map[string]any{
+		"failed_attempts": 0,
+		"locked_until":    now.Add(s.cfg.LockoutDuration),
+	})
+	if err != nil {
+		return err
+	}
+	return ErrAccountLocked
+}
+
+func (s *Service) reused(ctx context.Context, sess *repositories.Session) error {
+	l.FromContext(ctx).Warn("refresh token reused, revoking session", zap.String("session_id", sess.ID.String()), zap.String("customer_id", sess.CustomerID.String()))
+	if err := s.revoke(ctx, sess.ID); err != nil {
+		return err
+	}
+	return ErrInvalidRefreshToken
+}
+
+func (s *Service) revoke(ctx context.Context, sessionID uuid.UUID) error {
+	err := s.sessions.UpdateOne(ctx, repositories.Expr("id = ? AND revoked_at IS NULL", sessionID), map[string]any{"revoked_at": time.Now()})
+	if errors.Is(err, repositories.ErrRepoNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *Service) revokeAll(ctx context.Context, customerID uuid.UUID) error {
+	err := s.sessions.UpdateOne(ctx, repositories.Expr("customer_id = ? AND revoked_at IS NULL", customerID), map[string]any{"revoked_at": time.Now()})
+	if errors.Is(err, repositories.ErrRepoNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *Service) newRefreshToken(ctx context.Context, sessionID uuid.UUID) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b)
+	err := s.tokens.InsertOne(ctx, &repositories.RefreshToken{ID: uuid.New(), SessionID: sessionID, Hash: hashToken(raw)})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func (s *Service) tokensFor(sess *repositories.Session, refresh string) (*Tokens, error) {
+	access, err := s.issuer.Issue(sess.CustomerID.String(), []string{CustomerRole}, sess.ID.String(), s.cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    s.cfg.AccessTokenTTL,
+		SessionID:    sess.ID,
+		CustomerID:   sess.CustomerID,
+	}, nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
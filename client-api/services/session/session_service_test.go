@@ -0,0 +1,167 @@
+//go:build unit
+
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/services/customer/customertest"
+	"case-itau/utils/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+type fixture struct {
+	svc      *Service
+	verifier *auth.Verifier
+	customer repositories.Customers
+}
+
+func newFixture(t *testing.T) *fixture {
+	cf := customertest.New(t)
+	cust := cf.Create(t, "John Doe", "john.doe@example.com")
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte(testSecret), 0o600))
+	jwtCfg := auth.Config{Algorithm: auth.HS256, SecretFile: secretFile}
+	issuer, err := auth.NewIssuer(jwtCfg)
+	require.NoError(t, err)
+	verifier, err := auth.NewVerifier(jwtCfg)
+	require.NoError(t, err)
+
+	svc := NewService(cf.Tx, cf.Customers,
+		repositories.NewGormRepository[repositories.Credential](cf.DB),
+		repositories.NewGormRepository[repositories.Session](cf.DB),
+		repositories.NewGormRepository[repositories.RefreshToken](cf.DB),
+		issuer,
+		Config{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, MaxFailedAttempts: 3, LockoutDuration: time.Minute},
+	)
+	require.NoError(t, svc.SetPassword(context.Background(), cust.ID.String(), "", "correct-horse", false))
+	return &fixture{svc: svc, verifier: verifier, customer: cust}
+}
+
+func TestCases_Session_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success logging in issues a customer token", testLogin},
+		{"Success rotating refresh tokens and revoking on reuse", testRefreshRotation},
+		{"Failure logging in locks the account after repeated failures", testLockout},
+		{"Failure guessing passwords in parallel past the lockout", testConcurrentLockout},
+		{"Failure changing the own password without the current one", testSelfServicePassword},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testLogin(t *testing.T) {
+	t.Log("testLogin - Testing that the access token names the customer and the session")
+	f := newFixture(t)
+	tokens, err := f.svc.Login(context.Background(), "john.doe@example.com", "correct-horse", "127.0.0.1", "test")
+	require.NoError(t, err)
+
+	p, err := f.verifier.Verify(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, f.customer.ID.String(), p.Subject)
+	assert.Equal(t, []string{CustomerRole}, p.Roles)
+	assert.Equal(t, tokens.SessionID.String(), p.SessionID)
+
+	_, err = f.svc.Login(context.Background(), "nobody@example.com", "correct-horse", "", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func testRefreshRotation(t *testing.T) {
+	t.Log("testRefreshRotation - Testing that a refresh token works once and its reuse ends the session")
+	f := newFixture(t)
+	ctx := context.Background()
+	first, err := f.svc.Login(ctx, "john.doe@example.com", "correct-horse", "", "")
+	require.NoError(t, err)
+
+	second, err := f.svc.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.Equal(t, first.SessionID, second.SessionID)
+
+	_, err = f.svc.Refresh(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = f.svc.Refresh(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	active, err := f.svc.Active(ctx, second.SessionID.String())
+	assert.NoError(t, err)
+	assert.False(t, active)
+}
+
+func testLockout(t *testing.T) {
+	t.Log("testLockout - Testing that the third wrong password locks out even the right one")
+	f := newFixture(t)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := f.svc.Login(ctx, "john.doe@example.com", "wrong", "", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+	_, err := f.svc.Login(ctx, "john.doe@example.com", "wrong", "", "")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	_, err = f.svc.Login(ctx, "john.doe@example.com", "correct-horse", "", "")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	require.NoError(t, f.svc.SetPassword(ctx, f.customer.ID.String(), "", "battery-staple", false))
+	_, err = f.svc.Login(ctx, "john.doe@example.com", "battery-staple", "", "")
+	assert.NoError(t, err)
+}
+
+func testConcurrentLockout(t *testing.T) {
+	t.Log("testConcurrentLockout - Testing that parallel wrong passwords are checked only up to the lockout")
+	f := newFixture(t)
+	ctx := context.Background()
+
+	const guesses = 20
+	results := make([]error, guesses)
+	var wg sync.WaitGroup
+	for i := range guesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, results[i] = f.svc.Login(ctx, "john.doe@example.com", "wrong", "", "")
+		}()
+	}
+	wg.Wait()
+
+	var checked int
+	for _, err := range results {
+		if errors.Is(err, ErrInvalidCredentials) {
+			checked++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrAccountLocked)
+	}
+	assert.Less(t, checked, 3, "the third checked password locks the account")
+	_, err := f.svc.Login(ctx, "john.doe@example.com", "correct-horse", "", "")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+}
+
+func testSelfServicePassword(t *testing.T) {
+	t.Log("testSelfServicePassword - Testing that customers must confirm their current password")
+	f := newFixture(t)
+	ctx := context.Background()
+	id := f.customer.ID.String()
+
+	assert.ErrorIs(t, f.svc.SetPassword(ctx, id, "wrong", "battery-staple", true), ErrWrongPassword)
+	assert.NoError(t, f.svc.SetPassword(ctx, id, "correct-horse", "battery-staple", true))
+}
@@ -0,0 +1,240 @@
+package stepup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/services/customer"
+	l "case-itau/utils/logger"
+	"case-itau/utils/totp"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrStepUpRequired   = errors.New("operação exige confirmação com código do autenticador")
+	ErrNotEnrolled      = errors.New("autenticador não cadastrado")
+	ErrAlreadyEnrolled  = errors.New("autenticador já cadastrado")
+	ErrInvalidCode      = errors.New("código do autenticador inválido")
+	ErrVerificationLock = errors.New("confirmação bloqueada temporariamente após códigos inválidos")
+)
+
+// skew is the number of 30 second steps a code may be off by, to tolerate
+// clock drift on the customer's device.
+const skew = 1
+
+// Config sets when a step-up is required and how guessing is throttled.
+type Config struct {
+	// Threshold is the amount above which a withdrawal needs a code.
+	Threshold         decimal.Decimal
+	Issuer            string
+	MaxFailedAttempts int
+	LockoutDuration   time.Duration
+}
+
+// Enrollment is what an authenticator app needs to start generating codes.
+type Enrollment struct {
+	Secret string
+	URI    string
+}
+
+type Service struct {
+	tx          repositories.Transactor
+	customers   *customer.Service
+	enrollments repositories.IRepository[repositories.TOTPEnrollment]
+	cfg         Config
+}
+
+func NewService(tx repositories.Transactor, customers *customer.Service, enrollments repositories.IRepository[repositories.TOTPEnrollment], cfg Config) *Service {
+	return &Service{tx: tx, customers: customers, enrollments: enrollments, cfg: cfg}
+}
+
+// Threshold returns the amount above which withdrawals need a code.
+func (s *Service) Threshold() decimal.Decimal {
+	return s.cfg.Threshold
+}
+
+// Enroll creates a new secret for a customer. It stays pending until
+// confirmed with a code, and enrolling again replaces a pending secret.
+func (s *Service) Enroll(ctx context.Context, customerID string) (*Enrollment, error) {
+	cust, err := s.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		existing, err := s.enrollments.FindOne(ctx, map[string]any{"customer_id": cust.ID})
+		switch {
+		case err == nil && existing.ConfirmedAt != nil:
+			return ErrAlreadyEnrolled
+		case err == nil:
+			if err := s.enrollments.DeleteOne(ctx, map[string]any{"customer_id": cust.ID}); err != nil {
+				return err
+			}
+		case !errors.Is(err, repositories.ErrRepoNotFound):
+			return err
+		}
+		return s.enrollments.InsertOne(ctx, &repositories.TOTPEnrollment{CustomerID: cust.ID, Secret: secret})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Enrollment{Secret: secret, URI: totp.URI(s.cfg.Issuer, cust.Email, secret)}, nil
+}
+
+// Confirm activates a pending enrollment once the customer proves their app
+// generates valid codes.
+func (s *Service) Confirm(ctx context.Context, customerID, code string) error {
+	e, err := s.find(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	if e.ConfirmedAt != nil {
+		return ErrAlreadyEnrolled
+	}
+
+	step, ok := totp.Validate(e.Secret, code, time.Now(), skew)
+	if !ok {
+		return ErrInvalidCode
+	}
+	return s.enrollments.UpdateOne(ctx, map[string]any{"customer_id": e.CustomerID}, map[string]any{
+		"confirmed_at":   time.Now(),
+		"last_used_step": step,
+	})
+}
+
+// Disable removes the authenticator of a customer. When selfService is set,
+// a confirmed authenticator can only be removed with one of its codes.
+func (s *Service) Disable(ctx context.Context, customerID, code string, selfService bool) error {
+	e, err := s.find(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	if selfService && e.ConfirmedAt != nil {
+		if code == "" {
+			return ErrStepUpRequired
+		}
+		if err := s.verify(ctx, e, code); err != nil {
+			return err
+		}
+	}
+	return s.enrollments.DeleteOne(ctx, map[string]any{"customer_id": e.CustomerID})
+}
+
+// Check lets a withdrawal of amount through when it is within the threshold
+// or comes with a valid code from the customer's confirmed authenticator.
+// Each code is accepted only once.
+func (s *Service) Check(ctx context.Context, customerID string, amount decimal.Decimal, code string) error {
+	if amount.LessThanOrEqual(s.cfg.Threshold) {
+		return nil
+	}
+	if code == "" {
+		return ErrStepUpRequired
+	}
+
+	e, err := s.find(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, ErrNotEnrolled) {
+			return errors.Join(ErrStepUpRequired, ErrNotEnrolled)
+		}
+		return err
+	}
+	if e.ConfirmedAt == nil {
+		return errors.Join(ErrStepUpRequired, ErrNotEnrolled)
+	}
+	return s.verify(ctx, e, code)
+}
+
+func (s *Service) find(ctx context.Context, customerID string) (*repositories.TOTPEnrollment, error) {
+	e, err := s.enrollments.FindOne(ctx, map[string]any{"customer_id": customerID})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// verify checks a code of a confirmed enrollment and marks its step as used.
+// Repeated invalid codes lock verification for a while, since six digits are
+// otherwise cheap to guess.
+func (s *Service) verify(ctx context.Context, e *repositories.TOTPEnrollment, code string) error {
+	now := time.Now()
+	if e.LockedUntil != nil && now.Before(*e.LockedUntil) {
+		return ErrVerificationLock
+	}
+	attempt, err := s.reserveAttempt(ctx, e.CustomerID, now)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(e.Secret, code, now, skew)
+	if !ok {
+		return s.recordFailure(ctx, e.CustomerID, attempt, now)
+	}
+	err = s.enrollments.UpdateOne(ctx, repositories.Expr("customer_id = ? AND last_used_step < ? AND (locked_until IS NULL OR locked_until <= ?)", e.CustomerID, step, now), map[string]any{
+		"last_used_step":  step,
+		"failed_attempts": 0,
+		"locked_until":    nil,
+	})
+	if errors.Is(err, repositories.ErrRepoNotFound) {
+		// The code, or a later one, was already used, or a concurrent guess
+		// locked verification.
+		return s.recordFailure(ctx, e.CustomerID, attempt, now)
+	}
+	return err
+}
+
+// reserveAttempt counts an attempt before its code is checked and returns its
+// number since the last success or lockout. Counting first means parallel
+// guesses cannot all be checked against a count none of them has raised yet:
+// at most MaxFailedAttempts are checked before verification locks.
+func (s *Service) reserveAttempt(ctx context.Context, customerID uuid.UUID, now time.Time) (int, error) {
+	var attempt int
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.enrollments.UpdateOne(ctx,
+			repositories.Expr("customer_id = ? AND failed_attempts < ? AND (locked_until IS NULL OR locked_until <= ?)", customerID, s.cfg.MaxFailedAttempts, now),
+			map[string]any{"failed_attempts": repositories.Expr("failed_attempts + 1")},
+		)
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return ErrVerificationLock
+		}
+		if err != nil {
+			return err
+		}
+		e, err := s.enrollments.FindOne(ctx, map[string]any{"customer_id": customerID})
+		if err != nil {
+			return err
+		}
+		attempt = e.FailedAttempts
+		return nil
+	})
+	return attempt, err
+}
+
+// recordFailure locks verification when the failed attempt was the last one
+// allowed.
+func (s *Service) recordFailure(ctx context.Context, customerID uuid.UUID, attempt int, now time.Time) error {
+	if attempt < s.cfg.MaxFailedAttempts {
+		return ErrInvalidCode
+	}
+	l.FromContext(ctx).Warn("step-up verification locked", zap.String("customer_id", customerID.String()))
+	err := s.enrollments.UpdateOne(ctx, map[string]any{"customer_id": customerID}, map[string]any{
+		"failed_attempts": 0,
+		"locked_until":    now.Add(s.cfg.LockoutDuration),
+	})
+	if err != nil {
+		return err
+	}
+	return ErrVerificationLock
+}
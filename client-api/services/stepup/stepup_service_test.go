@@ -0,0 +1,170 @@
+//go:build unit
+
+package stepup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/services/customer/customertest"
+	"case-itau/utils/totp"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	svc      *Service
+	customer string
+}
+
+func newFixture(t *testing.T) *fixture {
+	cf := customertest.New(t)
+	cust := cf.Create(t, "John Doe", "john.doe@example.com")
+
+	svc := NewService(cf.Tx, cf.Customers, repositories.NewGormRepository[repositories.TOTPEnrollment](cf.DB), Config{
+		Threshold:         decimal.NewFromInt(1000),
+		Issuer:            "Customer API",
+		MaxFailedAttempts: 3,
+		LockoutDuration:   time.Minute,
+	})
+	return &fixture{svc: svc, customer: cust.ID.String()}
+}
+
+// enroll enrolls and confirms an authenticator with a code from the previous
+// step, leaving the current one unused.
+func (f *fixture) enroll(t *testing.T) string {
+	enrollment, err := f.svc.Enroll(context.Background(), f.customer)
+	require.NoError(t, err)
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now())-1)
+	require.NoError(t, err)
+	require.NoError(t, f.svc.Confirm(context.Background(), f.customer, code))
+	return enrollment.Secret
+}
+
+func TestCases_StepUp_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success letting amounts within the threshold through", testWithinThreshold},
+		{"Failure checking high-value amounts without an enrolled authenticator", testNotEnrolled},
+		{"Success checking a code once and refusing its replay", testCodeReplay},
+		{"Failure locking verification after repeated invalid codes", testVerificationLock},
+		{"Failure guessing codes in parallel past the lockout", testConcurrentGuesses},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testWithinThreshold(t *testing.T) {
+	t.Log("testWithinThreshold - Testing a success clause for amounts up to the threshold, which need no code")
+	f := newFixture(t)
+	assert.NoError(t, f.svc.Check(context.Background(), f.customer, decimal.NewFromInt(1000), ""))
+}
+
+func testNotEnrolled(t *testing.T) {
+	t.Log("testNotEnrolled - Testing a failure clause for high-value amounts with a missing code or authenticator")
+	f := newFixture(t)
+	ctx := context.Background()
+
+	err := f.svc.Check(ctx, f.customer, decimal.NewFromInt(1001), "")
+	assert.ErrorIs(t, err, ErrStepUpRequired)
+
+	err = f.svc.Check(ctx, f.customer, decimal.NewFromInt(1001), "123456")
+	assert.ErrorIs(t, err, ErrStepUpRequired)
+	assert.ErrorIs(t, err, ErrNotEnrolled)
+
+	// A pending enrollment does not count until it is confirmed.
+	enrollment, err := f.svc.Enroll(ctx, f.customer)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	err = f.svc.Check(ctx, f.customer, decimal.NewFromInt(1001), code)
+	assert.ErrorIs(t, err, ErrNotEnrolled)
+}
+
+func testCodeReplay(t *testing.T) {
+	t.Log("testCodeReplay - Testing a success clause for a valid code and a failure clause for using it again")
+	f := newFixture(t)
+	ctx := context.Background()
+	secret := f.enroll(t)
+
+	_, err := f.svc.Enroll(ctx, f.customer)
+	assert.ErrorIs(t, err, ErrAlreadyEnrolled)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	assert.NoError(t, f.svc.Check(ctx, f.customer, decimal.NewFromInt(5000), code))
+	assert.ErrorIs(t, f.svc.Check(ctx, f.customer, decimal.NewFromInt(5000), code), ErrInvalidCode)
+}
+
+func testVerificationLock(t *testing.T) {
+	t.Log("testVerificationLock - Testing a failure clause for the lockout after invalid codes, which also refuses valid ones")
+	f := newFixture(t)
+	ctx := context.Background()
+	secret := f.enroll(t)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	var last error
+	for range 3 {
+		last = f.svc.Check(ctx, f.customer, decimal.NewFromInt(5000), wrong)
+		assert.True(t, errors.Is(last, ErrInvalidCode) || errors.Is(last, ErrVerificationLock))
+	}
+	assert.ErrorIs(t, last, ErrVerificationLock)
+	assert.ErrorIs(t, f.svc.Check(ctx, f.customer, decimal.NewFromInt(5000), code), ErrVerificationLock)
+}
+
+func testConcurrentGuesses(t *testing.T) {
+	t.Log("testConcurrentGuesses - Testing a failure clause for parallel guesses, of which only the allowed attempts are checked")
+	f := newFixture(t)
+	ctx := context.Background()
+	secret := f.enroll(t)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	const guesses = 20
+	results := make([]error, guesses)
+	var wg sync.WaitGroup
+	for i := range guesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = f.svc.Check(ctx, f.customer, decimal.NewFromInt(5000), wrong)
+		}()
+	}
+	wg.Wait()
+
+	var checked int
+	for _, err := range results {
+		if errors.Is(err, ErrInvalidCode) {
+			checked++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrVerificationLock)
+	}
+	assert.Less(t, checked, 3, "the third checked guess locks verification")
+	assert.ErrorIs(t, f.svc.Check(ctx, f.customer, decimal.NewFromInt(5000), code), ErrVerificationLock)
+}
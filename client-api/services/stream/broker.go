@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"case-itau/repositories"
+	"case-itau/services/events"
+
+	"github.com/google/uuid"
+)
+
+// subscriptionBuffer is how many events a subscriber may lag behind before it
+// is disconnected. Clients are expected to reconnect with Last-Event-ID.
+const subscriptionBuffer = 64
+
+// maxReplay caps how many stored events are replayed on resume.
+const maxReplay = 500
+
+var (
+	ErrTooManyConnections = errors.New("limite de conexões simultâneas atingido")
+	ErrClosed             = errors.New("servidor em desligamento")
+)
+
+// Broker fans committed outbox events out to the live streams of the customer
+// they belong to, and replays stored events to clients resuming a stream.
+type Broker struct {
+	repo         repositories.IRepository[repositories.OutboxEvent]
+	maxPerClient int
+
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[*Subscription]struct{}
+	perClient   map[string]int
+	closed      bool
+}
+
+func NewBroker(repo repositories.IRepository[repositories.OutboxEvent], maxPerClient int) *Broker {
+	return &Broker{
+		repo:         repo,
+		maxPerClient: maxPerClient,
+		subscribers:  make(map[uuid.UUID]map[*Subscription]struct{}),
+		perClient:    make(map[string]int),
+	}
+}
+
+// Subscription receives the events of one customer on C. C is closed when the
+// subscription is closed or falls too far behind.
+type Subscription struct {
+	C <-chan events.Event
+
+	ch         chan events.Event
+	broker     *Broker
+	customerID uuid.UUID
+	client     string
+	once       sync.Once
+}
+
+// Subscribe opens a stream of the events of customerID on behalf of client,
+// failing with ErrTooManyConnections once client holds maxPerClient streams.
+func (b *Broker) Subscribe(customerID uuid.UUID, client string) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrClosed
+	}
+	if b.perClient[client] >= b.maxPerClient {
+		return nil, ErrTooManyConnections
+	}
+
+	ch := make(chan events.Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, broker: b, customerID: customerID, client: client}
+	if b.subscribers[customerID] == nil {
+		b.subscribers[customerID] = make(map[*Subscription]struct{})
+	}
+	b.subscribers[customerID][sub] = struct{}{}
+	b.perClient[client]++
+	return sub, nil
+}
+
+// Close stops the subscription and releases its connection slot. It is safe to
+// call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Close ends every open stream and makes Subscribe fail with ErrClosed, so
+// that long-lived connections do not hold up a graceful shutdown.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subs := range b.subscribers {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
+
+// remove must be called with b.mu held.
+func (b *Broker) remove(s *Subscription) {
+	s.once.Do(func() {
+		delete(b.subscribers[s.customerID], s)
+		if len(b.subscribers[s.customerID]) == 0 {
+			delete(b.subscribers, s.customerID)
+		}
+		b.perClient[s.client]--
+		if b.perClient[s.client] <= 0 {
+			delete(b.perClient, s.client)
+		}
+		close(s.ch)
+	})
+}
+
+// Publish delivers e to the live subscribers of its customer without blocking.
+// Subscribers whose buffer is full are dropped.
+func (b *Broker) Publish(e events.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[e.AggregateID] {
+		select {
+		case sub.ch <- e:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Replay returns the stored events of customerID with an ID greater than
+// afterID, oldest first.
+func (b *Broker) Replay(ctx context.Context, customerID uuid.UUID, afterID uint64) ([]events.Event, error) {
+	rows, err := b.repo.Find(ctx, repositories.Expr("aggregate_id = ? AND id > ?", customerID, afterID), "id ASC", maxReplay, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]events.Event, 0, len(rows))
+	for i := range rows {
+		out = append(out, events.FromRow(&rows[i]))
+	}
+	return out, nil
+}
@@ -0,0 +1,114 @@
+//go:build unit
+
+package stream
+
+import (
+	"testing"
+
+	"case-itau/services/events"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCases_Broker_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success routing events to the customer's subscribers only", testPublishRoutesByCustomer},
+		{"Failure opening more streams than allowed per client", testConnectionCap},
+		{"Success dropping a subscriber that falls behind", testSlowSubscriberDropped},
+		{"Success ending every stream on close", testCloseEndsStreams},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testPublishRoutesByCustomer(t *testing.T) {
+	t.Log("testPublishRoutesByCustomer - Testing that a subscriber only sees events of its customer")
+	b := NewBroker(nil, 5)
+	alice, bob := uuid.New(), uuid.New()
+
+	sub, err := b.Subscribe(alice, "10.0.0.1")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	b.Publish(events.Event{ID: 1, AggregateID: bob})
+	b.Publish(events.Event{ID: 2, AggregateID: alice})
+
+	e := <-sub.C
+	assert.Equal(t, uint64(2), e.ID)
+	assert.Empty(t, sub.C)
+}
+
+func testConnectionCap(t *testing.T) {
+	t.Log("testConnectionCap - Testing that a client gets ErrTooManyConnections until it closes a stream")
+	b := NewBroker(nil, 2)
+	id := uuid.New()
+
+	first, err := b.Subscribe(id, "10.0.0.1")
+	require.NoError(t, err)
+	_, err = b.Subscribe(uuid.New(), "10.0.0.1")
+	require.NoError(t, err)
+
+	_, err = b.Subscribe(id, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrTooManyConnections)
+
+	_, err = b.Subscribe(id, "10.0.0.2")
+	assert.NoError(t, err)
+
+	first.Close()
+	first.Close()
+	_, err = b.Subscribe(id, "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+func testSlowSubscriberDropped(t *testing.T) {
+	t.Log("testSlowSubscriberDropped - Testing that a full buffer closes the subscription and frees its slot")
+	b := NewBroker(nil, 1)
+	id := uuid.New()
+
+	sub, err := b.Subscribe(id, "10.0.0.1")
+	require.NoError(t, err)
+	for i := 0; i <= subscriptionBuffer; i++ {
+		b.Publish(events.Event{ID: uint64(i + 1), AggregateID: id})
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, subscriptionBuffer, received)
+
+	_, err = b.Subscribe(id, "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+func testCloseEndsStreams(t *testing.T) {
+	t.Log("testCloseEndsStreams - Testing that Close ends open streams and refuses new ones")
+	b := NewBroker(nil, 5)
+	alice, bob := uuid.New(), uuid.New()
+
+	first, err := b.Subscribe(alice, "10.0.0.1")
+	require.NoError(t, err)
+	second, err := b.Subscribe(bob, "10.0.0.2")
+	require.NoError(t, err)
+
+	b.Close()
+
+	_, open := <-first.C
+	assert.False(t, open)
+	_, open = <-second.C
+	assert.False(t, open)
+	first.Close()
+
+	_, err = b.Subscribe(alice, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrClosed)
+}
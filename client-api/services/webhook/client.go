@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned when a delivery would connect to an address
+// inside the network the API runs in.
+var ErrForbiddenTarget = errors.New("webhook target is not a public address")
+
+// NewClient returns the HTTP client deliveries are sent with. Subscribers
+// choose their URL, so the client refuses to connect to loopback, private,
+// link-local and other non-public addresses, which would let them reach the
+// admin listener or other internal services through the API. The check runs
+// on the address actually dialled, after DNS resolution and on every redirect,
+// so neither a hostname resolving to an internal address nor a redirect to one
+// gets through. Proxies from the environment are ignored, as the check would
+// then only see the proxy.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, forbiddenAddr)
+}
+
+func newClient(timeout time.Duration, forbidden func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenTarget, address)
+			}
+			if addr := addrPort.Addr().Unmap(); forbidden(addr) {
+				return fmt.Errorf("%w: %s", ErrForbiddenTarget, addr)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// cgnat is the shared address space of carrier-grade NAT, RFC 6598.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// forbiddenAddr reports whether addr is not a public unicast address.
+func forbiddenAddr(addr netip.Addr) bool {
+	return !addr.IsGlobalUnicast() || addr.IsPrivate() || cgnat.Contains(addr)
+}
@@ -0,0 +1,225 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/services/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+
+	// AllEvents subscribes to every event type.
+	AllEvents = "*"
+)
+
+var (
+	ErrNotFound         = errors.New("webhook não encontrado")
+	ErrDeliveryNotFound = errors.New("entrega não encontrada")
+)
+
+// Service manages webhook subscriptions and fans outbox events out into
+// deliveries. It implements events.Publisher.
+type Service struct {
+	subs       repositories.IRepository[repositories.WebhookSubscription]
+	deliveries repositories.IRepository[repositories.WebhookDelivery]
+	worker     *Worker
+}
+
+func NewService(subs repositories.IRepository[repositories.WebhookSubscription], deliveries repositories.IRepository[repositories.WebhookDelivery], worker *Worker) *Service {
+	return &Service{subs: subs, deliveries: deliveries, worker: worker}
+}
+
+// Create registers a subscription. A random secret is generated when none is
+// given; it is only ever returned by this call.
+func (s *Service) Create(ctx context.Context, url string, eventTypes []string, secret string) (*repositories.WebhookSubscription, error) {
+	if secret == "" {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := &repositories.WebhookSubscription{
+		ID:         uuid.New(),
+		URL:        url,
+		EventTypes: strings.Join(eventTypes, ","),
+		Secret:     secret,
+		Active:     true,
+	}
+	if err := s.subs.InsertOne(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) List(ctx context.Context) ([]repositories.WebhookSubscription, error) {
+	return s.subs.Find(ctx, nil, "created_at ASC", 0, 0)
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*repositories.WebhookSubscription, error) {
+	sub, err := s.subs.FindOne(ctx, map[string]any{"id": id})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Update replaces the URL, event types and active flag of a subscription, and
+// rotates the secret when a new one is given.
+func (s *Service) Update(ctx context.Context, id string, url string, eventTypes []string, secret string, active bool) (*repositories.WebhookSubscription, error) {
+	updates := map[string]any{
+		"url":         url,
+		"event_types": strings.Join(eventTypes, ","),
+		"active":      active,
+	}
+	if secret != "" {
+		updates["secret"] = secret
+	}
+
+	if err := s.subs.UpdateOne(ctx, map[string]any{"id": id}, updates); err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+// Delete removes a subscription. Its pending deliveries are dropped by the
+// worker when it finds the subscription gone.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.subs.DeleteOne(ctx, map[string]any{"id": id}); err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries returns a page of the deliveries of a subscription, newest
+// first, optionally filtered by status.
+func (s *Service) ListDeliveries(ctx context.Context, subscriptionID, status string, page, size int) ([]repositories.WebhookDelivery, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 10
+	}
+
+	offset := (page - 1) * size
+
+	where := map[string]any{"subscription_id": subscriptionID}
+	if status != "" {
+		where["status"] = status
+	}
+
+	total, err := s.deliveries.Count(ctx, where)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list, err := s.deliveries.Find(ctx, where, "created_at DESC", size, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return list, total, nil
+}
+
+// Replay schedules a delivery to be sent again right away with a fresh set of
+// attempts, whatever its current status.
+func (s *Service) Replay(ctx context.Context, subscriptionID, deliveryID string) (*repositories.WebhookDelivery, error) {
+	where := map[string]any{"id": deliveryID, "subscription_id": subscriptionID}
+	err := s.deliveries.UpdateOne(ctx, where, map[string]any{
+		"status":          StatusPending,
+		"attempts":        0,
+		"last_error":      "",
+		"next_attempt_at": time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrRepoNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	s.worker.Notify()
+	return s.deliveries.FindOne(ctx, where)
+}
+
+// Publish creates a pending delivery of e for every active subscription that
+// asked for its type. The outbox publishes an event again when an attempt
+// fails, so deliveries already created for it are kept rather than
+// duplicated.
+func (s *Service) Publish(ctx context.Context, e events.Event) error {
+	subs, err := s.subs.Find(ctx, map[string]any{"active": true}, "", 0, 0)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	created := false
+	defer func() {
+		if created {
+			s.worker.Notify()
+		}
+	}()
+	for _, sub := range subs {
+		if !Subscribed(sub.EventTypes, e.Type) {
+			continue
+		}
+		d := &repositories.WebhookDelivery{
+			ID:             uuid.New(),
+			SubscriptionID: sub.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        string(payload),
+			Status:         StatusPending,
+			NextAttemptAt:  time.Now(),
+		}
+		err := s.deliveries.InsertOne(ctx, d)
+		if errors.Is(err, repositories.ErrRepoDuplicate) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		created = true
+	}
+	return nil
+}
+
+// Subscribed reports whether a comma separated list of event types includes
+// eventType, either by name or through the "*" wildcard.
+func Subscribed(eventTypes, eventType string) bool {
+	types := strings.Split(eventTypes, ",")
+	return slices.Contains(types, AllEvents) || slices.Contains(types, eventType)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
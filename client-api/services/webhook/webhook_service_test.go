@@ -0,0 +1,263 @@
+//go:build unit
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/repositories/connection"
+	"case-itau/services/events"
+	l "case-itau/utils/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// receiver is a local subscriber that checks signatures and can be told to
+// fail a number of times before accepting deliveries.
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	valid    int
+	invalid  int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	timestamp, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if !Verify(r.secret, timestamp, body, req.Header.Get(HeaderSignature)) {
+		r.invalid++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	r.valid++
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type fixture struct {
+	svc        *Service
+	worker     *Worker
+	deliveries repositories.IRepository[repositories.WebhookDelivery]
+}
+
+func newFixture(t *testing.T, maxAttempts int) *fixture {
+	l.Logger = zap.NewNop()
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "webhooks.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&repositories.WebhookSubscription{}, &repositories.WebhookDelivery{}))
+
+	subs := repositories.NewGormRepository[repositories.WebhookSubscription](db)
+	deliveries := repositories.NewGormRepository[repositories.WebhookDelivery](db)
+	worker := NewWorker(subs, deliveries, &http.Client{Timeout: time.Second}, time.Second, maxAttempts)
+	return &fixture{
+		svc:        NewService(subs, deliveries, worker),
+		worker:     worker,
+		deliveries: deliveries,
+	}
+}
+
+func (f *fixture) publish(t *testing.T, eventType string) {
+	require.NoError(t, f.svc.Publish(context.Background(), events.Event{ID: 1, Type: eventType, AggregateID: uuid.New(), Payload: []byte(`{}`)}))
+}
+
+// makeDue moves every pending retry to now so the next run picks it up.
+func (f *fixture) makeDue(t *testing.T) {
+	require.NoError(t, f.deliveries.UpdateOne(context.Background(), map[string]any{"status": StatusPending}, map[string]any{"next_attempt_at": time.Now()}))
+}
+
+func TestCases_Webhook_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success delivering a signed event to a subscriber", testDeliverSigned},
+		{"Success skipping subscriptions to other event types", testFanOutFiltersTypes},
+		{"Success publishing an event again without duplicating deliveries", testPublishAgain},
+		{"Success retrying a failed delivery", testRetryThenDeliver},
+		{"Success dead-lettering and replaying a delivery", testDeadLetterAndReplay},
+		{"Failure classifying internal addresses as delivery targets", testForbiddenAddr},
+		{"Failure delivering to a loopback subscriber", testDeliverToLoopback},
+		{"Failure following a redirect to a forbidden address", testRedirectToForbiddenTarget},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testDeliverSigned(t *testing.T) {
+	t.Log("testDeliverSigned - Testing that the receiver gets a request with a valid signature")
+	rcv := &receiver{secret: "0123456789abcdef0123"}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	f := newFixture(t, 3)
+	_, err := f.svc.Create(context.Background(), srv.URL, []string{AllEvents}, rcv.secret)
+	require.NoError(t, err)
+
+	f.publish(t, events.TypeDepositBooked)
+	f.worker.deliverDue(context.Background())
+
+	assert.Equal(t, 1, rcv.valid)
+	assert.Equal(t, 0, rcv.invalid)
+	delivered, err := f.deliveries.Count(context.Background(), map[string]any{"status": StatusDelivered})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), delivered)
+}
+
+func testFanOutFiltersTypes(t *testing.T) {
+	t.Log("testFanOutFiltersTypes - Testing that only matching subscriptions get a delivery")
+	f := newFixture(t, 3)
+	_, err := f.svc.Create(context.Background(), "http://127.0.0.1:1/a", []string{events.TypeCustomerCreated}, "")
+	require.NoError(t, err)
+	_, err = f.svc.Create(context.Background(), "http://127.0.0.1:1/b", []string{events.TypeDepositBooked, events.TypeWithdrawalBooked}, "")
+	require.NoError(t, err)
+
+	f.publish(t, events.TypeWithdrawalBooked)
+
+	total, err := f.deliveries.Count(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+func testPublishAgain(t *testing.T) {
+	t.Log("testPublishAgain - Testing that an event retried by the outbox keeps a single delivery per subscription")
+	f := newFixture(t, 3)
+	_, err := f.svc.Create(context.Background(), "http://127.0.0.1:1/a", []string{AllEvents}, "")
+	require.NoError(t, err)
+
+	f.publish(t, events.TypeDepositBooked)
+	_, err = f.svc.Create(context.Background(), "http://127.0.0.1:1/b", []string{AllEvents}, "")
+	require.NoError(t, err)
+	f.publish(t, events.TypeDepositBooked)
+
+	total, err := f.deliveries.Count(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total, "the retry only adds the delivery of the new subscription")
+}
+
+func testRetryThenDeliver(t *testing.T) {
+	t.Log("testRetryThenDeliver - Testing that a delivery rejected once succeeds on the next attempt")
+	rcv := &receiver{secret: "0123456789abcdef0123", failures: 1}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	f := newFixture(t, 3)
+	sub, err := f.svc.Create(context.Background(), srv.URL, []string{AllEvents}, rcv.secret)
+	require.NoError(t, err)
+
+	f.publish(t, events.TypeCustomerCreated)
+	f.worker.deliverDue(context.Background())
+
+	list, _, err := f.svc.ListDeliveries(context.Background(), sub.ID.String(), StatusPending, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, http.StatusServiceUnavailable, list[0].LastStatusCode)
+	assert.True(t, list[0].NextAttemptAt.After(time.Now()))
+
+	f.makeDue(t)
+	f.worker.deliverDue(context.Background())
+	assert.Equal(t, 1, rcv.valid)
+}
+
+func testDeadLetterAndReplay(t *testing.T) {
+	t.Log("testDeadLetterAndReplay - Testing that a delivery goes to the dead-letter list and can be replayed")
+	rcv := &receiver{secret: "0123456789abcdef0123", failures: 2}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	f := newFixture(t, 2)
+	sub, err := f.svc.Create(context.Background(), srv.URL, []string{AllEvents}, rcv.secret)
+	require.NoError(t, err)
+
+	f.publish(t, events.TypeCustomerDeleted)
+	f.worker.deliverDue(context.Background())
+	f.makeDue(t)
+	f.worker.deliverDue(context.Background())
+
+	dead, _, err := f.svc.ListDeliveries(context.Background(), sub.ID.String(), StatusDead, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 2, dead[0].Attempts)
+
+	_, err = f.svc.Replay(context.Background(), sub.ID.String(), dead[0].ID.String())
+	require.NoError(t, err)
+	f.worker.deliverDue(context.Background())
+	assert.Equal(t, 1, rcv.valid)
+}
+
+func testForbiddenAddr(t *testing.T) {
+	t.Log("testForbiddenAddr - Testing a failure clause for loopback, private, link-local and unspecified addresses")
+	for _, raw := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.0.10", "169.254.169.254", "fe80::1", "fc00::1", "0.0.0.0", "::", "100.64.0.1", "224.0.0.1", "255.255.255.255"} {
+		assert.True(t, forbiddenAddr(netip.MustParseAddr(raw)), raw)
+	}
+	for _, raw := range []string{"8.8.8.8", "203.0.114.1", "2001:4860:4860::8888"} {
+		assert.False(t, forbiddenAddr(netip.MustParseAddr(raw)), raw)
+	}
+}
+
+func testDeliverToLoopback(t *testing.T) {
+	t.Log("testDeliverToLoopback - Testing a failure clause for a subscriber URL pointing at the API host")
+	rcv := &receiver{secret: "0123456789abcdef0123"}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	f := newFixture(t, 3)
+	f.worker.client = NewClient(time.Second)
+	sub, err := f.svc.Create(context.Background(), srv.URL, []string{AllEvents}, rcv.secret)
+	require.NoError(t, err)
+
+	f.publish(t, events.TypeDepositBooked)
+	f.worker.deliverDue(context.Background())
+
+	assert.Equal(t, 0, rcv.valid+rcv.invalid, "the subscriber is never reached")
+	pending, _, err := f.svc.ListDeliveries(context.Background(), sub.ID.String(), StatusPending, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Contains(t, pending[0].LastError, ErrForbiddenTarget.Error())
+}
+
+func testRedirectToForbiddenTarget(t *testing.T) {
+	t.Log("testRedirectToForbiddenTarget - Testing a failure clause for an allowed subscriber redirecting to a forbidden address")
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("the redirect target was reached")
+	}))
+	defer internal.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "http://127.0.0.2:"+strconv.Itoa(internal.Listener.Addr().(*net.TCPAddr).Port)+"/", http.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+
+	blocked := netip.MustParseAddr("127.0.0.2")
+	client := newClient(time.Second, func(addr netip.Addr) bool { return addr == blocked })
+	resp, err := client.Post(redirect.URL, "application/json", nil)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	assert.ErrorIs(t, err, ErrForbiddenTarget)
+}
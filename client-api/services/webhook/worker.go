@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"case-itau/repositories"
+	"case-itau/services/events"
+	l "case-itau/utils/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	deliveryBatchSize = 50
+	maxRetryBackoff   = 30 * time.Minute
+)
+
+// Worker POSTs pending deliveries to their subscribers. A delivery succeeds on
+// any 2xx answer; anything else is retried with exponential backoff until
+// maxAttempts is reached, at which point the delivery is dead-lettered.
+type Worker struct {
+	subs        repositories.IRepository[repositories.WebhookSubscription]
+	deliveries  repositories.IRepository[repositories.WebhookDelivery]
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	wake        chan struct{}
+}
+
+func NewWorker(subs repositories.IRepository[repositories.WebhookSubscription], deliveries repositories.IRepository[repositories.WebhookDelivery], client *http.Client, interval time.Duration, maxAttempts int) *Worker {
+	return &Worker{
+		subs:        subs,
+		deliveries:  deliveries,
+		client:      client,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes the worker up without waiting for the next poll.
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// deliverDue sends every pending delivery whose next attempt is due.
+func (w *Worker) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		pending, err := w.deliveries.Find(ctx, map[string]any{"status": StatusPending}, "next_attempt_at ASC", deliveryBatchSize, 0)
+		if err != nil {
+			l.Logger.Error("failed to load webhook deliveries", zap.Error(err))
+			return
+		}
+
+		for i := range pending {
+			if pending[i].NextAttemptAt.After(time.Now()) {
+				return
+			}
+			if err := w.attempt(ctx, &pending[i]); err != nil {
+				l.Logger.Error("failed to record webhook delivery attempt", zap.String("delivery_id", pending[i].ID.String()), zap.Error(err))
+				return
+			}
+		}
+
+		if len(pending) < deliveryBatchSize {
+			return
+		}
+	}
+}
+
+// attempt sends a delivery once and stores the outcome.
+func (w *Worker) attempt(ctx context.Context, d *repositories.WebhookDelivery) error {
+	where := map[string]any{"id": d.ID.String()}
+
+	sub, err := w.subs.FindOne(ctx, map[string]any{"id": d.SubscriptionID.String()})
+	if errors.Is(err, repositories.ErrRepoNotFound) {
+		return w.deliveries.UpdateOne(ctx, where, map[string]any{"status": StatusDead, "last_error": "subscription removed"})
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	attempts := d.Attempts + 1
+	code, sendErr := w.send(ctx, sub, d)
+
+	updates := map[string]any{"attempts": attempts, "last_status_code": code}
+	switch {
+	case sendErr == nil:
+		updates["status"] = StatusDelivered
+		updates["last_error"] = ""
+		updates["delivered_at"] = now
+	case attempts >= w.maxAttempts:
+		l.Logger.Warn("webhook delivery dead-lettered",
+			zap.String("delivery_id", d.ID.String()), zap.String("url", sub.URL), zap.Int("attempts", attempts), zap.Error(sendErr))
+		updates["status"] = StatusDead
+		updates["last_error"] = sendErr.Error()
+	default:
+		updates["last_error"] = sendErr.Error()
+		updates["next_attempt_at"] = now.Add(events.Backoff(attempts, maxRetryBackoff))
+	}
+	return w.deliveries.UpdateOne(ctx, where, updates)
+}
+
+// send POSTs the payload and returns the response status code, if any.
+func (w *Worker) send(ctx context.Context, sub *repositories.WebhookSubscription, d *repositories.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber answered with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
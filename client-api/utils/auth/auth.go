@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+var (
+	ErrMissingToken = errors.New("token de acesso ausente")
+	ErrTokenExpired = errors.New("token de acesso expirado")
+	ErrInvalidToken = errors.New("token de acesso inválido")
+)
+
+// Config selects the signing algorithm and where its keys come from. HS256
+// reads the shared secret from SecretFile; RS256 reads a PEM public key from
+// PublicKeyFile or a key set from JWKSPath, which may be a file or an URL.
+// PrivateKeyFile and KeyID are only needed for the API to issue RS256 tokens.
+type Config struct {
+	Algorithm      string `yaml:"algorithm" env:"JWT_ALGORITHM"`
+	SecretFile     string `yaml:"secret_file" env:"JWT_SECRET_FILE"`
+	PublicKeyFile  string `yaml:"public_key_file" env:"JWT_PUBLIC_KEY_FILE"`
+	JWKSPath       string `yaml:"jwks_path" env:"JWT_JWKS_PATH"`
+	PrivateKeyFile string `yaml:"private_key_file" env:"JWT_PRIVATE_KEY_FILE"`
+	KeyID          string `yaml:"key_id" env:"JWT_KEY_ID"`
+	Issuer         string `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience       string `yaml:"audience" env:"JWT_AUDIENCE"`
+}
+
+// Claims are the token claims understood by the API.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles     []string `json:"roles,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+}
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Subject   string
+	Roles     []string
+	SessionID string
+}
+
+type Verifier struct {
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{cfg.Algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	v := &Verifier{parser: jwt.NewParser(opts...)}
+	switch cfg.Algorithm {
+	case HS256:
+		secret, err := LoadSecret(cfg.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keyFunc = func(*jwt.Token) (any, error) { return secret, nil }
+	case RS256:
+		switch {
+		case cfg.JWKSPath != "":
+			keys := newKeySet(cfg.JWKSPath)
+			if err := keys.load(); err != nil {
+				return nil, err
+			}
+			v.keyFunc = keys.keyFunc
+		case cfg.PublicKeyFile != "":
+			key, err := loadPublicKey(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			v.keyFunc = func(*jwt.Token) (any, error) { return key, nil }
+		default:
+			return nil, errors.New("RS256 requires a public key file or a JWKS path")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+	return v, nil
+}
+
+// Verify parses a compact JWT and returns the caller it identifies.
+func (v *Verifier) Verify(token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrMissingToken
+	}
+
+	var claims Claims
+	if _, err := v.parser.ParseWithClaims(token, &claims, v.keyFunc); err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return Principal{}, ErrTokenExpired
+		}
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	return Principal{Subject: claims.Subject, Roles: claims.Roles, SessionID: claims.SessionID}, nil
+}
+
+// Issuer mints the access tokens handed out by the API itself.
+type Issuer struct {
+	algorithm string
+	key       any
+	kid       string
+	issuer    string
+	audience  string
+}
+
+// NewIssuer loads the signing key described by cfg: the shared secret for
+// HS256, or PrivateKeyFile for RS256.
+func NewIssuer(cfg Config) (*Issuer, error) {
+	i := &Issuer{algorithm: cfg.Algorithm, kid: cfg.KeyID, issuer: cfg.Issuer, audience: cfg.Audience}
+	var err error
+	switch cfg.Algorithm {
+	case HS256:
+		i.key, err = LoadSecret(cfg.SecretFile)
+	case RS256:
+		if cfg.PrivateKeyFile == "" {
+			return nil, errors.New("issuing RS256 tokens requires a private key file")
+		}
+		i.key, err = LoadPrivateKey(cfg.PrivateKeyFile)
+	default:
+		err = fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Issue returns a token for subject valid for ttl, bound to sessionID.
+func (i *Issuer) Issue(subject string, roles []string, sessionID string, ttl time.Duration) (string, error) {
+	claims := newClaims(subject, roles, ttl)
+	claims.Issuer = i.issuer
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+	claims.SessionID = sessionID
+	return sign(i.algorithm, i.key, i.kid, claims)
+}
+
+// Sign mints a token for subject, naming kid in its header when set. It backs
+// the local token command and the tests.
+func Sign(algorithm string, key any, kid, subject string, roles []string, ttl time.Duration) (string, error) {
+	return sign(algorithm, key, kid, newClaims(subject, roles, ttl))
+}
+
+func newClaims(subject string, roles []string, ttl time.Duration) Claims {
+	now := time.Now()
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Roles: roles,
+	}
+}
+
+func sign(algorithm string, key any, kid string, claims Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(algorithm), claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	return token.SignedString(key)
+}
+
+// LoadSecret reads an HS256 secret, ignoring surrounding whitespace.
+func LoadSecret(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("HS256 requires a secret file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret := []byte(strings.TrimSpace(string(data)))
+	if len(secret) < 32 {
+		return nil, errors.New("HS256 secret must have at least 32 bytes")
+	}
+	return secret, nil
+}
+
+// LoadPrivateKey reads a PEM encoded RSA private key.
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.ParseRSAPrivateKeyFromPEM(data)
+}
+
+func loadPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.ParseRSAPublicKeyFromPEM(data)
+}
@@ -0,0 +1,102 @@
+//go:build unit
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestCases_Verifier_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success verifying an HS256 token", testVerifyHS256},
+		{"Failure verifying expired, missing and tampered tokens", testVerifyRejects},
+		{"Success verifying an RS256 token against a JWKS file", testVerifyJWKS},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func hsVerifier(t *testing.T) *Verifier {
+	v, err := NewVerifier(Config{Algorithm: HS256, SecretFile: writeFile(t, "secret", []byte(testSecret+"\n"))})
+	require.NoError(t, err)
+	return v
+}
+
+func testVerifyHS256(t *testing.T) {
+	t.Log("testVerifyHS256 - Testing a success clause for subject and roles extraction")
+	token, err := Sign(HS256, []byte(testSecret), "", "alice", []string{"admin"}, time.Minute)
+	require.NoError(t, err)
+
+	p, err := hsVerifier(t).Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{Subject: "alice", Roles: []string{"admin"}}, p)
+}
+
+func testVerifyRejects(t *testing.T) {
+	t.Log("testVerifyRejects - Testing a failure clause for each kind of unusable token")
+	v := hsVerifier(t)
+
+	_, err := v.Verify("")
+	assert.ErrorIs(t, err, ErrMissingToken)
+
+	expired, _ := Sign(HS256, []byte(testSecret), "", "alice", nil, -time.Hour)
+	_, err = v.Verify(expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	other, _ := Sign(HS256, []byte("another-secret-another-secret-xx"), "", "alice", nil, time.Minute)
+	_, err = v.Verify(other)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func testVerifyJWKS(t *testing.T) {
+	t.Log("testVerifyJWKS - Testing a success clause for picking the key named by kid")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	doc, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kid": "k1",
+		"kty": "RSA",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+
+	v, err := NewVerifier(Config{Algorithm: RS256, JWKSPath: writeFile(t, "jwks.json", doc)})
+	require.NoError(t, err)
+
+	token, err := Sign(RS256, key, "k1", "bob", nil, time.Minute)
+	require.NoError(t, err)
+	p, err := v.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", p.Subject)
+
+	hs, _ := Sign(HS256, []byte(testSecret), "", "bob", nil, time.Minute)
+	_, err = v.Verify(hs)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
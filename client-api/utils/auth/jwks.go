@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksRefreshInterval throttles reloads triggered by tokens with unknown key
+// IDs, so forged kids cannot make the API hammer the key server.
+const jwksRefreshInterval = time.Minute
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet holds the RSA keys of a JWKS document. The document is reloaded when
+// a token names a key that is not known yet, which covers key rotation.
+type keySet struct {
+	path   string
+	client *http.Client
+
+	mu       sync.RWMutex
+	keys     map[string]*rsa.PublicKey
+	loadedAt time.Time
+}
+
+func newKeySet(path string) *keySet {
+	return &keySet{path: path, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *keySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > jwksRefreshInterval
+	s.mu.RUnlock()
+	if stale {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		if key, ok := s.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup finds the key for kid. Tokens without a kid are accepted only when
+// the set holds a single key.
+func (s *keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *keySet) load() error {
+	data, err := s.read()
+	if err != nil {
+		return fmt.Errorf("read JWKS: %w", err)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := rsaKey(k)
+		if err != nil {
+			return fmt.Errorf("parse JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no RSA signing keys")
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *keySet) read() ([]byte, error) {
+	if !strings.HasPrefix(s.path, "http://") && !strings.HasPrefix(s.path, "https://") {
+		return os.ReadFile(s.path)
+	}
+
+	resp, err := s.client.Get(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -1,16 +1,24 @@
 package logger
 
 import (
-	"os"
+	"context"
+	"strings"
 
 	"go.uber.org/zap"
 )
 
 var Logger *zap.Logger
 
-func NewLogger() {
+// Level is the level of Logger. It can be changed at runtime, and serves GET
+// and PUT requests to read and change it as an http.Handler.
+var Level zap.AtomicLevel
+
+// NewLogger builds the global Logger at level, one of DEBUG, INFO, WARN or
+// ERROR in any case.
+func NewLogger(level string) {
 	config := zap.NewProductionConfig()
-	config.Level = getLevel()
+	Level = getLevel(level)
+	config.Level = Level
 	buildConfig, err := config.Build()
 	if err != nil {
 		panic(err)
@@ -19,9 +27,8 @@ func NewLogger() {
 	Logger = buildConfig
 }
 
-func getLevel() zap.AtomicLevel {
-	level := os.Getenv("LOG_LEVEL")
-	switch level {
+func getLevel(level string) zap.AtomicLevel {
+	switch strings.ToUpper(level) {
 	case "DEBUG":
 		return zap.NewAtomicLevelAt(zap.DebugLevel)
 	case "WARN":
@@ -34,3 +41,20 @@ func getLevel() zap.AtomicLevel {
 		return zap.NewAtomicLevelAt(zap.InfoLevel)
 	}
 }
+
+type loggerKey struct{}
+
+// WithContext returns a copy of ctx carrying lg, usually the logger of the
+// request being served with its request ID attached.
+func WithContext(ctx context.Context, lg *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, lg)
+}
+
+// FromContext returns the logger stored in ctx, or the global Logger for
+// contexts that did not go through the HTTP middleware.
+func FromContext(ctx context.Context) *zap.Logger {
+	if lg, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return lg
+	}
+	return Logger
+}
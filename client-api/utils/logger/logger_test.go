@@ -0,0 +1,55 @@
+//go:build unit
+
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCases_Logger_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success falling back to the global logger", testFromContextFallback},
+		{"Success reading the request logger from the context", testFromContextRequestLogger},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testFromContextFallback(t *testing.T) {
+	t.Log("testFromContextFallback - Testing a success clause for a context without a request logger")
+	previous := Logger
+	t.Cleanup(func() { Logger = previous })
+	Logger = zap.NewNop()
+
+	assert.Same(t, Logger, FromContext(context.Background()))
+}
+
+func testFromContextRequestLogger(t *testing.T) {
+	t.Log("testFromContextRequestLogger - Testing a success clause for lines carrying the request fields")
+	core, logs := observer.New(zapcore.InfoLevel)
+	lg := zap.New(core).With(zap.String("request_id", "req-1"))
+
+	ctx := WithContext(context.Background(), lg)
+	FromContext(ctx).Info("customer created", zap.String("customer_id", "c-1"))
+
+	entries := logs.All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, "c-1", fields["customer_id"])
+	}
+}
@@ -0,0 +1,79 @@
+// Package metrics holds the Prometheus collectors exposed on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/shopspring/decimal"
+)
+
+// Registry holds every collector of the API along with the Go runtime and
+// process ones. A dedicated registry keeps metrics registered by libraries on
+// the default one out of the output.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to serve HTTP requests, by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Time taken by database statements, by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "table"})
+
+	Transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "customer_api_transactions_total",
+		Help: "Committed deposits and withdrawals, by type.",
+	}, []string{"type"})
+
+	TransactionAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "customer_api_transaction_amount_total",
+		Help: "Sum of the amounts of committed deposits and withdrawals, by type.",
+	}, []string{"type"})
+
+	InsufficientFunds = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "customer_api_insufficient_funds_total",
+		Help: "Withdrawals rejected for insufficient balance.",
+	})
+
+	CustomersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "customer_api_customers_created_total",
+		Help: "Customers created.",
+	})
+
+	CacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "customer_api_cache_lookups_total",
+		Help: "Lookups served by the repository cache, by cache and result: hit, miss or error.",
+	}, []string{"cache", "result"})
+
+	CacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "customer_api_cache_evictions_total",
+		Help: "Entries the in-memory cache dropped to stay within its size.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration, DBQueryDuration,
+		Transactions, TransactionAmount, InsufficientFunds, CustomersCreated,
+		CacheLookups, CacheEvictions,
+	)
+}
+
+// RecordTransaction counts a committed deposit or withdrawal. Amounts are
+// recorded as positive values for both types.
+func RecordTransaction(transactionType string, amount decimal.Decimal) {
+	Transactions.WithLabelValues(transactionType).Inc()
+	TransactionAmount.WithLabelValues(transactionType).Add(amount.Abs().InexactFloat64())
+}
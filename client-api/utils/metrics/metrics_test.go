@@ -0,0 +1,84 @@
+//go:build unit
+
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+
+	"case-itau/repositories/connection"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sample struct {
+	ID   int
+	Name string
+}
+
+func TestCases_Metrics_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success timing statements through the GORM plugin", testGormPlugin},
+		{"Success counting transactions with positive amounts", testRecordTransaction},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+// observations returns how many times the DBQueryDuration series with the
+// given labels was observed.
+func observations(t *testing.T, operation, table string) uint64 {
+	families, err := Registry.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != "db_query_duration_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			if labels["operation"] == operation && labels["table"] == table {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+func testGormPlugin(t *testing.T) {
+	t.Log("testGormPlugin - Testing a success clause for statements observed by operation and table")
+	db, err := connection.NewSqliteConnection(filepath.Join(t.TempDir(), "metrics.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin{}))
+	require.NoError(t, db.AutoMigrate(&sample{}))
+
+	require.NoError(t, db.Create(&sample{ID: 1, Name: "a"}).Error)
+	var out []sample
+	require.NoError(t, db.Find(&out).Error)
+	require.NoError(t, db.Find(&out).Error)
+
+	assert.Equal(t, uint64(1), observations(t, "create", "samples"))
+	assert.Equal(t, uint64(2), observations(t, "query", "samples"))
+}
+
+func testRecordTransaction(t *testing.T) {
+	t.Log("testRecordTransaction - Testing a success clause for withdrawals being summed as positive amounts")
+	RecordTransaction("withdraw", decimal.RequireFromString("-10.50"))
+	RecordTransaction("withdraw", decimal.RequireFromString("-4.50"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(Transactions.WithLabelValues("withdraw")))
+	assert.Equal(t, 15.0, testutil.ToFloat64(TransactionAmount.WithLabelValues("withdraw")))
+}
@@ -0,0 +1,99 @@
+package rbac
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Permissions checked by the API routes.
+const (
+	CustomersList         = "customers:list"
+	CustomersRead         = "customers:read"
+	CustomersCreate       = "customers:create"
+	CustomersUpdate       = "customers:update"
+	CustomersDelete       = "customers:delete"
+	CustomersDeposit      = "customers:deposit"
+	CustomersWithdraw     = "customers:withdraw"
+	CustomersTransactions = "customers:transactions"
+	CustomersAudit        = "customers:audit"
+	CustomersEvents       = "customers:events"
+	CustomersPassword     = "customers:password"
+	CustomersTOTP         = "customers:totp"
+	TransactionsImport    = "transactions:import"
+	WebhooksManage        = "webhooks:manage"
+	APIKeysManage         = "api_keys:manage"
+	MetricsRead           = "metrics:read"
+)
+
+// Permissions lists every permission, so that policies naming unknown ones are
+// rejected at startup instead of silently granting nothing.
+var Permissions = []string{
+	CustomersList, CustomersRead, CustomersCreate, CustomersUpdate, CustomersDelete,
+	CustomersDeposit, CustomersWithdraw, CustomersTransactions, CustomersAudit,
+	CustomersEvents, CustomersPassword, CustomersTOTP, TransactionsImport, WebhooksManage,
+	APIKeysManage, MetricsRead,
+}
+
+// Wildcard grants every permission.
+const Wildcard = "*"
+
+//go:embed default_policy.yaml
+var defaultPolicy []byte
+
+// Policy is the role to permission matrix.
+type Policy struct {
+	Roles       map[string][]string `yaml:"roles"`
+	OwnerScoped []string            `yaml:"owner_scoped"`
+}
+
+// Load reads the policy at path, or the built-in policy when path is empty.
+func Load(path string) (*Policy, error) {
+	data := defaultPolicy
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse RBAC policy: %w", err)
+	}
+	for role, perms := range p.Roles {
+		for _, perm := range perms {
+			if perm != Wildcard && !slices.Contains(Permissions, perm) {
+				return nil, fmt.Errorf("role %q grants unknown permission %q", role, perm)
+			}
+		}
+	}
+	for _, role := range p.OwnerScoped {
+		if _, ok := p.Roles[role]; !ok {
+			return nil, fmt.Errorf("owner scoped role %q is not defined", role)
+		}
+	}
+	return &p, nil
+}
+
+// Allows reports whether any of roles grants permission. Owner scoped roles
+// only count when owned is true, that is, when the request targets the
+// caller's own customer.
+func (p *Policy) Allows(roles []string, permission string, owned bool) bool {
+	for _, role := range roles {
+		perms, ok := p.Roles[role]
+		if !ok || (!owned && slices.Contains(p.OwnerScoped, role)) {
+			continue
+		}
+		if slices.Contains(perms, Wildcard) || slices.Contains(perms, permission) {
+			return true
+		}
+	}
+	return false
+}
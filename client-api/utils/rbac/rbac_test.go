@@ -0,0 +1,62 @@
+//go:build unit
+
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCases_Policy_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success applying the default permission matrix", testDefaultPolicy},
+		{"Success limiting owner scoped roles to their own customer", testOwnerScoped},
+		{"Failure loading a policy with unknown permissions", testUnknownPermission},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testDefaultPolicy(t *testing.T) {
+	t.Log("testDefaultPolicy - Testing the examples of the built-in matrix")
+	p, err := Load("")
+	require.NoError(t, err)
+
+	assert.True(t, p.Allows([]string{"admin"}, CustomersDelete, false))
+	assert.True(t, p.Allows([]string{"auditor"}, CustomersRead, false))
+	assert.False(t, p.Allows([]string{"auditor"}, CustomersDeposit, false))
+	assert.True(t, p.Allows([]string{"teller"}, CustomersWithdraw, false))
+	assert.False(t, p.Allows([]string{"teller"}, CustomersDelete, false))
+	assert.False(t, p.Allows([]string{"unknown"}, CustomersRead, false))
+	assert.False(t, p.Allows(nil, CustomersRead, false))
+}
+
+func testOwnerScoped(t *testing.T) {
+	t.Log("testOwnerScoped - Testing that the customer role only applies to its own ID")
+	p, err := Load("")
+	require.NoError(t, err)
+
+	assert.True(t, p.Allows([]string{"customer"}, CustomersDeposit, true))
+	assert.False(t, p.Allows([]string{"customer"}, CustomersDeposit, false))
+	assert.False(t, p.Allows([]string{"customer"}, CustomersDelete, true))
+	assert.True(t, p.Allows([]string{"customer", "teller"}, CustomersDeposit, false))
+}
+
+func testUnknownPermission(t *testing.T) {
+	t.Log("testUnknownPermission - Testing a failure clause for typos in the matrix")
+	_, err := Parse([]byte("roles:\n  teller: [customers:deposti]\n"))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte("roles:\n  teller: [customers:deposit]\nowner_scoped: [customer]\n"))
+	assert.Error(t, err)
+}
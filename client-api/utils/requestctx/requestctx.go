@@ -0,0 +1,35 @@
+package requestctx
+
+import "context"
+
+// AnonymousActor identifies changes made by callers that did not authenticate.
+const AnonymousActor = "anonymous"
+
+// Metadata describes who issued the request being served and from where.
+type Metadata struct {
+	RequestID string
+	Actor     string
+	ClientIP  string
+	// Roles are the roles granted to an authenticated actor.
+	Roles []string
+	// Scopes restrict what an API key may do. They are nil for users, who
+	// are not limited by scopes.
+	Scopes []string
+}
+
+type metadataKey struct{}
+
+// WithMetadata returns a copy of ctx carrying m.
+func WithMetadata(ctx context.Context, m Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, m)
+}
+
+// FromContext returns the metadata stored in ctx. Contexts that did not go
+// through the HTTP middleware, such as background jobs, get the anonymous actor.
+func FromContext(ctx context.Context) Metadata {
+	m, ok := ctx.Value(metadataKey{}).(Metadata)
+	if !ok || m.Actor == "" {
+		m.Actor = AnonymousActor
+	}
+	return m
+}
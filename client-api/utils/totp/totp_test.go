@@ -0,0 +1,80 @@
+//go:build unit
+
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCases_TOTP_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success computing the RFC 6238 test vectors", testRFCVectors},
+		{"Success validating codes within the allowed drift", testValidateDrift},
+		{"Success building a provisioning URI", testURI},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func testRFCVectors(t *testing.T) {
+	t.Log("testRFCVectors - Testing a success clause for the six digit truncation of the RFC 6238 vectors")
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+}
+
+func testValidateDrift(t *testing.T) {
+	t.Log("testValidateDrift - Testing a success clause for codes one step off and a failure clause beyond that")
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+
+	previous, err := Code(secret, Step(now)-1)
+	require.NoError(t, err)
+	step, ok := Validate(secret, previous, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+
+	stale, err := Code(secret, Step(now)-3)
+	require.NoError(t, err)
+	_, ok = Validate(secret, stale, now, 1)
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func testURI(t *testing.T) {
+	t.Log("testURI - Testing a success clause for the otpauth URI read by authenticator apps")
+	uri := URI("Customer API", "john.doe@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Customer%20API:john.doe@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Customer+API")
+}
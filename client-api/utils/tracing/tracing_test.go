@@ -0,0 +1,87 @@
+//go:build unit
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCases_Tracing_Unit(t *testing.T) {
+	tests := []struct {
+		name     string
+		testFunc func(*testing.T)
+	}{
+		{"Success continuing the trace of an incoming traceparent", testContinueTrace},
+		{"Success marking spans ended with an error as failed", testEndWithError},
+		{"Failure setting up an unknown exporter", testUnknownExporter},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testFunc(t)
+		})
+	}
+}
+
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func testContinueTrace(t *testing.T) {
+	t.Log("testContinueTrace - Testing a success clause for server and child spans joining the caller's trace")
+	recorder := newRecorder(t)
+	_, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	require.NoError(t, err)
+
+	carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+	ctx, server := StartServer(ctx, "GET /clientes/:id")
+	_, child := Start(ctx, "customer.GetByID")
+	child.End()
+	server.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[1].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[1].Parent().SpanID().String())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+func testEndWithError(t *testing.T) {
+	t.Log("testEndWithError - Testing a success clause for the error returned through a named result")
+	recorder := newRecorder(t)
+
+	run := func() (err error) {
+		_, span := Start(context.Background(), "customer.Transactions")
+		defer End(span, &err)
+		return errors.New("saldo insuficiente")
+	}
+	require.Error(t, run())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "saldo insuficiente", spans[0].Status().Description)
+}
+
+func testUnknownExporter(t *testing.T) {
+	t.Log("testUnknownExporter - Testing a failure clause for an exporter that is not supported")
+	_, err := Setup(context.Background(), Config{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
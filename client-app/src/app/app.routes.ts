@@ -5,10 +5,12 @@ import { CustomerDeleteComponent } from './components/customer-delete/customer-d
 import { CustomerDepositComponent } from './components/customer-deposit/customer-deposit.component';
 import { CustomerWithdrawComponent } from './components/customer-withdraw/customer-withdraw.component';
 import { CustomerTransactionListComponent } from './components/customer-transactions/transaction-list.component';
+import { LoginComponent } from './components/login/login.component';
 
 
 export const routes: Routes = [
   { path: '', redirectTo: 'clientes', pathMatch: 'full' },
+  { path: 'login', component: LoginComponent },
   { path: 'clientes', component: CustomerListComponent },
   { path: 'clientes/new', component: CustomerFormComponent },
   { path: 'clientes/:id/edit', component: CustomerFormComponent },
@@ -31,6 +31,7 @@ export class CustomerFormComponent implements OnInit {
   form!: FormGroup;
   isEdit = false;
   id?: string;
+  version?: number;
   loading = false;
   error?: string;
 
@@ -56,6 +57,7 @@ export class CustomerFormComponent implements OnInit {
       this.service.getById(this.id).subscribe({
         next: (c: Customer) => {
           this.form.patchValue({ name: c.name, email: c.email });
+          this.version = c.version;
           this.loading = false;
         },
         error: () => {
@@ -72,7 +74,7 @@ export class CustomerFormComponent implements OnInit {
     const payload = this.form.value;
 
     const obs = this.isEdit && this.id
-      ? this.service.update(this.id, payload)
+      ? this.service.update(this.id, payload, this.version)
       : this.service.create(payload);
 
     obs.subscribe({
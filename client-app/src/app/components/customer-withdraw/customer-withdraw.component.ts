@@ -19,6 +19,7 @@ export class CustomerWithdrawComponent implements OnInit {
   customer: Customer | undefined;
   isLoading = false;
   errorMessage?: string;
+  askTotp = false;
 
   withdrawConfig: TransactionConfig = {
     title: 'Realizar Saque',
@@ -47,12 +48,12 @@ export class CustomerWithdrawComponent implements OnInit {
     });
   }
 
-  handleTransaction(formData: { amount: number }) {
+  handleTransaction(formData: { amount: number; totpCode?: string }) {
     if (!this.customer) return;
     this.isLoading = true;
     this.errorMessage = undefined;
 
-    this.customerService.withdraw(this.customer.id, formData.amount).subscribe({
+    this.customerService.withdraw(this.customer.id, formData.amount, formData.totpCode).subscribe({
       next: () => {
         this.snackBar.open('Saque realizado com sucesso', 'Fechar', {
           duration: 4000,
@@ -64,6 +65,9 @@ export class CustomerWithdrawComponent implements OnInit {
       },
       error: (err) => {
         this.errorMessage = err.error?.message || 'Erro inesperado';
+        if (err.error?.code === 'STEP_UP_REQUIRED' || err.error?.code === 'INVALID_TOTP_CODE') {
+          this.askTotp = true;
+        }
         this.isLoading = false;
       }
     });
@@ -0,0 +1,60 @@
+import { Component } from '@angular/core';
+import { CommonModule } from '@angular/common';
+import { ReactiveFormsModule, FormBuilder, Validators, FormGroup } from '@angular/forms';
+import { Router } from '@angular/router';
+import { MatCardModule } from '@angular/material/card';
+import { MatIconModule } from '@angular/material/icon';
+import { MatFormFieldModule } from '@angular/material/form-field';
+import { MatInputModule } from '@angular/material/input';
+import { MatButtonModule } from '@angular/material/button';
+import { MatProgressBarModule } from '@angular/material/progress-bar';
+import { AuthService, TokenResponse } from '../../services/auth.service';
+
+@Component({
+  selector: 'app-login',
+  standalone: true,
+  imports: [
+    CommonModule,
+    ReactiveFormsModule,
+    MatCardModule,
+    MatIconModule,
+    MatFormFieldModule,
+    MatInputModule,
+    MatButtonModule,
+    MatProgressBarModule
+  ],
+  templateUrl: './login.component.html'
+})
+export class LoginComponent {
+  form!: FormGroup;
+  loading = false;
+  error?: string;
+
+  constructor(
+    private fb: FormBuilder,
+    private auth: AuthService,
+    private router: Router
+  ) {
+    this.form = this.fb.group({
+      email: ['', [Validators.required, Validators.email]],
+      password: ['', Validators.required],
+    });
+  }
+
+  submit(): void {
+    if (this.form.invalid) return;
+    this.loading = true;
+    this.error = undefined;
+    const { email, password } = this.form.value;
+    this.auth.login(email, password).subscribe({
+      next: (tokens: TokenResponse) => {
+        this.loading = false;
+        this.router.navigate(['/clientes', tokens.customer_id, 'transacoes']);
+      },
+      error: err => {
+        this.loading = false;
+        this.error = err.error?.message || 'Não foi possível entrar. Tente novamente.';
+      }
+    });
+  }
+}
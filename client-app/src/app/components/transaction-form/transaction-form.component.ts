@@ -35,21 +35,25 @@ export class TransactionFormComponent {
   @Input() clientName!: string;
   @Input() loading = false;
   @Input() error?: string;
+  // Shows the authenticator code field, required by the API to confirm
+  // high-value withdrawals.
+  @Input() askTotp = false;
 
-  @Output() formSubmit = new EventEmitter<{ amount: number }>();
+  @Output() formSubmit = new EventEmitter<{ amount: number; totpCode?: string }>();
   @Output() cancel = new EventEmitter<void>();
 
   form: FormGroup;
 
   constructor(private fb: FormBuilder) {
     this.form = this.fb.group({
-      amount: [null, [Validators.required, Validators.min(0.01)]]
+      amount: [null, [Validators.required, Validators.min(0.01)]],
+      totpCode: ['', Validators.pattern(/^\d{6}$/)]
     });
   }
 
   submitTransaction() {
     if (this.form.invalid) return;
-    this.formSubmit.emit({ amount: this.form.value.amount });
+    this.formSubmit.emit({ amount: this.form.value.amount, totpCode: this.form.value.totpCode || undefined });
   }
 
   cancelTransaction() {
@@ -0,0 +1,17 @@
+import { Injectable } from '@angular/core';
+import { HttpEvent, HttpHandler, HttpInterceptor, HttpRequest } from '@angular/common/http';
+import { Observable } from 'rxjs';
+
+// Key under which the bearer token used by the API is kept in localStorage.
+export const ACCESS_TOKEN_KEY = 'access_token';
+
+@Injectable()
+export class AuthInterceptor implements HttpInterceptor {
+  intercept(req: HttpRequest<unknown>, next: HttpHandler): Observable<HttpEvent<unknown>> {
+    const token = localStorage.getItem(ACCESS_TOKEN_KEY);
+    if (!token) {
+      return next.handle(req);
+    }
+    return next.handle(req.clone({ setHeaders: { Authorization: `Bearer ${token}` } }));
+  }
+}
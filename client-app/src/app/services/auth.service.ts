@@ -0,0 +1,56 @@
+import { Injectable } from '@angular/core';
+import { HttpClient } from '@angular/common/http';
+import { Observable, tap } from 'rxjs';
+import { ACCESS_TOKEN_KEY } from './auth.interceptor';
+
+export const REFRESH_TOKEN_KEY = 'refresh_token';
+export const CUSTOMER_ID_KEY = 'customer_id';
+
+export interface TokenResponse {
+  access_token: string;
+  refresh_token: string;
+  token_type: string;
+  expires_in: number;
+  session_id: string;
+  customer_id: string;
+}
+
+@Injectable({ providedIn: 'root' })
+export class AuthService {
+  private apiUrl = 'http://localhost:8080/auth';
+
+  constructor(private http: HttpClient) {}
+
+  login(email: string, password: string): Observable<TokenResponse> {
+    return this.http.post<TokenResponse>(`${this.apiUrl}/login`, { email, password })
+      .pipe(tap(tokens => this.store(tokens)));
+  }
+
+  refresh(): Observable<TokenResponse> {
+    const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY) ?? '';
+    return this.http.post<TokenResponse>(`${this.apiUrl}/refresh`, { refresh_token: refreshToken })
+      .pipe(tap(tokens => this.store(tokens)));
+  }
+
+  logout(): Observable<void> {
+    const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY) ?? '';
+    return this.http.post<void>(`${this.apiUrl}/logout`, { refresh_token: refreshToken })
+      .pipe(tap({ next: () => this.clear(), error: () => this.clear() }));
+  }
+
+  customerId(): string | null {
+    return localStorage.getItem(CUSTOMER_ID_KEY);
+  }
+
+  private store(tokens: TokenResponse): void {
+    localStorage.setItem(ACCESS_TOKEN_KEY, tokens.access_token);
+    localStorage.setItem(REFRESH_TOKEN_KEY, tokens.refresh_token);
+    localStorage.setItem(CUSTOMER_ID_KEY, tokens.customer_id);
+  }
+
+  private clear(): void {
+    localStorage.removeItem(ACCESS_TOKEN_KEY);
+    localStorage.removeItem(REFRESH_TOKEN_KEY);
+    localStorage.removeItem(CUSTOMER_ID_KEY);
+  }
+}
@@ -1,5 +1,5 @@
 import { Injectable } from '@angular/core';
-import { HttpClient } from '@angular/common/http';
+import { HttpClient, HttpHeaders } from '@angular/common/http';
 import { Observable } from 'rxjs';
 
 export interface Customer {
@@ -7,6 +7,7 @@ export interface Customer {
   name: string;
   email: string;
   balance: number;
+  version: number;
   balanceOculto?: boolean;                 
   balanceUpdatedAt?: string | Date;  
 }
@@ -29,8 +30,11 @@ export class CustomerService {
     return this.http.post<Customer>(this.apiUrl, payload);
   }
 
-  update(id: string, payload: { name: string; email: string }): Observable<Customer> {
-    return this.http.put<Customer>(`${this.apiUrl}/${id}`, payload);
+  update(id: string, payload: { name: string; email: string }, version?: number): Observable<Customer> {
+    // Sending the version read by the form makes the API reject the update with
+    // 412 when another operator changed the customer in the meantime.
+    const headers = version ? new HttpHeaders({ 'If-Match': `"${version}"` }) : undefined;
+    return this.http.put<Customer>(`${this.apiUrl}/${id}`, payload, { headers });
   }
 
   delete(id: string): Observable<void> {
@@ -41,8 +45,8 @@ export class CustomerService {
     return this.http.post<Customer>(`${this.apiUrl}/${id}/depositar`, { amount });
   }
 
-  withdraw(id: string, amount: number): Observable<Customer> {
-    return this.http.post<Customer>(`${this.apiUrl}/${id}/sacar`, { amount });
+  withdraw(id: string, amount: number, totpCode?: string): Observable<Customer> {
+    return this.http.post<Customer>(`${this.apiUrl}/${id}/sacar`, { amount, totp_code: totpCode });
   }
 
   getTransactions(id: string, page = 1, size = 10): Observable<any> {